@@ -0,0 +1,68 @@
+package api
+
+import (
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/service"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenApi struct{}
+
+func NewAccessTokenApi() *AccessTokenApi {
+	return &AccessTokenApi{}
+}
+
+// 获取个人访问令牌列表
+func (a *AccessTokenApi) GetAccessTokens(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	list, err := srv.GetAccessTokens()
+	if err != nil {
+		resp.Error(errcode.QueryAccessTokenError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+	})
+}
+
+// 创建个人访问令牌
+func (a *AccessTokenApi) CreateAccessToken(c *gin.Context) {
+	param := &validreq.CreateAccessTokenReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	at, token, err := srv.CreateAccessToken(param)
+	if err != nil {
+		resp.Error(errcode.CreateAccessTokenError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"id":          at.Id,
+		"name":        at.Name,
+		"scopes":      at.ScopeList(),
+		"expire_time": at.ExpireTime,
+		"token":       token,
+	})
+}
+
+// 撤销个人访问令牌
+func (a *AccessTokenApi) RevokeAccessToken(c *gin.Context) {
+	param := &validreq.RevokeAccessTokenReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.RevokeAccessToken(param.Id)
+	if err != nil {
+		resp.Error(errcode.RevokeAccessTokenError, err)
+		return
+	}
+	resp.Success(nil)
+}
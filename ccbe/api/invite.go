@@ -1,8 +1,12 @@
 package api
 
 import (
+	"strconv"
+
+	"cc/be/app"
 	"cc/be/errcode"
 	"cc/be/service"
+	"cc/be/utils"
 	"cc/be/validreq"
 
 	"github.com/gin-gonic/gin"
@@ -21,9 +25,10 @@ func (u *InviteApi) GenerateCodes(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	app.SetAuditTarget(c, "num:"+strconv.Itoa(param.Num))
 	// 创建用户
 	srv := service.New(c.Request.Context())
-	err = srv.GenerateCodes(param.LimitType, param.StartTime, param.EndTime, param.Num)
+	err = srv.GenerateCodes(param.LimitType, param.StartTime, param.EndTime, param.MaxUses, param.Num)
 	if err != nil {
 		resp.Error(errcode.GenerateCodeError, err)
 		return
@@ -32,3 +37,70 @@ func (u *InviteApi) GenerateCodes(c *gin.Context) {
 		"cnt": param.Num,
 	})
 }
+
+// 分页查询邀请码，可按状态、创建人和邀请码筛选
+func (u *InviteApi) GetInvites(c *gin.Context) {
+	resp := app.NewResponse(c)
+	var status *int8
+	if s := c.Query("status"); s != "" {
+		v := int8(utils.StrTo(s).MustInt())
+		status = &v
+	}
+	createUid := utils.StrTo(c.Query("create_uid")).MustInt()
+	code := c.Query("code")
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, err := srv.GetInvites(status, createUid, code, page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryInviteError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
+
+// 作废邀请码
+func (u *InviteApi) RevokeInvite(c *gin.Context) {
+	param := &validreq.RevokeInviteReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, "invite:"+strconv.Itoa(param.Id))
+	srv := service.New(c.Request.Context())
+	err = srv.RevokeInvite(param.Id)
+	if err != nil {
+		resp.Error(errcode.RevokeInviteError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 查询邀请码的使用记录
+func (u *InviteApi) GetRedemptions(c *gin.Context) {
+	resp := app.NewResponse(c)
+	id := utils.StrTo(c.Param("id")).MustInt()
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, err := srv.GetRedemptions(id, page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryRedemptionError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
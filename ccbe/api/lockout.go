@@ -0,0 +1,47 @@
+package api
+
+import (
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/service"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutApi struct{}
+
+func NewLockoutApi() *LockoutApi {
+	return &LockoutApi{}
+}
+
+// 查询当前生效的登录、注册锁定
+func (l *LockoutApi) GetLockouts(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	list, err := srv.GetLockouts()
+	if err != nil {
+		resp.Error(errcode.QueryLockoutError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+	})
+}
+
+// 解除锁定
+func (l *LockoutApi) Unlock(c *gin.Context) {
+	param := &validreq.UnlockReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, param.Scope+":"+param.Value)
+	srv := service.New(c.Request.Context())
+	err = srv.Unlock(param.Scope, param.Value)
+	if err != nil {
+		resp.Error(errcode.UnlockError, err)
+		return
+	}
+	resp.Success(nil)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/global"
+	"cc/be/service"
+	"cc/be/utils"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QuotaApi struct{}
+
+func NewQuotaApi() *QuotaApi {
+	return &QuotaApi{}
+}
+
+// 查询当前用户的存储用量、额度和文件数
+func (q *QuotaApi) GetStorageUsage(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	usage, err := srv.GetStorageUsage(global.Uid)
+	if err != nil {
+		resp.Error(errcode.QueryQuotaError, err)
+		return
+	}
+	resp.Success(usage)
+}
+
+// 查询指定用户的存储用量和额度设置
+func (q *QuotaApi) GetUserQuota(c *gin.Context) {
+	resp := app.NewResponse(c)
+	uid := utils.StrTo(c.Param("uid")).MustInt()
+	if uid <= 0 {
+		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(uid))
+	srv := service.New(c.Request.Context())
+	usage, plans, err := srv.GetUserQuota(uid)
+	if err != nil {
+		resp.Error(errcode.QueryQuotaError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"usage": usage,
+		"plans": plans,
+	})
+}
+
+// 设置用户的存储套餐或单独的额度
+func (q *QuotaApi) UpdateQuota(c *gin.Context) {
+	param := &validreq.UpdateQuotaReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(param.Uid)+",plan:"+param.Plan+
+		",hard:"+strconv.FormatInt(param.HardLimit, 10)+",soft:"+strconv.FormatInt(param.SoftLimit, 10))
+	srv := service.New(c.Request.Context())
+	err = srv.UpdateUserQuota(param.Uid, param.Plan, param.HardLimit, param.SoftLimit)
+	if err != nil {
+		resp.Error(errcode.UpdateQuotaError, err)
+		return
+	}
+	resp.Success(nil)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/service"
+	"cc/be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 邀请排行默认返回数量
+const REFERRAL_TOP_INVITERS = 20
+
+type ReferralApi struct{}
+
+func NewReferralApi() *ReferralApi {
+	return &ReferralApi{}
+}
+
+// 查询当前用户邀请的账号及活跃状态
+func (r *ReferralApi) GetInvitees(c *gin.Context) {
+	resp := app.NewResponse(c)
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, err := srv.GetInvitees(page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryInviteeError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
+
+// 查询邀请关系树，指定 uid 时返回该用户的下级邀请关系
+func (r *ReferralApi) GetReferralTree(c *gin.Context) {
+	resp := app.NewResponse(c)
+	uid := utils.StrTo(c.Query("uid")).MustInt()
+	depth := utils.StrTo(c.Query("depth")).MustInt()
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, truncated, err := srv.GetReferralTree(uid, depth, page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryReferralError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list":      list,
+		"truncated": truncated,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
+
+// 查询邀请人排行和邀请转化统计，unit: day-按天，month-按月
+func (r *ReferralApi) GetReferralStats(c *gin.Context) {
+	resp := app.NewResponse(c)
+	startTime := utils.StrTo(c.Query("start_time")).MustInt()
+	endTime := utils.StrTo(c.Query("end_time")).MustInt()
+	unit := c.DefaultQuery("unit", service.REFERRAL_UNIT_DAY)
+	srv := service.New(c.Request.Context())
+	stats, err := srv.GetReferralStats(startTime, endTime, unit, REFERRAL_TOP_INVITERS)
+	if err != nil {
+		resp.Error(errcode.QueryReferralError, err)
+		return
+	}
+	resp.Success(stats)
+}
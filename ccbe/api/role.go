@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/service"
+	"cc/be/utils"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleApi struct{}
+
+func NewRoleApi() *RoleApi {
+	return &RoleApi{}
+}
+
+// 授予用户角色
+func (r *RoleApi) GrantRole(c *gin.Context) {
+	param := &validreq.UserRoleReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(param.Uid)+",role:"+param.Role)
+	srv := service.New(c.Request.Context())
+	err = srv.GrantRole(param.Uid, param.Role)
+	if err != nil {
+		resp.Error(errcode.GrantRoleError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 撤销用户角色
+func (r *RoleApi) RevokeRole(c *gin.Context) {
+	param := &validreq.UserRoleReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(param.Uid)+",role:"+param.Role)
+	srv := service.New(c.Request.Context())
+	err = srv.RevokeRole(param.Uid, param.Role)
+	if err != nil {
+		resp.Error(errcode.RevokeRoleError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 获取指定用户的角色列表
+func (r *RoleApi) GetUserRoles(c *gin.Context) {
+	resp := app.NewResponse(c)
+	uid := utils.StrTo(c.Param("uid")).MustInt()
+	if uid <= 0 {
+		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(uid))
+	srv := service.New(c.Request.Context())
+	list, err := srv.GetUserroles(uid)
+	if err != nil {
+		resp.Error(errcode.QueryRoleError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"uid":   uid,
+		"roles": srv.GetUserRoles(uid),
+		"list":  list,
+	})
+}
+
+// 分页查询审计日志
+func (r *RoleApi) GetAuditlogs(c *gin.Context) {
+	resp := app.NewResponse(c)
+	uid := utils.StrTo(c.Query("uid")).MustInt()
+	action := c.Query("action")
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, err := srv.GetAuditlogs(uid, action, page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryAuditlogError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
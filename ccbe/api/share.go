@@ -1,11 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"errors"
+	"log"
+	"net/http"
+	"time"
 	"cc/be/app"
+	"cc/be/cache"
 	"cc/be/errcode"
 	"cc/be/global"
+	"cc/be/render"
 	"cc/be/service"
+	"cc/be/utils"
 	"cc/be/validreq"
 
 	"github.com/gin-gonic/gin"
@@ -25,10 +32,28 @@ func (s *ShareApi) GetShareData(c *gin.Context) {
 		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
 		return
 	}
-	// 查询视图的分享信息
+	// 设置了访问密码的分享需携带访问令牌
+	token := c.GetHeader("X-Share-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+	// 查询视图的分享信息，可通过 rev 访问允许公开的历史版本
+	rev := utils.StrTo(c.Query("rev")).MustInt()
 	srv := service.New(c.Request.Context())
-	share, err := srv.GetShareData(shareId)
-	if err != nil {
+	share, err := srv.GetShareData(shareId, token, rev)
+	if errors.Is(err, service.ErrSharePasswordRequired) {
+		resp.Error(errcode.SharePasswordRequiredError, err)
+		return
+	} else if errors.Is(err, service.ErrShareExpired) {
+		resp.Error(errcode.ShareExpiredError, err)
+		return
+	} else if errors.Is(err, service.ErrShareViewLimit) {
+		resp.Error(errcode.ShareViewLimitError, err)
+		return
+	} else if errors.Is(err, service.ErrShareRevision) {
+		resp.Error(errcode.QueryShareRevisionError, err)
+		return
+	} else if err != nil {
 		resp.Error(errcode.QueryShareError, err)
 		return
 	}
@@ -40,18 +65,177 @@ func (s *ShareApi) GetShareData(c *gin.Context) {
 		resp.Error(errcode.ShareStatusError, err)
 		return
 	}
+	srv.RecordShareView(share, c.ClientIP(), c.Request.Referer(), c.Request.UserAgent())
 	resp.Success(gin.H{
 		"uuid":       share.Uuid,
 		"viewId":     share.ViewId,
+		"cardId":     share.CardId,
 		"name":       share.Name,
 		"type":       share.Type,
 		"icon":       share.Icon,
 		"status":     share.Status,
+		"mode":       share.Mode,
+		"rev":        share.Rev,
 		"content":    share.Content,
 		"updateTime": share.UpdateTime,
 	})
 }
 
+// 服务端渲染分享页面，供搜索引擎、链接预览和不支持 JavaScript 的访问者使用
+func (s *ShareApi) RenderSharePage(c *gin.Context) {
+	shareId := c.Param("shareId")
+	srv := service.New(c.Request.Context())
+	rev := utils.StrTo(c.Query("rev")).MustInt()
+	share, err := srv.GetShareData(shareId, c.Query("token"), rev)
+	switch {
+	case errors.Is(err, service.ErrSharePasswordRequired):
+		renderShareMessage(c, http.StatusUnauthorized, "该分享需要访问密码", "请在 CardCool 中打开链接并输入访问密码。")
+		return
+	case errors.Is(err, service.ErrShareExpired):
+		renderShareMessage(c, http.StatusGone, "分享已过期", err.Error())
+		return
+	case errors.Is(err, service.ErrShareViewLimit):
+		renderShareMessage(c, http.StatusForbidden, "分享无法访问", err.Error())
+		return
+	case errors.Is(err, service.ErrShareRevision):
+		renderShareMessage(c, http.StatusNotFound, "分享版本不存在", err.Error())
+		return
+	case err != nil:
+		renderShareMessage(c, http.StatusInternalServerError, "分享加载失败", "请稍后重试。")
+		return
+	case share == nil:
+		renderShareMessage(c, http.StatusNotFound, "分享不存在", "该分享链接无效。")
+		return
+	case share.Status != 1:
+		renderShareMessage(c, http.StatusGone, "分享已取消", "分享者已取消该分享。")
+		return
+	}
+	srv.RecordShareView(share, c.ClientIP(), c.Request.Referer(), c.Request.UserAgent())
+	var buf bytes.Buffer
+	if err := srv.RenderSharePage(&buf, share); err != nil {
+		log.Printf("渲染分享页面异常: %s", err)
+		renderShareMessage(c, http.StatusInternalServerError, "分享加载失败", "分享内容格式异常。")
+		return
+	}
+	writeSharePage(c, http.StatusOK, buf.Bytes())
+}
+
+func renderShareMessage(c *gin.Context, code int, title, message string) {
+	var buf bytes.Buffer
+	render.RenderMessage(&buf, title, message)
+	writeSharePage(c, code, buf.Bytes())
+}
+
+// 分享页面的内容安全策略，页面内容由服务端生成，禁止执行脚本
+const SHARE_PAGE_CSP = "default-src 'none'; img-src * data:; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'"
+
+func writeSharePage(c *gin.Context, code int, data []byte) {
+	writePage(c, code, SHARE_PAGE_CSP, data)
+}
+
+func writePage(c *gin.Context, code int, csp string, data []byte) {
+	c.Header("Content-Security-Policy", csp)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "no-store")
+	c.Data(code, "text/html; charset=utf-8", data)
+}
+
+// 渲染可嵌入 iframe 的分享页面，仅允许分享者设置的来源嵌入
+// 提示页面不包含分享内容，允许任意来源嵌入以便在 iframe 中显示
+func (s *ShareApi) RenderShareEmbed(c *gin.Context) {
+	shareId := c.Param("shareId")
+	rev := utils.StrTo(c.Query("rev")).MustInt()
+	srv := service.New(c.Request.Context())
+	share, err := srv.GetShareEmbedData(shareId, c.Query("token"), rev)
+	switch {
+	case errors.Is(err, service.ErrShareEmbedDisabled):
+		renderShareMessage(c, http.StatusForbidden, "分享无法嵌入", err.Error())
+		return
+	case errors.Is(err, service.ErrSharePasswordRequired):
+		renderShareMessage(c, http.StatusUnauthorized, "该分享需要访问密码", "请在 CardCool 中打开链接并输入访问密码。")
+		return
+	case errors.Is(err, service.ErrShareExpired):
+		renderShareMessage(c, http.StatusGone, "分享已过期", err.Error())
+		return
+	case errors.Is(err, service.ErrShareViewLimit):
+		renderShareMessage(c, http.StatusForbidden, "分享无法访问", err.Error())
+		return
+	case errors.Is(err, service.ErrShareRevision):
+		renderShareMessage(c, http.StatusNotFound, "分享版本不存在", err.Error())
+		return
+	case err != nil:
+		renderShareMessage(c, http.StatusInternalServerError, "分享加载失败", "请稍后重试。")
+		return
+	case share == nil:
+		renderShareMessage(c, http.StatusNotFound, "分享不存在", "该分享链接无效。")
+		return
+	case share.Status != 1:
+		renderShareMessage(c, http.StatusGone, "分享已取消", "分享者已取消该分享。")
+		return
+	}
+	srv.RecordShareView(share, c.ClientIP(), c.Request.Referer(), c.Request.UserAgent())
+	var buf bytes.Buffer
+	if err := srv.RenderShareEmbed(&buf, share, c.Query("theme")); err != nil {
+		log.Printf("渲染分享嵌入页面异常: %s", err)
+		renderShareMessage(c, http.StatusInternalServerError, "分享加载失败", "分享内容格式异常。")
+		return
+	}
+	writePage(c, http.StatusOK, SHARE_PAGE_CSP+"; frame-ancestors "+service.ShareFrameAncestors(share), buf.Bytes())
+}
+
+// oEmbed 接口，按 oEmbed 规范直接返回 JSON 对象和 HTTP 状态码
+func (s *ShareApi) OEmbed(c *gin.Context) {
+	if format := c.Query("format"); format != "" && format != "json" {
+		c.Status(http.StatusNotImplemented)
+		return
+	}
+	shareURL := c.Query("url")
+	if shareURL == "" {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	maxWidth := utils.StrTo(c.Query("maxwidth")).MustInt()
+	maxHeight := utils.StrTo(c.Query("maxheight")).MustInt()
+	srv := service.New(c.Request.Context())
+	oembed, err := srv.GetShareOEmbed(shareURL, c.Query("theme"), maxWidth, maxHeight)
+	switch {
+	case errors.Is(err, service.ErrShareNotFound):
+		c.Status(http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrShareEmbedDisabled):
+		c.Status(http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("生成 oEmbed 响应异常: %s", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, oembed)
+}
+
+// 输入分享访问密码，获取短期访问令牌
+func (s *ShareApi) CreateShareAccess(c *gin.Context) {
+	params := &validreq.ShareAccessReq{}
+	resp, err := validParams(c, params)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	token, err := srv.CreateShareAccess(params.ShareId, params.Password, c.ClientIP())
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		resp.Error(errcode.ThrottleLockedError, err)
+		return
+	} else if err != nil {
+		resp.Error(errcode.ShareAccessError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"token":        token,
+		"token_expire": time.Now().Add(cache.SHARE_ACCESS_EXPIRE).Unix(),
+	})
+}
+
 // 获取指定视图的分享信息
 func (s *ShareApi) GetShareInfo(c *gin.Context) {
 	resp := app.NewResponse(c)
@@ -68,13 +252,40 @@ func (s *ShareApi) GetShareInfo(c *gin.Context) {
 		return
 	}
 	resp.Success(gin.H{
-		"uuid":       share.Uuid,
-		"status":     share.Status,
-		"viewId":     viewId,
-		"updateTime": share.UpdateTime,
+		"uuid":         share.Uuid,
+		"status":       share.Status,
+		"mode":         share.Mode,
+		"viewId":       viewId,
+		"hasPassword":  share.HasPassword(),
+		"expireTime":   share.ExpireTime,
+		"maxViews":     share.MaxViews,
+		"viewCount":    share.ViewCount,
+		"rev":          share.Rev,
+		"pinRev":       share.PinRev,
+		"revPublic":    share.RevPublic,
+		"embedOrigins": share.EmbedOrigins,
+		"updateTime":   share.UpdateTime,
 	})
 }
 
+// 获取指定视图分享的访问统计
+func (s *ShareApi) GetShareStats(c *gin.Context) {
+	resp := app.NewResponse(c)
+	viewId := c.Param("viewId")
+	if viewId == "" {
+		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
+		return
+	}
+	days := utils.StrTo(c.Query("days")).MustInt()
+	srv := service.New(c.Request.Context())
+	stats, err := srv.GetShareStats(global.Uid, viewId, days)
+	if err != nil {
+		resp.Error(errcode.QueryShareStatsError, err)
+		return
+	}
+	resp.Success(stats)
+}
+
 // 创建或刷新视图分享
 func (s *ShareApi) CreateShare(c *gin.Context) {
 	params := &validreq.CreateShareReq{}
@@ -90,13 +301,91 @@ func (s *ShareApi) CreateShare(c *gin.Context) {
 		return
 	}
 	resp.Success(gin.H{
-		"uuid":       share.Uuid,
-		"status":     share.Status,
-		"viewId":     params.ViewId,
-		"updateTime": share.UpdateTime,
+		"uuid":         share.Uuid,
+		"status":       share.Status,
+		"mode":         share.Mode,
+		"viewId":       params.ViewId,
+		"hasPassword":  share.HasPassword(),
+		"expireTime":   share.ExpireTime,
+		"maxViews":     share.MaxViews,
+		"viewCount":    share.ViewCount,
+		"rev":          share.Rev,
+		"pinRev":       share.PinRev,
+		"revPublic":    share.RevPublic,
+		"embedOrigins": share.EmbedOrigins,
+		"updateTime":   share.UpdateTime,
+	})
+}
+
+// 获取指定卡片的分享信息
+func (s *ShareApi) GetCardShareInfo(c *gin.Context) {
+	resp := app.NewResponse(c)
+	cardId := c.Param("cardId")
+	if cardId == "" {
+		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
+		return
+	}
+	srv := service.New(c.Request.Context())
+	share, err := srv.GetCardShareInfo(global.Uid, cardId)
+	if err != nil {
+		resp.Error(errcode.QueryShareError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"uuid":         share.Uuid,
+		"status":       share.Status,
+		"cardId":       cardId,
+		"hasPassword":  share.HasPassword(),
+		"expireTime":   share.ExpireTime,
+		"maxViews":     share.MaxViews,
+		"viewCount":    share.ViewCount,
+		"embedOrigins": share.EmbedOrigins,
+		"updateTime":   share.UpdateTime,
+	})
+}
+
+// 创建或刷新卡片分享
+func (s *ShareApi) CreateCardShare(c *gin.Context) {
+	params := &validreq.CreateCardShareReq{}
+	resp, err := validParams(c, params)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	share, err := srv.CreateCardShare(global.Uid, params)
+	if err != nil {
+		resp.Error(errcode.CreateShareError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"uuid":         share.Uuid,
+		"status":       share.Status,
+		"cardId":       params.CardId,
+		"hasPassword":  share.HasPassword(),
+		"expireTime":   share.ExpireTime,
+		"maxViews":     share.MaxViews,
+		"viewCount":    share.ViewCount,
+		"embedOrigins": share.EmbedOrigins,
+		"updateTime":   share.UpdateTime,
 	})
 }
 
+// 更新卡片分享状态
+func (s *ShareApi) UpdateCardShareStatus(c *gin.Context) {
+	params := &validreq.UpdateCardShareStatusReq{}
+	resp, err := validParams(c, params)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.UpdateCardShareStatus(global.Uid, params)
+	if err != nil {
+		resp.Error(errcode.UpdateShareStatusError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
 // 更新视图分享状态
 func (s *ShareApi) UpdateShareStatus(c *gin.Context) {
 	params := &validreq.UpdateShareStatusReq{}
@@ -113,3 +402,63 @@ func (s *ShareApi) UpdateShareStatus(c *gin.Context) {
 	}
 	resp.Success(nil)
 }
+
+// 获取视图分享的历史版本列表
+func (s *ShareApi) GetShareRevisions(c *gin.Context) {
+	resp := app.NewResponse(c)
+	viewId := c.Param("viewId")
+	if viewId == "" {
+		resp.Error(errcode.InvalidParams, errors.New("请求参数异常"))
+		return
+	}
+	srv := service.New(c.Request.Context())
+	share, list, err := srv.GetShareRevisions(global.Uid, viewId)
+	if err != nil {
+		resp.Error(errcode.QueryShareRevisionError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"rev":       share.Rev,
+		"pinRev":    share.PinRev,
+		"revPublic": share.RevPublic,
+		"list":      list,
+	})
+}
+
+// 固定或取消固定公开的分享版本
+func (s *ShareApi) PinShareRevision(c *gin.Context) {
+	params := &validreq.PinShareRevisionReq{}
+	resp, err := validParams(c, params)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	share, err := srv.PinShareRevision(global.Uid, params.ViewId, params.Rev)
+	if err != nil {
+		resp.Error(errcode.PinShareRevisionError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"rev":    share.Rev,
+		"pinRev": share.PinRev,
+	})
+}
+
+// 回滚分享到指定的历史版本
+func (s *ShareApi) RollbackShareRevision(c *gin.Context) {
+	params := &validreq.RollbackShareRevisionReq{}
+	resp, err := validParams(c, params)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	share, err := srv.RollbackShareRevision(global.Uid, params.ViewId, params.Rev)
+	if err != nil {
+		resp.Error(errcode.RollbackShareRevisionError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"rev":    share.Rev,
+		"pinRev": share.PinRev,
+	})
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/global"
+	"cc/be/service"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SpaceApi struct{}
+
+func NewSpaceApi() *SpaceApi {
+	return &SpaceApi{}
+}
+
+// 获取空间成员列表
+func (s *SpaceApi) GetSpaceMembers(c *gin.Context) {
+	resp := app.NewResponse(c)
+	spaceId := c.Param("spaceId")
+	srv := service.New(c.Request.Context())
+	list, ownerUid, err := srv.GetSpaceMembers(global.Uid, spaceId)
+	if err != nil {
+		resp.Error(errcode.QuerySpaceMemberError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"owner_uid": ownerUid,
+		"list":      list,
+	})
+}
+
+// 根据用户编码邀请成员加入空间
+func (s *SpaceApi) InviteSpaceMember(c *gin.Context) {
+	param := &validreq.InviteSpaceMemberReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	sm, err := srv.InviteSpaceMember(global.Uid, param.SpaceId, param.Code, param.Role)
+	if err != nil {
+		resp.Error(errcode.InviteSpaceMemberError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"uid":    sm.Uid,
+		"role":   sm.Role,
+		"status": sm.Status,
+	})
+}
+
+// 修改空间成员角色
+func (s *SpaceApi) UpdateSpaceMember(c *gin.Context) {
+	param := &validreq.UpdateSpaceMemberReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.UpdateSpaceMember(global.Uid, param.SpaceId, param.Uid, param.Role)
+	if err != nil {
+		resp.Error(errcode.UpdateSpaceMemberError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 移除空间成员或退出空间
+func (s *SpaceApi) RemoveSpaceMember(c *gin.Context) {
+	param := &validreq.RemoveSpaceMemberReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.RemoveSpaceMember(global.Uid, param.SpaceId, param.Uid)
+	if err != nil {
+		resp.Error(errcode.RemoveSpaceMemberError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 获取待接受的空间邀请
+func (s *SpaceApi) GetSpaceInvites(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	list, err := srv.GetSpaceInvites(global.Uid)
+	if err != nil {
+		resp.Error(errcode.QuerySpaceInviteError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+	})
+}
+
+// 接受空间邀请，客户端需重新拉取全部数据以获取空间的历史数据
+func (s *SpaceApi) AcceptSpaceInvite(c *gin.Context) {
+	param := &validreq.SpaceInviteReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.AcceptSpaceInvite(global.Uid, param.SpaceId)
+	if err != nil {
+		resp.Error(errcode.HandleSpaceInviteError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 拒绝空间邀请
+func (s *SpaceApi) DeclineSpaceInvite(c *gin.Context) {
+	param := &validreq.SpaceInviteReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.DeclineSpaceInvite(global.Uid, param.SpaceId)
+	if err != nil {
+		resp.Error(errcode.HandleSpaceInviteError, err)
+		return
+	}
+	resp.Success(nil)
+}
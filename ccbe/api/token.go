@@ -4,18 +4,21 @@ import (
 	"cc/be/app"
 	"cc/be/cache"
 	"cc/be/errcode"
+	"cc/be/idp"
 	"cc/be/model"
 	"cc/be/service"
 	"cc/be/validreq"
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 回调状态：1-直接登录，2-绑定注册
+// 登录状态：1-直接登录，2-绑定注册，3-需要两步验证
 const CB_LOGIN = 1
 const CB_BIND = 2
+const CB_TOTP = 3
 
 type TokenApi struct{}
 
@@ -30,23 +33,18 @@ func (t *TokenApi) Login(c *gin.Context) {
 		return
 	}
 	srv := service.New(c.Request.Context())
-	user, err := srv.Login(param)
-	if err != nil {
-		resp.Error(errcode.LoginError, err)
+	keys := service.LoginThrottleKeys(param.Mobile, c.ClientIP())
+	if err := srv.CheckThrottle(keys); err != nil {
+		resp.Error(errcode.ThrottleLockedError, err)
 		return
 	}
-	// 查询配置信息
-	if user.Config == "" {
-		p := &model.Propext{}
-		user.Config = p.GetExtPropByUid(user.Id, model.USER_CONFIG_ID, model.TYPE_USER_CONFIG)
-	}
-	// 生成 Token
-	token, expireTime, err := app.GenerateToken(user.Id)
+	user, err := srv.Login(param)
 	if err != nil {
-		resp.Error(errcode.GenerateTokenError, err)
+		srv.ThrottleFailure(keys)
+		resp.Error(errcode.LoginError, err)
 		return
 	}
-	resp.Success(getRespData(token, expireTime, user))
+	loginSuccess(resp, &srv, user, keys)
 }
 
 func (t *TokenApi) BindUser(c *gin.Context) {
@@ -56,25 +54,36 @@ func (t *TokenApi) BindUser(c *gin.Context) {
 		return
 	}
 	srv := service.New(c.Request.Context())
+	keys := service.LoginThrottleKeys(param.Mobile, c.ClientIP())
+	if err := srv.CheckThrottle(keys); err != nil {
+		resp.Error(errcode.ThrottleLockedError, err)
+		return
+	}
 	user, err := srv.BindUser(param)
 	if err != nil {
+		srv.ThrottleFailure(keys)
 		resp.Error(errcode.LoginError, err)
 		return
 	}
-	// 查询配置信息
-	if user.Config == "" {
-		p := &model.Propext{}
-		user.Config = p.GetExtPropByUid(user.Id, model.USER_CONFIG_ID, model.TYPE_USER_CONFIG)
-	}
-	// 生成 Token
-	token, expireTime, err := app.GenerateToken(user.Id)
+	loginSuccess(resp, &srv, user, keys)
+}
+
+// 获取第三方授权跳转地址
+func (t *TokenApi) Authorize(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	url, state, err := srv.GetAuthURL(c.Param("provider"), 0)
 	if err != nil {
-		resp.Error(errcode.GenerateTokenError, err)
+		resp.Error(errcode.AuthorizeURLError, err)
 		return
 	}
-	resp.Success(getRespData(token, expireTime, user))
+	resp.Success(gin.H{
+		"url":   url,
+		"state": state,
+	})
 }
 
+// 第三方授权回调：已绑定账号直接登录，否则返回身份凭证用于绑定或注册
 func (t *TokenApi) Callback(c *gin.Context) {
 	param := &validreq.CallbackReq{}
 	resp, err := validParams(c, param)
@@ -82,15 +91,70 @@ func (t *TokenApi) Callback(c *gin.Context) {
 		return
 	}
 	srv := service.New(c.Request.Context())
-	user, err := srv.Callback(param)
+	user, ticket, identity, err := srv.Callback(param)
 	if err != nil {
 		resp.Error(errcode.LoginError, err)
 		return
 	}
-	if user.Id == 0 {
-		resp.Error(errcode.WechatCallbackUserError, err)
+	if user == nil {
+		resp.Success(gin.H{
+			"status": CB_BIND,
+			"ticket": ticket,
+			"userinfo": gin.H{
+				"provider": identity.Provider,
+				"username": identity.Username,
+				"avatar":   identity.Avatar,
+			},
+		})
+		return
+	}
+	loginSuccess(resp, &srv, user, nil)
+}
+
+// 两步验证登录：校验登录挑战和验证码后下发正式 token
+func (t *TokenApi) LoginTotp(c *gin.Context) {
+	param := &validreq.LoginTotpReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	user, err := srv.VerifyLoginChallenge(param.Challenge, param.Code)
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		resp.Error(errcode.ThrottleLockedError, err)
+		return
+	} else if err != nil {
+		resp.Error(errcode.TotpVerifyError, err)
 		return
 	}
+	// 两步验证通过后才清除密码登录的失败记录
+	srv.ThrottleSuccess(service.LoginThrottleKeys(user.Mobile, c.ClientIP()))
+	respToken(resp, user)
+}
+
+// 账号校验通过：启用两步验证时返回登录挑战，否则直接下发 token
+// keys 为密码登录的限流对象，启用两步验证时在验证码校验通过后才清除
+func loginSuccess(resp *app.Response, srv *service.Service, user *model.User, keys []service.ThrottleKey) {
+	if srv.IsTotpEnabled(user.Id) {
+		challenge, err := srv.CreateLoginChallenge(user.Id)
+		if err != nil {
+			resp.Error(errcode.LoginError, err)
+			return
+		}
+		resp.Success(gin.H{
+			"status":           CB_TOTP,
+			"challenge":        challenge,
+			"challenge_expire": time.Now().Add(cache.LOGIN_CHALLENGE_EXPIRE).Unix(),
+		})
+		return
+	}
+	srv.ThrottleSuccess(keys)
+	respToken(resp, user)
+}
+
+// 生成 token 并返回登录数据
+func respToken(resp *app.Response, user *model.User) {
 	// 查询配置信息
 	if user.Config == "" {
 		p := &model.Propext{}
@@ -102,10 +166,13 @@ func (t *TokenApi) Callback(c *gin.Context) {
 		resp.Error(errcode.GenerateTokenError, err)
 		return
 	}
-	resp.Success(getRespData(token, expireTime, user))
+	data := getRespData(token, expireTime, user)
+	data["status"] = CB_LOGIN
+	resp.Success(data)
 }
 
 func getRespData(token string, expireTime int64, user *model.User) map[string]any {
+	mi := &model.Identity{}
 	return gin.H{
 		"token":        token,
 		"token_expire": expireTime,
@@ -117,7 +184,7 @@ func getRespData(token string, expireTime int64, user *model.User) map[string]an
 			"username": user.Username,
 			"avatar":   user.Avatar,
 			"mobile":   user.Mobile,
-			"openid":   user.Openid,
+			"openid":   mi.GetSubject(user.Id, idp.PROVIDER_WECHAT),
 			"code":     user.Code,
 		},
 		"config": user.Config,
@@ -132,8 +199,14 @@ func (t *TokenApi) Register(c *gin.Context) {
 	}
 	// 注册用户
 	srv := service.New(c.Request.Context())
+	keys := service.RegisterThrottleKeys(c.ClientIP())
+	if err := srv.CheckThrottle(keys); err != nil {
+		resp.Error(errcode.ThrottleLockedError, err)
+		return
+	}
 	user, err := srv.Register(param)
 	if err != nil {
+		srv.ThrottleFailure(keys)
 		resp.Error(errcode.RegisterError, err)
 		return
 	}
@@ -178,8 +251,14 @@ func (t *TokenApi) RegisterByCode(c *gin.Context) {
 	}
 	// 注册用户
 	srv := service.New(c.Request.Context())
+	keys := service.RegisterThrottleKeys(c.ClientIP())
+	if err := srv.CheckThrottle(keys); err != nil {
+		resp.Error(errcode.ThrottleLockedError, err)
+		return
+	}
 	user, err := srv.RegisterByCode(param)
 	if err != nil {
+		srv.ThrottleFailure(keys)
 		resp.Error(errcode.RegisterError, err)
 		return
 	}
@@ -216,6 +295,44 @@ func (t *TokenApi) RegisterByCode(c *gin.Context) {
 	resp.Success(getRespData(token, expireTime, user))
 }
 
+// 获取注册或重置密码的短信验证码
+func (t *TokenApi) SendCode(c *gin.Context) {
+	param := &validreq.SendCodeReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.SendPublicCode(param, c.ClientIP())
+	if err != nil {
+		resp.Error(errcode.SendCodeError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 通过短信验证码重置密码
+func (t *TokenApi) ResetPassword(c *gin.Context) {
+	param := &validreq.ResetPasswordReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.ResetPassword(param)
+	if err != nil {
+		resp.Error(errcode.ResetPasswordError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 公开 token 校验公钥(JWKS)，供其他服务校验登录 token
+func (t *TokenApi) Jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, app.JWKS())
+}
+
 func (t *TokenApi) Timeout(c *gin.Context) {
 	resp := app.NewResponse(c)
 	time.Sleep(time.Duration(5) * time.Second)
@@ -245,10 +362,11 @@ func (t *TokenApi) QiniuCallback(c *gin.Context) {
 		resp.Error(errcode.QiniuCallbackAuthError, errors.New("七牛回调验签失败"))
 		return
 	}
-	err = srv.QiniuCallback(param)
+	res, err := srv.QiniuCallback(param)
 	if err != nil {
 		resp.Error(errcode.QiniuCallbackError, err)
 		return
 	}
-	resp.Ctx.JSON(errcode.Success.StatusCode(), param)
+	// 回调响应会返回给客户端，客户端以响应中的 key 为准
+	resp.Ctx.JSON(errcode.Success.StatusCode(), res)
 }
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"strconv"
+
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/service"
+	"cc/be/validreq"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TotpApi struct{}
+
+func NewTotpApi() *TotpApi {
+	return &TotpApi{}
+}
+
+// 获取两步验证状态
+func (t *TotpApi) GetTotpStatus(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	enabled, recoveryCodes := srv.GetTotpStatus()
+	resp.Success(gin.H{
+		"enabled":        enabled,
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// 绑定认证器，返回密钥和扫码 URI
+func (t *TotpApi) EnrollTotp(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	secret, uri, err := srv.EnrollTotp()
+	if err != nil {
+		resp.Error(errcode.EnrollTotpError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+// 激活两步验证，返回恢复码
+func (t *TotpApi) ActivateTotp(c *gin.Context) {
+	param := &validreq.TotpCodeReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	codes, err := srv.ActivateTotp(param.Code)
+	if err != nil {
+		resp.Error(errcode.ActivateTotpError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// 关闭两步验证
+func (t *TotpApi) DisableTotp(c *gin.Context) {
+	param := &validreq.TotpCodeReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.DisableTotp(param.Code)
+	if err != nil {
+		resp.Error(errcode.DisableTotpError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 重新生成恢复码
+func (t *TotpApi) RegenerateRecoveryCodes(c *gin.Context) {
+	param := &validreq.TotpCodeReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	codes, err := srv.RegenerateRecoveryCodes(param.Code)
+	if err != nil {
+		resp.Error(errcode.RecoveryCodeError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// 管理员重置用户的两步验证
+func (t *TotpApi) ResetTotp(c *gin.Context) {
+	param := &validreq.ResetTotpReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	app.SetAuditTarget(c, "uid:"+strconv.Itoa(param.Uid))
+	srv := service.New(c.Request.Context())
+	err = srv.ResetTotp(param.Uid)
+	if err != nil {
+		resp.Error(errcode.ResetTotpError, err)
+		return
+	}
+	resp.Success(nil)
+}
@@ -1,9 +1,21 @@
 package api
 
 import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
 	"cc/be/app"
 	"cc/be/errcode"
+	"cc/be/global"
+	"cc/be/resp"
 	"cc/be/service"
+	"cc/be/storage"
+	"cc/be/utils"
+	"cc/be/validreq"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,13 +26,264 @@ func NewUploadApi() *UploadApi {
 	return &UploadApi{}
 }
 
+// 上传失败的响应，超出存储额度时使用额度错误码
+func uploadError(r *app.Response, code *errcode.Error, err error) {
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		code = errcode.QuotaExceededError
+	}
+	r.Error(code, err)
+}
+
+// 存储用量超出软限制时返回提醒
+func quotaWarning(srv *service.Service, uid int) *resp.Warning {
+	usage, err := srv.GetStorageUsage(uid)
+	if err != nil || !usage.OverSoftLimit {
+		return nil
+	}
+	return &resp.Warning{
+		Code: errcode.QuotaSoftLimitWarning.Code(),
+		Msg:  errcode.QuotaSoftLimitWarning.Msg(),
+	}
+}
+
 func (u *UploadApi) GetUploadToken(c *gin.Context) {
 	resp := app.NewResponse(c)
 	srv := service.New(c.Request.Context())
 	res, err := srv.GetUploadToken()
 	if err != nil {
-		resp.Error(errcode.UploadTokenError, err)
+		uploadError(resp, errcode.UploadTokenError, err)
+		return
+	}
+	res.Warning = quotaWarning(&srv, global.Uid)
+	resp.Success(res)
+}
+
+// 读取 multipart 请求中的文件，不将整个请求写入内存或临时文件
+// token 字段需在 file 之前，也可通过 query 参数传递
+func readUploadPart(c *gin.Context) (string, *multipart.Part, error) {
+	token := c.Query("token")
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return "", nil, errors.New("请求格式不正确")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return "", nil, errors.New("缺少上传文件")
+		} else if err != nil {
+			return "", nil, errors.New("请求格式不正确")
+		}
+		switch part.FormName() {
+		case "token":
+			b, _ := io.ReadAll(io.LimitReader(part, 1024))
+			token = string(b)
+		case "file":
+			return token, part, nil
+		}
+	}
+}
+
+// 请求体大小上限，multipart 的边界和其他字段额外预留 1M
+func limitUploadBody(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, storage.Default().MaxSize()+1048576)
+}
+
+// 使用上传凭证上传文件到本地存储，表单字段: token-上传凭证，file-文件
+// 与七牛云直传一致，成功时直接返回文件信息
+func (u *UploadApi) Upload(c *gin.Context) {
+	resp := app.NewResponse(c)
+	limitUploadBody(c)
+	token, part, err := readUploadPart(c)
+	if err != nil {
+		resp.Error(errcode.UploadFileError, err)
+		return
+	}
+	srv := service.New(c.Request.Context())
+	res, err := srv.ReceiveUpload(token, part.FileName(), part, c.Request.ContentLength)
+	if err != nil {
+		uploadError(resp, errcode.UploadFileError, err)
+		return
+	}
+	res.Warning = quotaWarning(&srv, res.Uid)
+	resp.Ctx.JSON(errcode.Success.StatusCode(), res)
+}
+
+// 直接上传文件，写入当前配置的存储，表单字段: file-文件
+// 请求需带 Content-Length，读取文件内容前校验上传额度
+func (u *UploadApi) UploadFile(c *gin.Context) {
+	resp := app.NewResponse(c)
+	limitUploadBody(c)
+	_, part, err := readUploadPart(c)
+	if err != nil {
+		resp.Error(errcode.UploadFileError, err)
+		return
+	}
+	srv := service.New(c.Request.Context())
+	res, err := srv.UploadFile(part.FileName(), part, c.Request.ContentLength)
+	if err != nil {
+		uploadError(resp, errcode.UploadFileError, err)
+		return
+	}
+	res.Warning = quotaWarning(&srv, global.Uid)
+	resp.Success(res)
+}
+
+// 创建分片上传任务，大文件按返回的 chunk_size 分片上传
+func (u *UploadApi) InitChunkUpload(c *gin.Context) {
+	param := &validreq.InitChunkUploadReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	res, err := srv.InitChunkUpload(param.Filename, param.Size)
+	if err != nil {
+		uploadError(resp, errcode.InitChunkUploadError, err)
+		return
+	}
+	resp.Success(res)
+}
+
+// 查询分片上传任务及已上传的分片，用于断点续传
+func (u *UploadApi) GetChunkUpload(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	res, err := srv.GetChunkUpload(c.Param("uploadId"))
+	if err != nil {
+		resp.Error(errcode.QueryChunkUploadError, err)
+		return
+	}
+	resp.Success(res)
+}
+
+// 上传分片，请求体为分片内容，query 参数: upload_id-任务 id，index-分片序号(从 0 开始)
+func (u *UploadApi) UploadChunk(c *gin.Context) {
+	resp := app.NewResponse(c)
+	index, err := strconv.Atoi(c.Query("index"))
+	if err != nil {
+		resp.Error(errcode.UploadChunkError, errors.New("分片序号不正确"))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.UPLOAD_CHUNK_SIZE+1)
+	srv := service.New(c.Request.Context())
+	err = srv.UploadChunk(c.Query("upload_id"), index, c.Request.Body)
+	if err != nil {
+		resp.Error(errcode.UploadChunkError, err)
+		return
+	}
+	resp.Success(gin.H{"index": index})
+}
+
+// 合并分片完成上传
+func (u *UploadApi) CompleteChunkUpload(c *gin.Context) {
+	param := &validreq.ChunkUploadReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	res, err := srv.CompleteChunkUpload(param.UploadId)
+	if err != nil {
+		uploadError(resp, errcode.UploadFileError, err)
+		return
+	}
+	res.Warning = quotaWarning(&srv, global.Uid)
+	resp.Success(res)
+}
+
+// 取消分片上传任务
+func (u *UploadApi) AbortChunkUpload(c *gin.Context) {
+	param := &validreq.ChunkUploadReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.AbortChunkUpload(param.UploadId)
+	if err != nil {
+		resp.Error(errcode.UploadChunkError, err)
+		return
+	}
+	resp.Success("success")
+}
+
+// 登记客户端直传完成的文件，用于未开启上传回调的存储
+func (u *UploadApi) CompleteUpload(c *gin.Context) {
+	param := &validreq.UploadCompleteReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	res, err := srv.CompleteUpload(param)
+	if err != nil {
+		resp.Error(errcode.UploadCompleteError, err)
 		return
 	}
+	res.Warning = quotaWarning(&srv, global.Uid)
 	resp.Success(res)
 }
+
+// 删除已上传的文件，释放存储空间
+func (u *UploadApi) DeleteFile(c *gin.Context) {
+	param := &validreq.DeleteFileReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.DeleteFile(param.Key)
+	if err != nil {
+		resp.Error(errcode.DeleteFileError, err)
+		return
+	}
+	resp.Success("success")
+}
+
+// 查询当前用户的文件库，keyword 搜索文件名，type 按文件类型筛选(image/video/audio/text/application)
+func (u *UploadApi) GetFiles(c *gin.Context) {
+	resp := app.NewResponse(c)
+	page := utils.GetPage(c)
+	pageSize := utils.GetPageSize(c)
+	srv := service.New(c.Request.Context())
+	list, total, err := srv.GetFiles(c.Query("keyword"), c.Query("type"), page, pageSize)
+	if err != nil {
+		resp.Error(errcode.QueryFilesError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"list": list,
+		"pager": app.Pager{
+			Page:      page,
+			PageSize:  pageSize,
+			TotalRows: int(total),
+		},
+	})
+}
+
+// 从文件库批量删除文件，释放存储空间
+func (u *UploadApi) DeleteFiles(c *gin.Context) {
+	param := &validreq.DeleteFilesReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	n, err := srv.DeleteFiles(param.Ids)
+	if err != nil {
+		resp.Error(errcode.DeleteFilesError, err)
+		return
+	}
+	resp.Success(gin.H{"deleted": n})
+}
+
+// 文件下载，size 为缩略图规格名称或宽度，跳转到对应的下载地址
+func (u *UploadApi) Download(c *gin.Context) {
+	srv := service.New(c.Request.Context())
+	url, err := srv.GetDownloadURL(strings.TrimPrefix(c.Param("key"), "/"), c.Query("size"))
+	if err != nil {
+		app.NewResponse(c).Error(errcode.DownloadFileError, err)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
@@ -4,6 +4,8 @@ import (
 	"cc/be/app"
 	"cc/be/cache"
 	"cc/be/errcode"
+	"cc/be/global"
+	"cc/be/idp"
 	"cc/be/service"
 	"cc/be/validreq"
 	"time"
@@ -24,6 +26,7 @@ func (u *UserApi) AddAccount(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	app.SetAuditTarget(c, "mobile:"+param.Mobile)
 	// 创建用户
 	srv := service.New(c.Request.Context())
 	user, err := srv.AddAccount(param.Mobile)
@@ -83,23 +86,93 @@ func (u *UserApi) GetInfo(c *gin.Context) {
 	})
 }
 
-func (u *UserApi) BindWechat(c *gin.Context) {
-	param := &validreq.BindWechatReq{}
+// 获取绑定第三方账号的授权跳转地址
+func (u *UserApi) AuthorizeBind(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	url, state, err := srv.GetAuthURL(c.Param("provider"), global.Uid)
+	if err != nil {
+		resp.Error(errcode.AuthorizeURLError, err)
+		return
+	}
+	resp.Success(gin.H{
+		"url":   url,
+		"state": state,
+	})
+}
+
+// 绑定第三方账号
+func (u *UserApi) BindIdentity(c *gin.Context) {
+	param := &validreq.BindIdentityReq{}
 	resp, err := validParams(c, param)
 	if err != nil {
 		return
 	}
 	srv := service.New(c.Request.Context())
-	openid, err := srv.BindWechat(param)
+	identity, err := srv.BindIdentity(param)
 	if err != nil {
-		resp.Error(errcode.BindWechatError, err)
+		resp.Error(errcode.BindIdentityError, err)
+		return
+	}
+	data := gin.H{
+		"id":       identity.Id,
+		"provider": identity.Provider,
+		"username": identity.Username,
+		"avatar":   identity.Avatar,
+	}
+	if identity.Provider == idp.PROVIDER_WECHAT {
+		data["openid"] = identity.Subject
+	}
+	resp.Success(data)
+}
+
+// 获取已绑定的第三方账号列表
+func (u *UserApi) GetIdentities(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	list, err := srv.GetIdentities()
+	if err != nil {
+		resp.Error(errcode.QueryIdentityError, err)
 		return
 	}
 	resp.Success(gin.H{
-		"openid": openid,
+		"list":      list,
+		"providers": idp.Names(),
 	})
 }
 
+// 解除第三方账号绑定
+func (u *UserApi) UnbindIdentity(c *gin.Context) {
+	param := &validreq.UnbindIdentityReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.UnbindIdentity(param.Id)
+	if err != nil {
+		resp.Error(errcode.UnbindIdentityError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
+// 获取修改手机号的短信验证码
+func (u *UserApi) SendMobileCode(c *gin.Context) {
+	param := &validreq.SendMobileCodeReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	err = srv.SendChangeMobileCode(param.Mobile, c.ClientIP())
+	if err != nil {
+		resp.Error(errcode.SendCodeError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
 func (u *UserApi) UpdateMobileAccount(c *gin.Context) {
 	param := &validreq.UpdateMobileAccountReq{}
 	resp, err := validParams(c, param)
@@ -160,6 +233,46 @@ func (u *UserApi) Change(c *gin.Context) {
 	resp.Success(user)
 }
 
+// 查询注销申请
+func (u *UserApi) GetDeletion(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	deletion, err := srv.GetDeletion()
+	if err != nil {
+		resp.Error(errcode.QueryDeletionError, err)
+		return
+	}
+	resp.Success(deletion)
+}
+
+// 申请注销账号
+func (u *UserApi) RequestDeletion(c *gin.Context) {
+	param := &validreq.RequestDeletionReq{}
+	resp, err := validParams(c, param)
+	if err != nil {
+		return
+	}
+	srv := service.New(c.Request.Context())
+	deletion, err := srv.RequestDeletion(param, c.ClientIP())
+	if err != nil {
+		resp.Error(errcode.RequestDeletionError, err)
+		return
+	}
+	resp.Success(deletion)
+}
+
+// 撤销注销申请
+func (u *UserApi) CancelDeletion(c *gin.Context) {
+	resp := app.NewResponse(c)
+	srv := service.New(c.Request.Context())
+	err := srv.CancelDeletion(c.ClientIP())
+	if err != nil {
+		resp.Error(errcode.CancelDeletionError, err)
+		return
+	}
+	resp.Success(nil)
+}
+
 // 用户相关路由处理
 // 获取信息列表和配置数据
 func (u *UserApi) GetToken(c *gin.Context) {}
@@ -171,6 +284,7 @@ func (u *UserApi) UpdateClient(c *gin.Context) {
 	if err != nil {
 		return
 	}
+	app.SetAuditTarget(c, "version:"+param.Version)
 	info := &map[string]string{
 		"version": param.Version,
 		"baidu":   param.Baidu,
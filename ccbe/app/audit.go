@@ -0,0 +1,12 @@
+package app
+
+import "github.com/gin-gonic/gin"
+
+// 响应错误码和审计对象在请求上下文中的键名
+const RESP_CODE_KEY = "resp_code"
+const AUDIT_TARGET_KEY = "audit_target"
+
+// 设置审计日志的操作对象
+func SetAuditTarget(c *gin.Context, target string) {
+	c.Set(AUDIT_TARGET_KEY, target)
+}
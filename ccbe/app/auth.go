@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 登录信息在请求上下文中的键名
+const AUTH_KEY = "auth"
+
+// 当前请求的登录信息，Scopes 为 nil 表示登录会话，否则为个人访问令牌的权限范围
+type Auth struct {
+	Uid    int
+	Rid    string
+	Scopes []string
+}
+
+type authContextKey struct{}
+
+// 保存登录信息到 gin 上下文和请求的 context，GraphQL 等只能获取 context 的处理器从 context 读取
+func SetAuth(c *gin.Context, auth *Auth) {
+	c.Set(AUTH_KEY, auth)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), authContextKey{}, auth))
+}
+
+// 获取请求的登录信息，未经过登录校验时返回 nil
+func GetAuth(ctx context.Context) *Auth {
+	if c, ok := ctx.(*gin.Context); ok {
+		ctx = c.Request.Context()
+	}
+	auth, _ := ctx.Value(authContextKey{}).(*Auth)
+	return auth
+}
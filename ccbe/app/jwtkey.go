@@ -0,0 +1,181 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"cc/be/setting"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// 支持的签名算法
+const ALG_HS256 = "HS256"
+const ALG_RS256 = "RS256"
+const ALG_EDDSA = "EdDSA"
+
+// 签名密钥，只有公钥的密钥仅用于校验
+type jwtKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	signKey interface{}
+	verKey  interface{}
+}
+
+var (
+	// 当前签名密钥
+	signingKey *jwtKey
+	// 校验密钥，按 kid 索引，未设置 kid 的旧 token 使用 kid 为空的密钥
+	verifyKeys = map[string]*jwtKey{}
+)
+
+// 根据配置加载签名密钥
+func InitJwtKeys(s *setting.JwtSetting) error {
+	keys := map[string]*jwtKey{}
+	// 兼容原有的 HS256 密钥，未配置 kid 的 token 使用该密钥校验
+	if s.Secret != "" {
+		keys[""] = &jwtKey{
+			method:  jwt.SigningMethodHS256,
+			signKey: []byte(s.Secret),
+			verKey:  []byte(s.Secret),
+		}
+	}
+	for _, ks := range s.Keys {
+		if ks.Kid == "" {
+			return errors.New("签名密钥缺少 kid")
+		}
+		if _, ok := keys[ks.Kid]; ok {
+			return errors.New("签名密钥 kid 重复: " + ks.Kid)
+		}
+		k, err := loadJwtKey(ks)
+		if err != nil {
+			return fmt.Errorf("加载签名密钥 %s 异常: %w", ks.Kid, err)
+		}
+		keys[ks.Kid] = k
+	}
+	active, ok := keys[s.SigningKey]
+	if !ok {
+		return errors.New("未找到当前签名密钥: " + s.SigningKey)
+	}
+	if active.signKey == nil {
+		return errors.New("当前签名密钥缺少私钥: " + s.SigningKey)
+	}
+	signingKey = active
+	verifyKeys = keys
+	return nil
+}
+
+func loadJwtKey(ks setting.JwtKeySetting) (*jwtKey, error) {
+	k := &jwtKey{kid: ks.Kid}
+	switch ks.Algorithm {
+	case ALG_HS256:
+		if ks.Secret == "" {
+			return nil, errors.New("HS256 密钥缺少 Secret")
+		}
+		k.method = jwt.SigningMethodHS256
+		k.signKey = []byte(ks.Secret)
+		k.verKey = []byte(ks.Secret)
+		return k, nil
+	case ALG_RS256:
+		k.method = jwt.SigningMethodRS256
+	case ALG_EDDSA:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("不支持的签名算法: " + ks.Algorithm)
+	}
+	if ks.PrivateKeyFile != "" {
+		pem, err := os.ReadFile(ks.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if ks.Algorithm == ALG_RS256 {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey = priv
+			k.verKey = &priv.PublicKey
+		} else {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey = priv
+			k.verKey = priv.(crypto.Signer).Public()
+		}
+	}
+	if ks.PublicKeyFile != "" {
+		pem, err := os.ReadFile(ks.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if ks.Algorithm == ALG_RS256 {
+			k.verKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		} else {
+			k.verKey, err = jwt.ParseEdPublicKeyFromPEM(pem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if k.verKey == nil {
+		return nil, errors.New("缺少公钥或私钥文件")
+	}
+	return k, nil
+}
+
+// 根据 token 头部的 kid 选择校验密钥
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := verifyKeys[kid]
+	if !ok {
+		return nil, errors.New("未知的签名密钥: " + kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errors.New("签名算法不匹配")
+	}
+	return k.verKey, nil
+}
+
+// 公开的校验密钥(JWKS)，不包含 HS256 密钥
+func JWKS() map[string]interface{} {
+	kids := make([]string, 0, len(verifyKeys))
+	for kid := range verifyKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	keys := []map[string]string{}
+	for _, kid := range kids {
+		k := verifyKeys[kid]
+		switch pub := k.verKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": ALG_RS256,
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"use": "sig",
+				"alg": ALG_EDDSA,
+				"crv": "Ed25519",
+				"kid": kid,
+				"x":   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return map[string]interface{}{
+		"keys": keys,
+	}
+}
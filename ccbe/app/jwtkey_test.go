@@ -0,0 +1,223 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cc/be/global"
+	"cc/be/setting"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// 将密钥写入 PEM 文件，返回私钥和公钥文件路径
+func writeKeyFiles(t *testing.T, name string, priv interface{}, pub interface{}) (string, string) {
+	dir := t.TempDir()
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privFile := filepath.Join(dir, name+".pem")
+	pubFile := filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return privFile, pubFile
+}
+
+type testKeys struct {
+	rsa        *rsa.PrivateKey
+	rsaPriv    string
+	rsaPub     string
+	ed         ed25519.PrivateKey
+	edPriv     string
+	edPub      string
+	rsaPubPEM  []byte
+	legacyHMAC string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	global.JwtSetting = &setting.JwtSetting{Issuer: "test", Expire: time.Hour}
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &testKeys{rsa: rk, ed: edPriv, legacyHMAC: "legacy-secret"}
+	k.rsaPriv, k.rsaPub = writeKeyFiles(t, "r1", rk, &rk.PublicKey)
+	k.edPriv, k.edPub = writeKeyFiles(t, "e1", edPriv, edPub)
+	k.rsaPubPEM, _ = os.ReadFile(k.rsaPub)
+	return k
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	claims := &Claims{Uid: 7, Rid: "r", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKeyFuncRejectsAlgMismatch(t *testing.T) {
+	k := newTestKeys(t)
+	err := InitJwtKeys(&setting.JwtSetting{
+		Secret:     k.legacyHMAC,
+		SigningKey: "r1",
+		Keys:       []setting.JwtKeySetting{{Kid: "r1", Algorithm: ALG_RS256, PublicKeyFile: k.rsaPub, PrivateKeyFile: k.rsaPriv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 以 RSA 公钥内容作为 HMAC 密钥签名的 HS256 token 不能通过校验
+	if _, err := ParseToken(signToken(t, jwt.SigningMethodHS256, "r1", k.rsaPubPEM)); err == nil {
+		t.Fatal("HS256 token 使用 RSA 密钥校验应失败")
+	}
+	// 未设置 kid 的旧 token 只能使用 HS256
+	if _, err := ParseToken(signToken(t, jwt.SigningMethodRS256, "", k.rsa)); err == nil {
+		t.Fatal("RS256 token 使用旧 HS256 密钥校验应失败")
+	}
+	if c, err := ParseToken(signToken(t, jwt.SigningMethodHS256, "", []byte(k.legacyHMAC))); err != nil || c.Uid != 7 {
+		t.Fatalf("旧 HS256 token 校验 = %+v, %v", c, err)
+	}
+}
+
+func TestKeyFuncRejectsUnknownKid(t *testing.T) {
+	k := newTestKeys(t)
+	err := InitJwtKeys(&setting.JwtSetting{
+		SigningKey: "r1",
+		Keys:       []setting.JwtKeySetting{{Kid: "r1", Algorithm: ALG_RS256, PrivateKeyFile: k.rsaPriv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(signToken(t, jwt.SigningMethodRS256, "r2", k.rsa)); err == nil {
+		t.Fatal("未知 kid 的 token 校验应失败")
+	}
+	// 未配置 Secret 时没有 kid 为空的密钥
+	if _, err := ParseToken(signToken(t, jwt.SigningMethodHS256, "", []byte("x"))); err == nil {
+		t.Fatal("未设置 kid 的 token 校验应失败")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	k := newTestKeys(t)
+	err := InitJwtKeys(&setting.JwtSetting{
+		SigningKey: "r1",
+		Keys:       []setting.JwtKeySetting{{Kid: "r1", Algorithm: ALG_RS256, PrivateKeyFile: k.rsaPriv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _, err := GenerateToken(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 轮换到 EdDSA 密钥，旧密钥只保留公钥用于校验
+	err = InitJwtKeys(&setting.JwtSetting{
+		SigningKey: "e1",
+		Keys: []setting.JwtKeySetting{
+			{Kid: "e1", Algorithm: ALG_EDDSA, PrivateKeyFile: k.edPriv},
+			{Kid: "r1", Algorithm: ALG_RS256, PublicKeyFile: k.rsaPub},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := ParseToken(old); err != nil || c.Uid != 7 {
+		t.Fatalf("轮换后旧 token 校验 = %+v, %v", c, err)
+	}
+	token, _, err := GenerateToken(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, _ := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	if parsed.Header["kid"] != "e1" || parsed.Method.Alg() != ALG_EDDSA {
+		t.Fatalf("新 token 头部 = %v", parsed.Header)
+	}
+	if c, err := ParseToken(token); err != nil || c.Uid != 8 {
+		t.Fatalf("新 token 校验 = %+v, %v", c, err)
+	}
+	// 只有公钥的密钥不能作为当前签名密钥
+	err = InitJwtKeys(&setting.JwtSetting{
+		SigningKey: "r1",
+		Keys:       []setting.JwtKeySetting{{Kid: "r1", Algorithm: ALG_RS256, PublicKeyFile: k.rsaPub}},
+	})
+	if err == nil {
+		t.Fatal("缺少私钥的签名密钥应初始化失败")
+	}
+	// 移除旧密钥后旧 token 失效
+	err = InitJwtKeys(&setting.JwtSetting{
+		SigningKey: "e1",
+		Keys:       []setting.JwtKeySetting{{Kid: "e1", Algorithm: ALG_EDDSA, PrivateKeyFile: k.edPriv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(old); err == nil {
+		t.Fatal("移除旧密钥后旧 token 校验应失败")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	k := newTestKeys(t)
+	err := InitJwtKeys(&setting.JwtSetting{
+		Secret:     k.legacyHMAC,
+		SigningKey: "e1",
+		Keys: []setting.JwtKeySetting{
+			{Kid: "e1", Algorithm: ALG_EDDSA, PrivateKeyFile: k.edPriv},
+			{Kid: "h1", Algorithm: ALG_HS256, Secret: "hmac"},
+			{Kid: "r1", Algorithm: ALG_RS256, PublicKeyFile: k.rsaPub},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := JWKS()["keys"].([]map[string]string)
+	// HS256 密钥不公开，按 kid 排序
+	if len(keys) != 2 || keys[0]["kid"] != "e1" || keys[1]["kid"] != "r1" {
+		t.Fatalf("JWKS = %v", keys)
+	}
+	ed := keys[0]
+	if ed["kty"] != "OKP" || ed["crv"] != "Ed25519" || ed["alg"] != ALG_EDDSA || ed["use"] != "sig" {
+		t.Fatalf("Ed25519 JWK = %v", ed)
+	}
+	if x, _ := base64.RawURLEncoding.DecodeString(ed["x"]); string(x) != string(k.ed.Public().(ed25519.PublicKey)) {
+		t.Fatalf("Ed25519 x = %s", ed["x"])
+	}
+	r := keys[1]
+	if r["kty"] != "RSA" || r["alg"] != ALG_RS256 || r["use"] != "sig" {
+		t.Fatalf("RSA JWK = %v", r)
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(r["n"])
+	e, _ := base64.RawURLEncoding.DecodeString(r["e"])
+	if new(big.Int).SetBytes(n).Cmp(k.rsa.N) != 0 || int(new(big.Int).SetBytes(e).Int64()) != k.rsa.E {
+		t.Fatalf("RSA n/e = %s/%s", r["n"], r["e"])
+	}
+	if r["e"] != "AQAB" {
+		t.Fatalf("RSA e = %s", r["e"])
+	}
+}
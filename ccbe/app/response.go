@@ -58,6 +58,7 @@ func (r *Response) Error(errcode *errcode.Error, err error) {
 		message += ": " + err.Error()
 	}
 	global.Logger.Error(message)
+	r.Ctx.Set(RESP_CODE_KEY, errcode.Code())
 	response := gin.H{"code": errcode.Code(), "msg": message}
 	r.Ctx.JSON(errcode.StatusCode(), response)
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"time"
 
 	"cc/be/global"
@@ -23,24 +24,24 @@ func GenerateToken(uid int) (string, int64, error) {
 		Uid: uid,
 		Rid: utils.Unid(expireTime.UnixMilli()),
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    global.JwtSetting.Issuer,
 			ExpiresAt: jwt.NewNumericDate(expireTime), // 过期时间
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
-	res, err := token.SignedString(getSecret())
+	if signingKey == nil {
+		return "", 0, errors.New("签名密钥未初始化")
+	}
+	token := jwt.NewWithClaims(signingKey.method, &claims)
+	if signingKey.kid != "" {
+		token.Header["kid"] = signingKey.kid
+	}
+	res, err := token.SignedString(signingKey.signKey)
 	return res, expireTime.Unix(), err
 }
 
-func getSecret() []byte {
-	return []byte(global.JwtSetting.Secret)
-}
-func secretFunc(token *jwt.Token) (interface{}, error) {
-	return getSecret(), nil
-}
-
 // 解析 token
 func ParseToken(token string) (*Claims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, secretFunc)
+	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, keyFunc)
 	if err != nil || tokenClaims == nil {
 		return nil, err
 	}
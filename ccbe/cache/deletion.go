@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"log"
+	"strconv"
+	"time"
+)
+
+// 已注销用户集合，用于拒绝注销前签发的 token
+const DELETED_USERS_KEY = "deleted_users"
+
+// 注销清除任务锁，避免多实例重复执行
+const DELETION_WORKER_KEY = "deletion_worker"
+
+// 标记用户已注销
+func SetUserDeleted(uid int) {
+	ctx := context.Background()
+	err := global.RedisDb.SAdd(ctx, DELETED_USERS_KEY, uid).Err()
+	if err != nil {
+		log.Printf("更新注销用户缓存异常: %s", err)
+	}
+}
+
+// 判断用户是否已注销
+func IsUserDeleted(uid int) bool {
+	ctx := context.Background()
+	ok, err := global.RedisDb.SIsMember(ctx, DELETED_USERS_KEY, uid).Result()
+	if err != nil {
+		log.Printf("查询注销用户缓存异常: %s", err)
+		return false
+	}
+	return ok
+}
+
+// 获取清除任务锁
+func LockDeletionWorker(d time.Duration) bool {
+	ctx := context.Background()
+	ok, err := global.RedisDb.SetNX(ctx, DELETION_WORKER_KEY, 1, d).Result()
+	return err == nil && ok
+}
+
+func UnlockDeletionWorker() {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, DELETION_WORKER_KEY)
+}
+
+// 清除以 uid 为 key 的用户缓存
+func ClearUserCache(uid int) {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx,
+		getUserInfoKey(uid),
+		getUserRoleKey(uid),
+		getUserUpdateKey(uid),
+		getUploadTokenKey(strconv.Itoa(uid)),
+	)
+}
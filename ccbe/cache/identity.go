@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 第三方授权 state，用于防止 CSRF 并记录授权用途
+const OAUTH_STATE_KEY = "oauth_state:"
+const OAUTH_STATE_EXPIRE = 600 * time.Second
+
+// 待绑定的第三方身份凭证，授权回调未关联账号时下发给前端
+const IDENTITY_TICKET_KEY = "identity_ticket:"
+const IDENTITY_TICKET_EXPIRE = 600 * time.Second
+
+// 授权 state 信息，Uid 不为 0 时表示已登录用户绑定身份，Nonce 用于校验 OIDC id_token
+type OauthState struct {
+	Provider string `json:"provider"`
+	Uid      int    `json:"uid"`
+	Nonce    string `json:"nonce"`
+}
+
+// 保存授权 state
+func SetOauthState(state string, s *OauthState) error {
+	ctx := context.Background()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return global.RedisDb.Set(ctx, OAUTH_STATE_KEY+state, data, OAUTH_STATE_EXPIRE).Err()
+}
+
+// 获取并删除授权 state，state 只能使用一次
+func PopOauthState(state string) *OauthState {
+	ctx := context.Background()
+	key := OAUTH_STATE_KEY + state
+	str, err := global.RedisDb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		log.Printf("查询授权 state 缓存异常: %s", err)
+		return nil
+	}
+	global.RedisDb.Del(ctx, key)
+	s := &OauthState{}
+	if err := json.Unmarshal([]byte(str), s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// 保存待绑定的身份信息
+func SetIdentityTicket(ticket string, identity interface{}) error {
+	ctx := context.Background()
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+	return global.RedisDb.Set(ctx, IDENTITY_TICKET_KEY+ticket, data, IDENTITY_TICKET_EXPIRE).Err()
+}
+
+// 获取待绑定的身份信息，不存在时返回 false
+func GetIdentityTicket(ticket string, identity interface{}) bool {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, IDENTITY_TICKET_KEY+ticket).Result()
+	if err == redis.Nil {
+		return false
+	} else if err != nil {
+		log.Printf("查询身份凭证缓存异常: %s", err)
+		return false
+	}
+	return json.Unmarshal([]byte(str), identity) == nil
+}
+
+// 清除待绑定的身份信息
+func ClearIdentityTicket(ticket string) {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, IDENTITY_TICKET_KEY+ticket)
+}
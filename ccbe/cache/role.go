@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 用户角色缓存: 逗号分隔的角色列表
+const USER_ROLE_KEY = "user_role:"
+const USER_ROLE_EXPIRE = 3600 * time.Second
+
+func getUserRoleKey(uid int) string {
+	return USER_ROLE_KEY + strconv.Itoa(uid)
+}
+
+// 获取用户角色缓存，未命中时返回 false
+func GetUserRoles(uid int) ([]string, bool) {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, getUserRoleKey(uid)).Result()
+	if err == redis.Nil {
+		return nil, false
+	} else if err != nil {
+		log.Printf("查询用户角色缓存异常: %s", err)
+		return nil, false
+	}
+	if str == "" {
+		return []string{}, true
+	}
+	return strings.Split(str, ","), true
+}
+
+// 设置用户角色缓存
+func SetUserRoles(uid int, roles []string) {
+	ctx := context.Background()
+	err := global.RedisDb.Set(ctx, getUserRoleKey(uid), strings.Join(roles, ","), USER_ROLE_EXPIRE).Err()
+	if err != nil {
+		log.Printf("更新用户角色缓存异常: %s", err)
+	}
+}
+
+// 清除用户角色缓存
+func ClearUserRoles(uid int) {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, getUserRoleKey(uid))
+}
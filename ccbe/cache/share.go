@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 分享访问令牌：输入访问密码后下发，有效期内访问分享无需再次输入密码
+const SHARE_ACCESS_KEY = "share_access:"
+const SHARE_ACCESS_EXPIRE = 7200 * time.Second
+
+// 访问令牌信息，Stamp 为签发时的密码摘要片段，修改密码后令牌失效
+type ShareAccess struct {
+	ShareId string `json:"share_id"`
+	Stamp   string `json:"stamp"`
+}
+
+// 保存分享访问令牌
+func SetShareAccess(token string, access *ShareAccess) error {
+	ctx := context.Background()
+	data, err := json.Marshal(access)
+	if err != nil {
+		return err
+	}
+	return global.RedisDb.Set(ctx, SHARE_ACCESS_KEY+token, data, SHARE_ACCESS_EXPIRE).Err()
+}
+
+// 获取分享访问令牌，不存在时返回 nil
+func GetShareAccess(token string) *ShareAccess {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, SHARE_ACCESS_KEY+token).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		log.Printf("查询分享访问令牌缓存异常: %s", err)
+		return nil
+	}
+	access := &ShareAccess{}
+	if err := json.Unmarshal([]byte(str), access); err != nil {
+		return nil
+	}
+	return access
+}
+
+// 实时分享内容缓存，UpdateTime 为生成时分享者的数据更新时间，更新时间变化后缓存失效
+const SHARE_LIVE_KEY = "share_live:"
+const SHARE_LIVE_EXPIRE = 86400 * time.Second
+
+type ShareLive struct {
+	UpdateTime int64  `json:"update_time"`
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	Type       int8   `json:"type"`
+	Content    string `json:"content"`
+}
+
+// 保存实时分享内容
+func SetShareLive(shareId string, live *ShareLive) {
+	ctx := context.Background()
+	data, err := json.Marshal(live)
+	if err != nil {
+		return
+	}
+	if err := global.RedisDb.Set(ctx, SHARE_LIVE_KEY+shareId, data, SHARE_LIVE_EXPIRE).Err(); err != nil {
+		log.Printf("Redis 更新实时分享缓存异常: %s", err)
+	}
+}
+
+// 获取实时分享内容，不存在时返回 nil
+func GetShareLive(shareId string) *ShareLive {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, SHARE_LIVE_KEY+shareId).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		log.Printf("查询实时分享缓存异常: %s", err)
+		return nil
+	}
+	live := &ShareLive{}
+	if err := json.Unmarshal([]byte(str), live); err != nil {
+		return nil
+	}
+	return live
+}
+
+// 删除实时分享内容缓存
+func DelShareLive(shareId string) {
+	ctx := context.Background()
+	if err := global.RedisDb.Del(ctx, SHARE_LIVE_KEY+shareId).Err(); err != nil {
+		log.Printf("Redis 删除实时分享缓存异常: %s", err)
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 失败次数计数，key 为 throttle_fail:{scope}:{value}
+const THROTTLE_FAIL_KEY = "throttle_fail:"
+const THROTTLE_FAIL_EXPIRE = 86400 * time.Second
+
+// 锁定标记，key 的过期时间即为锁定时长
+const THROTTLE_LOCK_KEY = "throttle_lock:"
+
+// 锁定记录有序集合，score 为解锁时间，供管理后台查看
+const THROTTLE_LOCKOUTS_KEY = "throttle_lockouts"
+
+// 锁定记录
+type Lockout struct {
+	Scope      string `json:"scope"`
+	Value      string `json:"value"`
+	Fails      int64  `json:"fails"`
+	UnlockTime int64  `json:"unlock_time"`
+}
+
+func getThrottleMember(scope, value string) string {
+	return scope + ":" + value
+}
+
+// 获取剩余锁定时长，未锁定时返回 0
+func GetThrottleLock(scope, value string) time.Duration {
+	ctx := context.Background()
+	ttl, err := global.RedisDb.TTL(ctx, THROTTLE_LOCK_KEY+getThrottleMember(scope, value)).Result()
+	if err != nil {
+		log.Printf("查询锁定状态异常: %s", err)
+		return 0
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// 记录一次失败，返回累计失败次数
+func IncrThrottleFail(scope, value string) int64 {
+	ctx := context.Background()
+	key := THROTTLE_FAIL_KEY + getThrottleMember(scope, value)
+	cnt, err := global.RedisDb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("更新失败次数异常: %s", err)
+		return 0
+	}
+	global.RedisDb.Expire(ctx, key, THROTTLE_FAIL_EXPIRE)
+	return cnt
+}
+
+// 设置锁定
+func SetThrottleLock(scope, value string, d time.Duration) {
+	ctx := context.Background()
+	member := getThrottleMember(scope, value)
+	err := global.RedisDb.Set(ctx, THROTTLE_LOCK_KEY+member, 1, d).Err()
+	if err != nil {
+		log.Printf("设置锁定状态异常: %s", err)
+		return
+	}
+	global.RedisDb.ZAdd(ctx, THROTTLE_LOCKOUTS_KEY, redis.Z{
+		Score:  float64(time.Now().Add(d).Unix()),
+		Member: member,
+	})
+}
+
+// 清除失败次数和锁定状态
+func ClearThrottle(scope, value string) {
+	ctx := context.Background()
+	member := getThrottleMember(scope, value)
+	global.RedisDb.Del(ctx, THROTTLE_FAIL_KEY+member, THROTTLE_LOCK_KEY+member)
+	global.RedisDb.ZRem(ctx, THROTTLE_LOCKOUTS_KEY, member)
+}
+
+// 获取当前生效的锁定记录
+func GetLockouts() ([]Lockout, error) {
+	ctx := context.Background()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	// 清理已过期的锁定记录
+	global.RedisDb.ZRemRangeByScore(ctx, THROTTLE_LOCKOUTS_KEY, "-inf", "("+now)
+	list, err := global.RedisDb.ZRangeByScoreWithScores(ctx, THROTTLE_LOCKOUTS_KEY, &redis.ZRangeBy{
+		Min: now,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	lockouts := make([]Lockout, 0, len(list))
+	for _, z := range list {
+		member, _ := z.Member.(string)
+		scope, value, _ := strings.Cut(member, ":")
+		fails, _ := global.RedisDb.Get(ctx, THROTTLE_FAIL_KEY+member).Int64()
+		lockouts = append(lockouts, Lockout{
+			Scope:      scope,
+			Value:      value,
+			Fails:      fails,
+			UnlockTime: int64(z.Score),
+		})
+	}
+	return lockouts, nil
+}
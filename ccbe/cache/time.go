@@ -59,3 +59,33 @@ func SetUserUpdateTime(uid int, newTime int64) {
 		global.SSEClientMap.PushMsg(uid, global.Rid, newTime)
 	}
 }
+
+// 批量获取用户的更新时间缓存，缓存不存在(超过有效期未更新)时不返回该用户
+func GetUsersUpdateTime(uids []int) map[int]int64 {
+	res := map[int]int64{}
+	if len(uids) == 0 {
+		return res
+	}
+	ctx := context.Background()
+	keys := make([]string, len(uids))
+	for i, uid := range uids {
+		keys[i] = getUserUpdateKey(uid)
+	}
+	vals, err := global.RedisDb.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("Redis 查询缓存异常: %s", err)
+		return res
+	}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		ti, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		res[uids[i]] = ti
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 两步验证登录挑战: 密码校验通过后下发，验证通过后换取正式 token
+const LOGIN_CHALLENGE_KEY = "login_challenge:"
+const LOGIN_CHALLENGE_EXPIRE = 300 * time.Second
+
+// 同一挑战允许的最大验证失败次数
+const LOGIN_CHALLENGE_MAX_ATTEMPTS = 5
+
+const LOGIN_CHALLENGE_ATTEMPT_KEY = "login_challenge_attempt:"
+
+// 保存登录挑战
+func SetLoginChallenge(challenge string, uid int) error {
+	ctx := context.Background()
+	return global.RedisDb.Set(ctx, LOGIN_CHALLENGE_KEY+challenge, uid, LOGIN_CHALLENGE_EXPIRE).Err()
+}
+
+// 获取登录挑战对应的 uid，不存在时返回 0
+func GetLoginChallenge(challenge string) int {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, LOGIN_CHALLENGE_KEY+challenge).Result()
+	if err == redis.Nil {
+		return 0
+	} else if err != nil {
+		log.Printf("查询登录挑战缓存异常: %s", err)
+		return 0
+	}
+	uid, _ := strconv.Atoi(str)
+	return uid
+}
+
+// 记录一次验证失败，超过次数时删除挑战，返回剩余次数
+func IncrLoginChallengeAttempt(challenge string) int {
+	ctx := context.Background()
+	key := LOGIN_CHALLENGE_ATTEMPT_KEY + challenge
+	cnt, err := global.RedisDb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("更新登录挑战失败次数异常: %s", err)
+	}
+	global.RedisDb.Expire(ctx, key, LOGIN_CHALLENGE_EXPIRE)
+	left := LOGIN_CHALLENGE_MAX_ATTEMPTS - int(cnt)
+	if left <= 0 {
+		ClearLoginChallenge(challenge)
+		return 0
+	}
+	return left
+}
+
+// 清除登录挑战
+func ClearLoginChallenge(challenge string) {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, LOGIN_CHALLENGE_KEY+challenge, LOGIN_CHALLENGE_ATTEMPT_KEY+challenge)
+}
@@ -2,14 +2,26 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"cc/be/global"
 	"cc/be/resp"
+	"sort"
+	"strconv"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 const QINIU_UPLOAD_KEY = "qiniu_upload:"
 
+// 分片上传任务，key 中包含 uid，只能查询自己的任务
+const UPLOAD_SESSION_KEY = "upload_session:"
+const UPLOAD_SESSION_EXPIRE = 24 * time.Hour
+
+// 未引用文件清理任务锁，避免多实例重复执行
+const FILE_GC_WORKER_KEY = "file_gc_worker"
+
 func getUploadTokenKey(uidStr string) string {
 	return QINIU_UPLOAD_KEY + uidStr
 }
@@ -36,3 +48,82 @@ func SetUploadToken(uidStr string, data *resp.UploadToken) {
 	}
 	global.RedisDb.ExpireAt(ctx, key, time.Unix(data.ExpireTime, 0))
 }
+
+func getUploadSessionKey(uid int, uploadId string) string {
+	return UPLOAD_SESSION_KEY + strconv.Itoa(uid) + ":" + uploadId
+}
+
+// 保存分片上传任务
+func SetUploadSession(uid int, s *resp.UploadSession) error {
+	ctx := context.Background()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return global.RedisDb.Set(ctx, getUploadSessionKey(uid, s.UploadId), data, UPLOAD_SESSION_EXPIRE).Err()
+}
+
+// 获取分片上传任务，不存在或已过期时返回 nil
+func GetUploadSession(uid int, uploadId string) *resp.UploadSession {
+	ctx := context.Background()
+	str, err := global.RedisDb.Get(ctx, getUploadSessionKey(uid, uploadId)).Result()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		log.Printf("查询分片上传缓存异常: %s", err)
+		return nil
+	}
+	s := &resp.UploadSession{}
+	if err := json.Unmarshal([]byte(str), s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// 记录已上传的分片序号，与任务同时过期
+func AddUploadChunk(uid int, uploadId string, index int) error {
+	ctx := context.Background()
+	key := getUploadSessionKey(uid, uploadId) + ":chunks"
+	if err := global.RedisDb.SAdd(ctx, key, index).Err(); err != nil {
+		return err
+	}
+	return global.RedisDb.Expire(ctx, key, UPLOAD_SESSION_EXPIRE).Err()
+}
+
+// 获取已上传的分片序号
+func GetUploadChunks(uid int, uploadId string) []int {
+	ctx := context.Background()
+	list, err := global.RedisDb.SMembers(ctx, getUploadSessionKey(uid, uploadId)+":chunks").Result()
+	if err != nil {
+		log.Printf("查询分片上传缓存异常: %s", err)
+	}
+	res := make([]int, 0, len(list))
+	for _, v := range list {
+		if i, err := strconv.Atoi(v); err == nil {
+			res = append(res, i)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// 删除分片上传任务
+func DelUploadSession(uid int, uploadId string) {
+	ctx := context.Background()
+	key := getUploadSessionKey(uid, uploadId)
+	if err := global.RedisDb.Del(ctx, key, key+":chunks").Err(); err != nil {
+		log.Printf("Redis 删除分片上传缓存异常: %s", err)
+	}
+}
+
+// 获取未引用文件清理任务锁
+func LockFileGCWorker(d time.Duration) bool {
+	ctx := context.Background()
+	ok, err := global.RedisDb.SetNX(ctx, FILE_GC_WORKER_KEY, 1, d).Result()
+	return err == nil && ok
+}
+
+func UnlockFileGCWorker() {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, FILE_GC_WORKER_KEY)
+}
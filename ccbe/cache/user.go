@@ -57,6 +57,20 @@ func UpdateUserFsize(uid int, size int64) {
 	global.RedisDb.Expire(ctx, key, USER_INFO_EXPIRE)
 }
 
+// 设置用户文件上传容量，没有用户信息缓存时不处理
+func SetUserFsize(uid int, size int64) {
+	ctx := context.Background()
+	key := getUserInfoKey(uid)
+	n, err := global.RedisDb.Exists(ctx, key).Result()
+	if err != nil || n == 0 {
+		return
+	}
+	err = global.RedisDb.HSet(ctx, key, "fsize", size).Err()
+	if err != nil {
+		log.Printf("更新用户文件容量缓存异常: %s", err)
+	}
+}
+
 // 清除用户信息缓存
 func ClearUserInfo(uid int) {
 	ctx := context.Background()
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"cc/be/global"
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 验证码: hash 结构，code-验证码摘要，attempts-已校验失败次数
+const VERIFY_CODE_KEY = "verify_code:"
+const VERIFY_CODE_EXPIRE = 600 * time.Second
+
+// 同一验证码允许的最大校验失败次数
+const VERIFY_CODE_MAX_ATTEMPTS = 5
+
+// 同一接收方的发送间隔
+const VERIFY_SEND_KEY = "verify_send:"
+const VERIFY_SEND_INTERVAL = 60 * time.Second
+
+// 每日发送次数: 按接收方和 IP 分别计数
+const VERIFY_DAILY_KEY = "verify_daily:"
+const VERIFY_DAILY_EXPIRE = 86400 * time.Second
+
+func getVerifyCodeKey(purpose, target string) string {
+	return VERIFY_CODE_KEY + purpose + ":" + target
+}
+
+// 保存验证码，同时重置失败次数
+func SetVerifyCode(purpose, target, hash string) error {
+	ctx := context.Background()
+	key := getVerifyCodeKey(purpose, target)
+	pipe := global.RedisDb.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "code", hash, "attempts", 0)
+	pipe.Expire(ctx, key, VERIFY_CODE_EXPIRE)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// 获取验证码摘要，不存在时返回空字符串
+func GetVerifyCode(purpose, target string) string {
+	ctx := context.Background()
+	hash, err := global.RedisDb.HGet(ctx, getVerifyCodeKey(purpose, target), "code").Result()
+	if err == redis.Nil {
+		return ""
+	} else if err != nil {
+		log.Printf("查询验证码缓存异常: %s", err)
+		return ""
+	}
+	return hash
+}
+
+// 记录一次校验失败，超过次数时删除验证码，返回剩余次数
+func IncrVerifyAttempt(purpose, target string) int {
+	ctx := context.Background()
+	key := getVerifyCodeKey(purpose, target)
+	cnt, err := global.RedisDb.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		log.Printf("更新验证码失败次数异常: %s", err)
+	}
+	left := VERIFY_CODE_MAX_ATTEMPTS - int(cnt)
+	if left <= 0 {
+		ClearVerifyCode(purpose, target)
+		return 0
+	}
+	return left
+}
+
+// 清除验证码
+func ClearVerifyCode(purpose, target string) {
+	ctx := context.Background()
+	global.RedisDb.Del(ctx, getVerifyCodeKey(purpose, target))
+}
+
+// 检查并占用发送间隔，间隔内重复发送时返回 false
+func LockVerifySend(target string) bool {
+	ctx := context.Background()
+	ok, err := global.RedisDb.SetNX(ctx, VERIFY_SEND_KEY+target, 1, VERIFY_SEND_INTERVAL).Result()
+	if err != nil {
+		log.Printf("更新验证码发送间隔异常: %s", err)
+		return false
+	}
+	return ok
+}
+
+// 增加每日发送次数并返回当前次数
+func IncrVerifyDaily(key string) int64 {
+	ctx := context.Background()
+	key = VERIFY_DAILY_KEY + key
+	cnt, err := global.RedisDb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("更新验证码发送次数异常: %s", err)
+		return 0
+	}
+	if cnt == 1 {
+		global.RedisDb.Expire(ctx, key, VERIFY_DAILY_EXPIRE)
+	}
+	return cnt
+}
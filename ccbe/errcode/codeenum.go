@@ -13,6 +13,7 @@ var (
 	TokenParamEmpty = NewError(1001, "Token 参数为空")
 	TokenExpired    = NewError(1002, "Token 已过期")
 	TokenParseError = NewError(1003, "Token 解析异常")
+	TokenScopeError = NewError(1004, "Token 无权访问该接口")
 
 	// 业务异常错误码
 	// 用户 & Token
@@ -20,24 +21,89 @@ var (
 	GenerateTokenError       = NewError(2002, "生成 token 异常")
 	UpdateTokenStatusError   = NewError(2003, "更新 token 使用状态异常")
 	LoginCodeInfoError       = NewError(2004, "登陆码信息异常")
-	AdminAuthError           = NewError(2005, "非超级管理员，禁止进行该操作")
+	PermissionError          = NewError(2005, "无权限进行该操作")
 	RegisterError            = NewError(2006, "账号注册异常")
 	GenerateCodeError        = NewError(2007, "生成邀请码异常")
 	WechatCallbackUserError  = NewError(2008, "微信回调用户登录异常")
-	BindWechatError          = NewError(2009, "绑定微信异常")
+	BindIdentityError        = NewError(2009, "绑定第三方账号异常")
 	UpdateMobileAccountError = NewError(2010, "更新手机账号异常")
+	AuthorizeURLError        = NewError(2011, "获取第三方授权地址异常")
+	QueryIdentityError       = NewError(2012, "查询第三方账号绑定异常")
+	UnbindIdentityError      = NewError(2013, "解除第三方账号绑定异常")
+	ThrottleLockedError      = NewError(2014, "尝试次数过多，已临时锁定")
+	SendCodeError            = NewError(2015, "发送验证码异常")
+	ResetPasswordError       = NewError(2016, "重置密码异常")
+	QueryDeletionError       = NewError(2017, "查询注销申请异常")
+	RequestDeletionError     = NewError(2018, "申请注销账号异常")
+	CancelDeletionError      = NewError(2019, "撤销注销申请异常")
 	QueryUserError           = NewError(2020, "查询用户信息异常")
 	// 视图分享
-	QueryShareError        = NewError(2030, "查询视图分享信息异常")
-	CreateShareError       = NewError(2031, "创建视图分享失败")
-	UpdateShareStatusError = NewError(2032, "更新视图分享状态失败")
-	QueryShareNullError    = NewError(2033, "未查询分享信息")
-	ShareStatusError       = NewError(2034, "当前分享已取消")
+	QueryShareError            = NewError(2030, "查询视图分享信息异常")
+	CreateShareError           = NewError(2031, "创建视图分享失败")
+	UpdateShareStatusError     = NewError(2032, "更新视图分享状态失败")
+	QueryShareNullError        = NewError(2033, "未查询分享信息")
+	ShareStatusError           = NewError(2034, "当前分享已取消")
+	ShareExpiredError          = NewError(2035, "当前分享已过期")
+	ShareViewLimitError        = NewError(2036, "当前分享已达到访问次数上限")
+	SharePasswordRequiredError = NewError(2037, "当前分享需要输入访问密码")
+	ShareAccessError           = NewError(2038, "分享访问密码校验失败")
+	QueryShareStatsError       = NewError(2039, "查询分享访问统计异常")
+	// 角色权限 & 审计日志
+	GrantRoleError     = NewError(2041, "授予角色异常")
+	RevokeRoleError    = NewError(2042, "撤销角色异常")
+	QueryRoleError     = NewError(2043, "查询用户角色异常")
+	QueryAuditlogError = NewError(2044, "查询审计日志异常")
+	QueryLockoutError  = NewError(2045, "查询锁定记录异常")
+	UnlockError        = NewError(2046, "解除锁定异常")
+	// 文件下载
+	DownloadFileError = NewError(2047, "获取文件下载地址异常")
+	// 个人访问令牌
+	QueryAccessTokenError  = NewError(2051, "查询访问令牌异常")
+	CreateAccessTokenError = NewError(2052, "创建访问令牌异常")
+	RevokeAccessTokenError = NewError(2053, "撤销访问令牌异常")
+	// 两步验证
+	EnrollTotpError   = NewError(2061, "绑定认证器异常")
+	ActivateTotpError = NewError(2062, "启用两步验证异常")
+	DisableTotpError  = NewError(2063, "关闭两步验证异常")
+	RecoveryCodeError = NewError(2064, "生成恢复码异常")
+	TotpVerifyError   = NewError(2065, "两步验证失败")
+	ResetTotpError    = NewError(2066, "重置两步验证异常")
+	// 邀请码 & 邀请关系
+	QueryInviteError     = NewError(2071, "查询邀请码异常")
+	RevokeInviteError    = NewError(2072, "作废邀请码异常")
+	QueryRedemptionError = NewError(2073, "查询邀请码使用记录异常")
+	QueryInviteeError    = NewError(2074, "查询邀请记录异常")
+	QueryReferralError   = NewError(2075, "查询邀请关系异常")
+	// 分享历史版本
+	QueryShareRevisionError    = NewError(2076, "查询分享历史版本异常")
+	PinShareRevisionError      = NewError(2077, "固定分享版本失败")
+	RollbackShareRevisionError = NewError(2078, "回滚分享版本失败")
+	// 文件库
+	QueryFilesError  = NewError(2079, "查询文件列表异常")
+	DeleteFilesError = NewError(2080, "删除文件失败")
+	// 共享空间
+	QuerySpaceMemberError  = NewError(2081, "查询空间成员异常")
+	InviteSpaceMemberError = NewError(2082, "邀请空间成员失败")
+	UpdateSpaceMemberError = NewError(2083, "修改空间成员角色失败")
+	RemoveSpaceMemberError = NewError(2084, "移除空间成员失败")
+	QuerySpaceInviteError  = NewError(2085, "查询空间邀请异常")
+	HandleSpaceInviteError = NewError(2086, "处理空间邀请失败")
+	// 存储额度
+	QueryQuotaError       = NewError(2087, "查询存储用量异常")
+	UpdateQuotaError      = NewError(2088, "修改存储额度失败")
+	QuotaExceededError    = NewError(2089, "存储空间已超出额度")
+	QuotaSoftLimitWarning = NewError(2090, "存储空间即将用完")
 	// 上传文件
 	UploadTokenError             = NewError(2091, "获取文件上传凭证异常")
 	QiniuCallbackAuthVerifyError = NewError(2092, "七牛文件上传回调auth校验异常")
 	QiniuCallbackAuthError       = NewError(2093, "七牛文件上传回调auth校验失败")
 	QiniuCallbackError           = NewError(2094, "七牛文件上传回调异常")
+	UploadFileError              = NewError(2095, "文件上传失败")
+	UploadCompleteError          = NewError(2096, "登记上传文件异常")
+	InitChunkUploadError         = NewError(2097, "创建分片上传任务失败")
+	UploadChunkError             = NewError(2098, "上传分片失败")
+	QueryChunkUploadError        = NewError(2099, "查询分片上传任务异常")
+	DeleteFileError              = NewError(2100, "删除文件失败")
 	// 节点类型
 	NodeTypeListError = NewError(2101, "查询节点类型列表异常")
 	// 节点分组
@@ -15,7 +15,11 @@ var (
 	JwtSetting        *setting.JwtSetting
 	DatabaseSetting   *setting.DatabaseSetting
 	RedisSetting      *setting.RedisSetting
-	QiniuSetting      *setting.QiniuSetting
+	StorageSetting    *setting.StorageSetting
+	QuotaSetting      *setting.QuotaSetting
+	IdentitySetting   *setting.IdentitySetting
+	SenderSetting     *setting.SenderSetting
+	ShareSetting      *setting.ShareSetting
 )
 
 var (
@@ -1,7 +1,150 @@
 package graph
 
+import (
+	"context"
+	"errors"
+	"log"
+
+	"cc/be/app"
+	"cc/be/model"
+	"cc/be/service"
+)
+
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct{}
+
+// 校验个人访问令牌的权限范围，登录会话不受限制
+func checkScope(ctx context.Context, scope string) error {
+	auth := app.GetAuth(ctx)
+	if auth == nil {
+		return errors.New("未登录")
+	}
+	if !model.HasScope(auth.Scopes, scope) {
+		return errors.New("访问令牌缺少权限范围: " + scope)
+	}
+	return nil
+}
+
+// 当前请求的登录用户
+func authUid(ctx context.Context) int {
+	if auth := app.GetAuth(ctx); auth != nil {
+		return auth.Uid
+	}
+	return 0
+}
+
+// 推送数据的写入目标，共享空间的数据写入空间所有者名下
+type pushTarget struct {
+	srv    service.Service
+	uid    int
+	shared map[string]*model.Spacemember
+	// 数据所在的 uid -> 本次推送涉及的空间 id
+	spaces map[int][]string
+	seen   map[string]bool
+	views  map[string]model.View
+}
+
+func newPushTarget(ctx context.Context, srv service.Service) (*pushTarget, error) {
+	uid := authUid(ctx)
+	shared, err := srv.GetJoinedSpaces(uid)
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	return &pushTarget{srv: srv, uid: uid, shared: shared, spaces: map[int][]string{}, seen: map[string]bool{}}, nil
+}
+
+// 是否为已加入的其他用户的共享空间
+func (p *pushTarget) isShared(spaceId string) bool {
+	_, ok := p.shared[spaceId]
+	return ok
+}
+
+// 获取空间数据所在的 uid，查看者无权修改共享空间的数据
+func (p *pushTarget) ownerOf(spaceId string) (int, error) {
+	owner := p.uid
+	if m, ok := p.shared[spaceId]; ok {
+		if m.Role == model.SPACE_ROLE_VIEWER {
+			return 0, errors.New("无权修改共享空间数据")
+		}
+		owner = m.OwnerUid
+	}
+	if spaceId != "" && !p.seen[spaceId] {
+		p.seen[spaceId] = true
+		p.spaces[owner] = append(p.spaces[owner], spaceId)
+	}
+	return owner, nil
+}
+
+// 查询视图所在的用户和空间
+func (p *pushTarget) loadViews(viewIds []string) error {
+	uids := []int{p.uid}
+	for _, m := range p.shared {
+		uids = append(uids, m.OwnerUid)
+	}
+	mv := &model.View{}
+	list, err := mv.GetViewSpaces(uids, viewIds)
+	if err != nil {
+		return errors.New("查询数据异常")
+	}
+	p.views = make(map[string]model.View, len(*list))
+	for _, v := range *list {
+		p.views[v.Id] = v
+	}
+	return nil
+}
+
+// 获取视图数据所在的 uid，需先调用 loadViews
+func (p *pushTarget) ownerOfView(viewId string) (int, error) {
+	v, ok := p.views[viewId]
+	if !ok {
+		// 视图尚未推送，加入共享空间时无法确定写入位置，由客户端重试
+		if len(p.shared) > 0 {
+			return 0, errors.New("视图数据未同步，请稍后重试")
+		}
+		return p.uid, nil
+	}
+	owner, err := p.ownerOf(v.SpaceId)
+	if err != nil {
+		return 0, err
+	}
+	if owner != v.Uid {
+		return 0, errors.New("无权修改共享空间数据")
+	}
+	return owner, nil
+}
+
+// 代替空间所有者写入时，返回不能写入的已存在数据
+// 已存在的数据必须位于已加入且可编辑的共享空间，防止覆盖所有者私有空间的数据，id 不存在时可以新增
+func (p *pushTarget) rejected(owner int, table string, ids []string) (map[string]bool, error) {
+	if owner == p.uid || len(ids) == 0 {
+		return nil, nil
+	}
+	var spaces map[string]string
+	var err error
+	switch table {
+	case "viewnode", "viewedge":
+		spaces, err = model.GetViewRowSpaces(table, owner, ids)
+	default:
+		spaces, err = model.GetRowSpaces(table, owner, ids)
+	}
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	rejected := map[string]bool{}
+	for id, spaceId := range spaces {
+		m, ok := p.shared[spaceId]
+		if !ok || m.OwnerUid != owner || m.Role == model.SPACE_ROLE_VIEWER {
+			log.Printf("拒绝写入共享空间外的数据 [%d] %s: %s", p.uid, table, id)
+			rejected[id] = true
+		}
+	}
+	return rejected, nil
+}
+
+// 刷新更新时间缓存，通知共享空间的所有成员
+func (p *pushTarget) notify(owner int, t int64) {
+	p.srv.NotifySpaceUpdate(owner, p.spaces[owner], t)
+}
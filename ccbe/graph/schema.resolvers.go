@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"cc/be/cache"
 	"cc/be/conv"
-	"cc/be/global"
 	"cc/be/graph/generated"
 	"cc/be/graph/gmodel"
 	"cc/be/model"
@@ -19,9 +18,29 @@ import (
 
 // PushSpace is the resolver for the pushSpace field.
 func (r *mutationResolver) PushSpace(ctx context.Context, spacePushRow []*gmodel.SpaceInputPushRow) ([]*gmodel.Space, error) {
-	uid := global.Uid
-	var ids []string
+	if err := checkScope(ctx, model.SCOPE_WRITE_CARDS); err != nil {
+		return nil, err
+	}
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*gmodel.SpaceInputPushRow
 	for _, row := range spacePushRow {
+		// 共享空间的信息仅所有者可修改
+		if target.isShared(row.NewDocumentState.ID) {
+			continue
+		}
+		target.ownerOf(row.NewDocumentState.ID)
+		rows = append(rows, row)
+	}
+	if len(rows) <= 0 {
+		return nil, nil
+	}
+	uid := authUid(ctx)
+	var ids []string
+	for _, row := range rows {
 		ids = append(ids, row.NewDocumentState.ID)
 	}
 	s := &model.Space{}
@@ -33,7 +52,7 @@ func (r *mutationResolver) PushSpace(ctx context.Context, spacePushRow []*gmodel
 	var insertList []*model.Space
 	var updateList []*model.Space
 	var ut int64 = 0
-	for _, row := range spacePushRow {
+	for _, row := range rows {
 		tmp, _ := conv.SpaceDocToModel(uid, row.NewDocumentState)
 		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
 			if updateTime < tmp.UpdateTime {
@@ -53,15 +72,18 @@ func (r *mutationResolver) PushSpace(ctx context.Context, spacePushRow []*gmodel
 		err = s.UpdateSpaces(&updateList)
 		fmt.Println("update spaces err", err)
 	}
-	// 刷新更新时间缓存
-	cache.SetUserUpdateTime(uid, ut)
+	// 刷新更新时间缓存，通知共享空间的所有成员
+	target.notify(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PushType is the resolver for the pushType field.
 func (r *mutationResolver) PushType(ctx context.Context, typePushRow []*gmodel.TypeInputPushRow) ([]*gmodel.Type, error) {
-	uid := global.Uid
+	if err := checkScope(ctx, model.SCOPE_WRITE_CARDS); err != nil {
+		return nil, err
+	}
+	uid := authUid(ctx)
 	var ids []string
 	for _, row := range typePushRow {
 		ids = append(ids, row.NewDocumentState.ID)
@@ -105,226 +127,373 @@ func (r *mutationResolver) PushType(ctx context.Context, typePushRow []*gmodel.T
 
 // PushCard is the resolver for the pushCard field.
 func (r *mutationResolver) PushCard(ctx context.Context, cardPushRow []*gmodel.CardInputPushRow) ([]*gmodel.Card, error) {
-	uid := global.Uid
-	var ids []string
-	for _, row := range cardPushRow {
-		ids = append(ids, row.NewDocumentState.ID)
+	if err := checkScope(ctx, model.SCOPE_WRITE_CARDS); err != nil {
+		return nil, err
 	}
-	t := &model.Card{}
-	checkMap, err := t.GetCardCheckMap(uid, ids)
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
 	if err != nil {
-		return nil, errors.New("查询数据异常")
+		return nil, err
 	}
-	// 判断数据库
-	var insertList []*model.Card
-	var updateIds []string
-	var updateList []*model.Card
-	var ut int64 = 0
+	// 按数据所在的 uid 分组，共享空间的数据写入空间所有者名下
+	groups := map[int][]*gmodel.CardInputPushRow{}
 	for _, row := range cardPushRow {
-		tmp, _ := conv.CardDocToModel(uid, row.NewDocumentState)
-		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
-			if updateTime < tmp.UpdateTime {
+		owner, err := target.ownerOf(row.NewDocumentState.SpaceID)
+		if err != nil {
+			return nil, err
+		}
+		groups[owner] = append(groups[owner], row)
+	}
+	for uid, rows := range groups {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.NewDocumentState.ID)
+		}
+		t := &model.Card{}
+		checkMap, err := t.GetCardCheckMap(uid, ids)
+		if err != nil {
+			return nil, errors.New("查询数据异常")
+		}
+		rejected, err := target.rejected(uid, "card", ids)
+		if err != nil {
+			return nil, err
+		}
+		// 判断数据库
+		var insertList []*model.Card
+		var updateIds []string
+		var updateList []*model.Card
+		var ut int64 = 0
+		for _, row := range rows {
+			tmp, _ := conv.CardDocToModel(uid, row.NewDocumentState)
+			// 不能覆盖所有者在其他空间的数据
+			if rejected[tmp.Id] {
+				continue
+			}
+			if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
+				if updateTime < tmp.UpdateTime {
+					ut = utils.MaxTime(ut, tmp.UpdateTime)
+					updateIds = append(updateIds, tmp.Id)
+					updateList = append(updateList, tmp)
+				}
+			} else { // 新增
 				ut = utils.MaxTime(ut, tmp.UpdateTime)
-				updateIds = append(updateIds, tmp.Id)
-				updateList = append(updateList, tmp)
+				insertList = append(insertList, tmp)
 			}
-		} else { // 新增
-			ut = utils.MaxTime(ut, tmp.UpdateTime)
-			insertList = append(insertList, tmp)
 		}
+		if len(insertList) > 0 {
+			err = srv.CreateCards(&insertList)
+			fmt.Println("create cards err", err)
+		}
+		if len(updateList) > 0 {
+			err = srv.UpdateCards(uid, &updateList, &updateIds)
+			fmt.Println("update cards err", err)
+		}
+		// 刷新更新时间缓存，通知共享空间的所有成员
+		target.notify(uid, ut)
 	}
-	srv := service.New(ctx)
-	if len(insertList) > 0 {
-		err = srv.CreateCards(&insertList)
-		fmt.Println("create cards err", err)
-	}
-	if len(updateList) > 0 {
-		err = srv.UpdateCards(uid, &updateList, &updateIds)
-		fmt.Println("update cards err", err)
-	}
-	cache.SetUserUpdateTime(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PushTag is the resolver for the pushTag field.
 func (r *mutationResolver) PushTag(ctx context.Context, tagPushRow []*gmodel.TagInputPushRow) ([]*gmodel.Tag, error) {
-	uid := global.Uid
-	var ids []string
-	for _, row := range tagPushRow {
-		ids = append(ids, row.NewDocumentState.ID)
+	if err := checkScope(ctx, model.SCOPE_WRITE_CARDS); err != nil {
+		return nil, err
 	}
-	m := &model.Tag{}
-	checkMap, err := m.GetTagCheckMap(uid, ids)
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
 	if err != nil {
-		return nil, errors.New("查询数据异常")
+		return nil, err
 	}
-	// 判断数据库
-	var insertList []*model.Tag
-	var updateList []*model.Tag
-	var ut int64 = 0
+	// 按数据所在的 uid 分组，共享空间的数据写入空间所有者名下
+	groups := map[int][]*gmodel.TagInputPushRow{}
 	for _, row := range tagPushRow {
-		tmp, _ := conv.TagDocToModel(uid, row.NewDocumentState)
-		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
-			if updateTime < tmp.UpdateTime {
+		owner, err := target.ownerOf(row.NewDocumentState.SpaceID)
+		if err != nil {
+			return nil, err
+		}
+		groups[owner] = append(groups[owner], row)
+	}
+	for uid, rows := range groups {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.NewDocumentState.ID)
+		}
+		m := &model.Tag{}
+		checkMap, err := m.GetTagCheckMap(uid, ids)
+		if err != nil {
+			return nil, errors.New("查询数据异常")
+		}
+		rejected, err := target.rejected(uid, "tag", ids)
+		if err != nil {
+			return nil, err
+		}
+		// 判断数据库
+		var insertList []*model.Tag
+		var updateList []*model.Tag
+		var ut int64 = 0
+		for _, row := range rows {
+			tmp, _ := conv.TagDocToModel(uid, row.NewDocumentState)
+			// 不能覆盖所有者在其他空间的数据
+			if rejected[tmp.Id] {
+				continue
+			}
+			if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
+				if updateTime < tmp.UpdateTime {
+					ut = utils.MaxTime(ut, tmp.UpdateTime)
+					updateList = append(updateList, tmp)
+				}
+			} else { // 新增
 				ut = utils.MaxTime(ut, tmp.UpdateTime)
-				updateList = append(updateList, tmp)
+				insertList = append(insertList, tmp)
 			}
-		} else { // 新增
-			ut = utils.MaxTime(ut, tmp.UpdateTime)
-			insertList = append(insertList, tmp)
 		}
+		if len(insertList) > 0 {
+			err = m.CreateTags(&insertList)
+			fmt.Println("create tags err", err)
+		}
+		if len(updateList) > 0 {
+			err = m.UpdateTags(&updateList)
+			fmt.Println("update tags err", err)
+		}
+		// 刷新更新时间缓存，通知共享空间的所有成员
+		target.notify(uid, ut)
 	}
-	if len(insertList) > 0 {
-		err = m.CreateTags(&insertList)
-		fmt.Println("create tags err", err)
-	}
-	if len(updateList) > 0 {
-		err = m.UpdateTags(&updateList)
-		fmt.Println("update tags err", err)
-	}
-	// 刷新更新时间缓存
-	cache.SetUserUpdateTime(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PushView is the resolver for the pushView field.
 func (r *mutationResolver) PushView(ctx context.Context, viewPushRow []*gmodel.ViewInputPushRow) ([]*gmodel.View, error) {
-	uid := global.Uid
-	var ids []string
-	for _, row := range viewPushRow {
-		ids = append(ids, row.NewDocumentState.ID)
+	if err := checkScope(ctx, model.SCOPE_WRITE_VIEWS); err != nil {
+		return nil, err
 	}
-	t := &model.View{}
-	checkMap, err := t.GetViewCheckMap(uid, ids)
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
 	if err != nil {
-		return nil, errors.New("查询数据异常")
+		return nil, err
 	}
-	// 判断数据库
-	var insertList []*model.View
-	var updateIds []string
-	var updateList []*model.View
-	var contentMap = make(map[string]string)
-	var ut int64 = 0
+	// 按数据所在的 uid 分组，共享空间的数据写入空间所有者名下
+	groups := map[int][]*gmodel.ViewInputPushRow{}
 	for _, row := range viewPushRow {
-		tmp, _ := conv.ViewDocToModel(uid, row.NewDocumentState)
-		contentMap[tmp.Id] = row.NewDocumentState.Content
-		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
-			if updateTime < tmp.UpdateTime {
+		owner, err := target.ownerOf(row.NewDocumentState.SpaceID)
+		if err != nil {
+			return nil, err
+		}
+		groups[owner] = append(groups[owner], row)
+	}
+	for uid, rows := range groups {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.NewDocumentState.ID)
+		}
+		t := &model.View{}
+		checkMap, err := t.GetViewCheckMap(uid, ids)
+		if err != nil {
+			return nil, errors.New("查询数据异常")
+		}
+		rejected, err := target.rejected(uid, "view", ids)
+		if err != nil {
+			return nil, err
+		}
+		// 判断数据库
+		var insertList []*model.View
+		var updateIds []string
+		var updateList []*model.View
+		var contentMap = make(map[string]string)
+		var ut int64 = 0
+		for _, row := range rows {
+			tmp, _ := conv.ViewDocToModel(uid, row.NewDocumentState)
+			// 不能覆盖所有者在其他空间的数据
+			if rejected[tmp.Id] {
+				continue
+			}
+			contentMap[tmp.Id] = row.NewDocumentState.Content
+			if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
+				if updateTime < tmp.UpdateTime {
+					ut = utils.MaxTime(ut, tmp.UpdateTime)
+					updateIds = append(updateIds, tmp.Id)
+					updateList = append(updateList, tmp)
+				}
+			} else { // 新增
 				ut = utils.MaxTime(ut, tmp.UpdateTime)
-				updateIds = append(updateIds, tmp.Id)
-				updateList = append(updateList, tmp)
+				insertList = append(insertList, tmp)
 			}
-		} else { // 新增
-			ut = utils.MaxTime(ut, tmp.UpdateTime)
-			insertList = append(insertList, tmp)
 		}
+		if len(insertList) > 0 {
+			err = srv.CreateViews(&insertList, &contentMap)
+			fmt.Println("create views err", err)
+		}
+		if len(updateList) > 0 {
+			err = srv.UpdateViews(uid, &updateList, &updateIds, &contentMap)
+			fmt.Println("update views err", err)
+		}
+		// 刷新更新时间缓存，通知共享空间的所有成员
+		target.notify(uid, ut)
 	}
-	srv := service.New(ctx)
-	if len(insertList) > 0 {
-		err = srv.CreateViews(&insertList, &contentMap)
-		fmt.Println("create views err", err)
-	}
-	if len(updateList) > 0 {
-		err = srv.UpdateViews(uid, &updateList, &updateIds, &contentMap)
-		fmt.Println("update views err", err)
-	}
-	cache.SetUserUpdateTime(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PushViewnode is the resolver for the pushViewnode field.
 func (r *mutationResolver) PushViewnode(ctx context.Context, viewnodePushRow []*gmodel.ViewnodeInputPushRow) ([]*gmodel.Viewnode, error) {
-	uid := global.Uid
-	var ids []string
-	for _, row := range viewnodePushRow {
-		ids = append(ids, row.NewDocumentState.ID)
+	if err := checkScope(ctx, model.SCOPE_WRITE_VIEWS); err != nil {
+		return nil, err
 	}
-	t := &model.Viewnode{}
-	checkMap, err := t.GetViewnodeCheckMap(uid, ids)
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
 	if err != nil {
-		return nil, errors.New("查询数据异常")
+		return nil, err
 	}
-	// 判断数据库
-	var insertList []*model.Viewnode
-	var updateIds []string
-	var updateList []*model.Viewnode
-	var ut int64 = 0
+	var viewIds []string
 	for _, row := range viewnodePushRow {
-		tmp, _ := conv.ViewnodeDocToModel(uid, row.NewDocumentState)
-		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
-			if updateTime < tmp.UpdateTime {
+		viewIds = append(viewIds, row.NewDocumentState.ViewID)
+	}
+	if err := target.loadViews(viewIds); err != nil {
+		return nil, err
+	}
+	// 按数据所在的 uid 分组，共享空间的数据写入空间所有者名下
+	groups := map[int][]*gmodel.ViewnodeInputPushRow{}
+	for _, row := range viewnodePushRow {
+		owner, err := target.ownerOfView(row.NewDocumentState.ViewID)
+		if err != nil {
+			return nil, err
+		}
+		groups[owner] = append(groups[owner], row)
+	}
+	for uid, rows := range groups {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.NewDocumentState.ID)
+		}
+		t := &model.Viewnode{}
+		checkMap, err := t.GetViewnodeCheckMap(uid, ids)
+		if err != nil {
+			return nil, errors.New("查询数据异常")
+		}
+		rejected, err := target.rejected(uid, "viewnode", ids)
+		if err != nil {
+			return nil, err
+		}
+		// 判断数据库
+		var insertList []*model.Viewnode
+		var updateIds []string
+		var updateList []*model.Viewnode
+		var ut int64 = 0
+		for _, row := range rows {
+			tmp, _ := conv.ViewnodeDocToModel(uid, row.NewDocumentState)
+			// 不能覆盖所有者在其他空间的数据
+			if rejected[tmp.Id] {
+				continue
+			}
+			if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
+				if updateTime < tmp.UpdateTime {
+					ut = utils.MaxTime(ut, tmp.UpdateTime)
+					updateIds = append(updateIds, tmp.Id)
+					updateList = append(updateList, tmp)
+				}
+			} else { // 新增
 				ut = utils.MaxTime(ut, tmp.UpdateTime)
-				updateIds = append(updateIds, tmp.Id)
-				updateList = append(updateList, tmp)
+				insertList = append(insertList, tmp)
 			}
-		} else { // 新增
-			ut = utils.MaxTime(ut, tmp.UpdateTime)
-			insertList = append(insertList, tmp)
 		}
+		if len(insertList) > 0 {
+			err = srv.CreateViewnodes(&insertList)
+			fmt.Println("create viewnodes err", err)
+		}
+		if len(updateList) > 0 {
+			err = srv.UpdateViewnodes(uid, &updateList, &updateIds)
+			fmt.Println("update viewnodes err", err)
+		}
+		// 刷新更新时间缓存，通知共享空间的所有成员
+		target.notify(uid, ut)
 	}
-	srv := service.New(ctx)
-	if len(insertList) > 0 {
-		err = srv.CreateViewnodes(&insertList)
-		fmt.Println("create viewnodes err", err)
-	}
-	if len(updateList) > 0 {
-		err = srv.UpdateViewnodes(uid, &updateList, &updateIds)
-		fmt.Println("update viewnodes err", err)
-	}
-	cache.SetUserUpdateTime(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PushViewedge is the resolver for the pushViewedge field.
 func (r *mutationResolver) PushViewedge(ctx context.Context, viewedgePushRow []*gmodel.ViewedgeInputPushRow) ([]*gmodel.Viewedge, error) {
-	uid := global.Uid
-	var ids []string
-	for _, row := range viewedgePushRow {
-		ids = append(ids, row.NewDocumentState.ID)
+	if err := checkScope(ctx, model.SCOPE_WRITE_VIEWS); err != nil {
+		return nil, err
 	}
-	t := &model.Viewedge{}
-	checkMap, err := t.GetViewedgeCheckMap(uid, ids)
+	srv := service.New(ctx)
+	target, err := newPushTarget(ctx, srv)
 	if err != nil {
-		return nil, errors.New("查询数据异常")
+		return nil, err
 	}
-	// 判断数据库
-	var insertList []*model.Viewedge
-	var updateIds []string
-	var updateList []*model.Viewedge
-	var ut int64 = 0
+	var viewIds []string
 	for _, row := range viewedgePushRow {
-		tmp, _ := conv.ViewedgeDocToModel(uid, row.NewDocumentState)
-		if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
-			if updateTime < tmp.UpdateTime {
+		viewIds = append(viewIds, row.NewDocumentState.ViewID)
+	}
+	if err := target.loadViews(viewIds); err != nil {
+		return nil, err
+	}
+	// 按数据所在的 uid 分组，共享空间的数据写入空间所有者名下
+	groups := map[int][]*gmodel.ViewedgeInputPushRow{}
+	for _, row := range viewedgePushRow {
+		owner, err := target.ownerOfView(row.NewDocumentState.ViewID)
+		if err != nil {
+			return nil, err
+		}
+		groups[owner] = append(groups[owner], row)
+	}
+	for uid, rows := range groups {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.NewDocumentState.ID)
+		}
+		t := &model.Viewedge{}
+		checkMap, err := t.GetViewedgeCheckMap(uid, ids)
+		if err != nil {
+			return nil, errors.New("查询数据异常")
+		}
+		rejected, err := target.rejected(uid, "viewedge", ids)
+		if err != nil {
+			return nil, err
+		}
+		// 判断数据库
+		var insertList []*model.Viewedge
+		var updateIds []string
+		var updateList []*model.Viewedge
+		var ut int64 = 0
+		for _, row := range rows {
+			tmp, _ := conv.ViewedgeDocToModel(uid, row.NewDocumentState)
+			// 不能覆盖所有者在其他空间的数据
+			if rejected[tmp.Id] {
+				continue
+			}
+			if updateTime := (*checkMap)[tmp.Id]; updateTime > 0 { // 需要更新
+				if updateTime < tmp.UpdateTime {
+					ut = utils.MaxTime(ut, tmp.UpdateTime)
+					updateIds = append(updateIds, tmp.Id)
+					updateList = append(updateList, tmp)
+				}
+			} else { // 新增
 				ut = utils.MaxTime(ut, tmp.UpdateTime)
-				updateIds = append(updateIds, tmp.Id)
-				updateList = append(updateList, tmp)
+				insertList = append(insertList, tmp)
 			}
-		} else { // 新增
-			ut = utils.MaxTime(ut, tmp.UpdateTime)
-			insertList = append(insertList, tmp)
 		}
+		if len(insertList) > 0 {
+			err = srv.CreateViewedges(&insertList)
+			fmt.Println("create viewedges err", err)
+		}
+		if len(updateList) > 0 {
+			err = srv.UpdateViewedges(uid, &updateList, &updateIds)
+			fmt.Println("update viewedges err", err)
+		}
+		// 刷新更新时间缓存，通知共享空间的所有成员
+		target.notify(uid, ut)
 	}
-	srv := service.New(ctx)
-	if len(insertList) > 0 {
-		err = srv.CreateViewedges(&insertList)
-		fmt.Println("create viewedges err", err)
-	}
-	if len(updateList) > 0 {
-		err = srv.UpdateViewedges(uid, &updateList, &updateIds)
-		fmt.Println("update viewedges err", err)
-	}
-	cache.SetUserUpdateTime(uid, ut)
 	// 第一个参数为冲突的数据列表
 	return nil, nil
 }
 
 // PullSpace is the resolver for the pullSpace field.
 func (r *queryResolver) PullSpace(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.SpacePullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_CARDS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -333,9 +502,13 @@ func (r *queryResolver) PullSpace(ctx context.Context, checkpoint *gmodel.InputC
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
+	srv := service.New(ctx)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
 	s := &model.Space{}
-	spaces, err := s.GetSpaces(uid, minUpdateTime, limit)
+	spaces, err := s.GetSpaces(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -344,6 +517,9 @@ func (r *queryResolver) PullSpace(ctx context.Context, checkpoint *gmodel.InputC
 
 // PullType is the resolver for the pullType field.
 func (r *queryResolver) PullType(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.TypePullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_CARDS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -352,9 +528,12 @@ func (r *queryResolver) PullType(ctx context.Context, checkpoint *gmodel.InputCh
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
 	srv := service.New(ctx)
-	list, err := srv.GetTypes(uid, minUpdateTime, limit)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	list, err := srv.GetTypes(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -363,6 +542,9 @@ func (r *queryResolver) PullType(ctx context.Context, checkpoint *gmodel.InputCh
 
 // PullCard is the resolver for the pullCard field.
 func (r *queryResolver) PullCard(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.CardPullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_CARDS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -371,9 +553,12 @@ func (r *queryResolver) PullCard(ctx context.Context, checkpoint *gmodel.InputCh
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
 	srv := service.New(ctx)
-	list, err := srv.GetCards(uid, minUpdateTime, limit)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	list, err := srv.GetCards(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -382,6 +567,9 @@ func (r *queryResolver) PullCard(ctx context.Context, checkpoint *gmodel.InputCh
 
 // PullTag is the resolver for the pullTag field.
 func (r *queryResolver) PullTag(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.TagPullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_CARDS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -390,9 +578,13 @@ func (r *queryResolver) PullTag(ctx context.Context, checkpoint *gmodel.InputChe
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
+	srv := service.New(ctx)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
 	m := &model.Tag{}
-	tags, err := m.GetTags(uid, minUpdateTime, limit)
+	tags, err := m.GetTags(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -401,6 +593,9 @@ func (r *queryResolver) PullTag(ctx context.Context, checkpoint *gmodel.InputChe
 
 // PullView is the resolver for the pullView field.
 func (r *queryResolver) PullView(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.ViewPullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_VIEWS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -409,9 +604,12 @@ func (r *queryResolver) PullView(ctx context.Context, checkpoint *gmodel.InputCh
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
 	srv := service.New(ctx)
-	list, contentMap, err := srv.GetViews(uid, minUpdateTime, limit)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	list, contentMap, err := srv.GetViews(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -420,6 +618,9 @@ func (r *queryResolver) PullView(ctx context.Context, checkpoint *gmodel.InputCh
 
 // PullViewnode is the resolver for the pullViewnode field.
 func (r *queryResolver) PullViewnode(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.ViewnodePullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_VIEWS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -428,9 +629,12 @@ func (r *queryResolver) PullViewnode(ctx context.Context, checkpoint *gmodel.Inp
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
 	srv := service.New(ctx)
-	list, err := srv.GetViewnodes(uid, minUpdateTime, limit)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	list, err := srv.GetViewnodes(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -439,6 +643,9 @@ func (r *queryResolver) PullViewnode(ctx context.Context, checkpoint *gmodel.Inp
 
 // PullViewedge is the resolver for the pullViewedge field.
 func (r *queryResolver) PullViewedge(ctx context.Context, checkpoint *gmodel.InputCheckpoint, limit int) (*gmodel.ViewedgePullBulk, error) {
+	if err := checkScope(ctx, model.SCOPE_READ_VIEWS); err != nil {
+		return nil, err
+	}
 	var minUpdateTime int64
 	if checkpoint != nil && checkpoint.UpdateTime > 0 {
 		minUpdateTime = checkpoint.UpdateTime
@@ -447,9 +654,12 @@ func (r *queryResolver) PullViewedge(ctx context.Context, checkpoint *gmodel.Inp
 		limit = 100
 	}
 	// 查询未同步的数据
-	uid := global.Uid
 	srv := service.New(ctx)
-	list, err := srv.GetViewedges(uid, minUpdateTime, limit)
+	scope, err := srv.GetSyncScope(authUid(ctx))
+	if err != nil {
+		return nil, errors.New("查询数据异常")
+	}
+	list, err := srv.GetViewedges(scope, minUpdateTime, limit)
 	if err != nil {
 		return nil, errors.New("查询数据异常")
 	}
@@ -0,0 +1,74 @@
+package idp
+
+import (
+	"context"
+	"errors"
+
+	"cc/be/setting"
+)
+
+// 第三方身份提供方类型
+const PROVIDER_WECHAT = "wechat"
+
+var ErrProviderNotFound = errors.New("不支持该登录方式")
+
+// 第三方身份信息
+type Identity struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Unionid  string `json:"unionid"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Email    string `json:"email"`
+}
+
+// 身份提供方接口
+type Provider interface {
+	// 提供方名称，同时作为 identity 表中的 provider 值
+	Name() string
+	// 生成授权跳转地址，nonce 为随机值，OIDC 登录时写入 id_token
+	AuthURL(state, nonce string) string
+	// 使用授权码换取身份信息，nonce 为生成授权地址时使用的值，用于校验 id_token
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+var providers = map[string]Provider{}
+
+// 注册身份提供方
+func Register(p Provider) {
+	providers[p.Name()] = p
+}
+
+// 获取身份提供方
+func Get(name string) (Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return p, nil
+}
+
+// 获取已启用的身份提供方名称列表
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// 根据配置初始化身份提供方
+func Init(s *setting.IdentitySetting) {
+	if s == nil {
+		return
+	}
+	if s.Wechat.AppId != "" {
+		Register(NewWechat(s.Wechat))
+	}
+	for _, o := range s.Oidc {
+		if o.Name == "" || o.Issuer == "" {
+			continue
+		}
+		Register(NewOidc(o))
+	}
+}
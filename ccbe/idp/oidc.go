@@ -0,0 +1,320 @@
+package idp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cc/be/setting"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// OIDC 服务发现信息
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcToken struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+type oidcClaims struct {
+	Nonce             string `json:"nonce"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+	Email             string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// 通用 OIDC 授权码登录
+type Oidc struct {
+	setting    setting.OidcSetting
+	httpClient *http.Client
+	mu         sync.Mutex
+	discovery  *oidcDiscovery
+	keys       map[string]interface{}
+}
+
+func NewOidc(s setting.OidcSetting) *Oidc {
+	return &Oidc{
+		setting:    s,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       map[string]interface{}{},
+	}
+}
+
+func (o *Oidc) Name() string {
+	return o.setting.Name
+}
+
+func (o *Oidc) scopes() string {
+	if o.setting.Scopes == "" {
+		return "openid profile email"
+	}
+	return o.setting.Scopes
+}
+
+func (o *Oidc) AuthURL(state, nonce string) string {
+	d, err := o.getDiscovery(context.Background())
+	if err != nil {
+		return ""
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.setting.ClientId)
+	q.Set("redirect_uri", o.setting.RedirectURI)
+	q.Set("scope", o.scopes())
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode()
+}
+
+func (o *Oidc) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	if nonce == "" {
+		return nil, errors.New("缺少授权 nonce")
+	}
+	d, err := o.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.setting.RedirectURI)
+	form.Set("client_id", o.setting.ClientId)
+	form.Set("client_secret", o.setting.ClientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	token := &oidcToken{}
+	if err := o.doJSON(req, token); err != nil {
+		return nil, fmt.Errorf("换取token异常: %w", err)
+	}
+	if token.IdToken == "" {
+		return nil, errors.New("授权响应缺少 id_token")
+	}
+	claims, err := o.verifyIdToken(ctx, token.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	// id_token 必须带有生成授权地址时的 nonce，防止重放
+	if claims.Nonce != nonce {
+		return nil, errors.New("id_token nonce 校验失败")
+	}
+	identity := &Identity{
+		Provider: o.Name(),
+		Subject:  claims.Subject,
+		Username: claims.Name,
+		Avatar:   claims.Picture,
+		Email:    claims.Email,
+	}
+	if identity.Username == "" {
+		identity.Username = claims.PreferredUsername
+	}
+	// id_token 中没有用户资料时，从 userinfo 接口补充
+	if identity.Username == "" && d.UserinfoEndpoint != "" && token.AccessToken != "" {
+		o.fillUserinfo(ctx, d.UserinfoEndpoint, token.AccessToken, identity)
+	}
+	return identity, nil
+}
+
+func (o *Oidc) fillUserinfo(ctx context.Context, endpoint, accessToken string, identity *Identity) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	info := &oidcClaims{}
+	if err := o.doJSON(req, info); err != nil || info.Subject != identity.Subject {
+		return
+	}
+	identity.Username = info.Name
+	if identity.Username == "" {
+		identity.Username = info.PreferredUsername
+	}
+	if identity.Avatar == "" {
+		identity.Avatar = info.Picture
+	}
+	if identity.Email == "" {
+		identity.Email = info.Email
+	}
+}
+
+// 校验 id_token 的签名、签发方、受众和有效期
+func (o *Oidc) verifyIdToken(ctx context.Context, idToken string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	_, err := parser.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.getKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("id_token 校验失败: %w", err)
+	}
+	if !claims.VerifyIssuer(strings.TrimSuffix(o.setting.Issuer, "/"), true) && !claims.VerifyIssuer(o.setting.Issuer, true) {
+		return nil, errors.New("id_token 签发方不匹配")
+	}
+	if !claims.VerifyAudience(o.setting.ClientId, true) {
+		return nil, errors.New("id_token 受众不匹配")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id_token 缺少 sub")
+	}
+	return claims, nil
+}
+
+func (o *Oidc) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	u := strings.TrimSuffix(o.setting.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	d := &oidcDiscovery{}
+	if err := o.doJSON(req, d); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 配置异常: %w", err)
+	}
+	if d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, errors.New("OIDC 配置信息不完整")
+	}
+	o.discovery = d
+	return d, nil
+}
+
+// 获取 id_token 签名公钥，未知 kid 时刷新 JWKS
+func (o *Oidc) getKey(ctx context.Context, kid string) (interface{}, error) {
+	o.mu.Lock()
+	key, ok := o.keys[kid]
+	o.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	d, err := o.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("获取 JWKS 异常: %w", err)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	o.mu.Lock()
+	o.keys = keys
+	o.mu.Unlock()
+	key, ok = keys[kid]
+	if !ok {
+		// 只有一个公钥且 token 未指定 kid 时直接使用
+		if kid == "" && len(keys) == 1 {
+			for _, k := range keys {
+				return k, nil
+			}
+		}
+		return nil, errors.New("未找到签名公钥: " + kid)
+	}
+	return key, nil
+}
+
+func (o *Oidc) doJSON(req *http.Request, v interface{}) error {
+	res, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("不支持的曲线: " + k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.New("不支持的密钥类型: " + k.Kty)
+}
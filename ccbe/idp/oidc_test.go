@@ -0,0 +1,199 @@
+package idp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cc/be/setting"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// 本地模拟的 OIDC 服务
+type mockOidcServer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientId string
+	code     string
+	// 签发 id_token 时使用的声明，可在用例中修改
+	nonce    string
+	audience string
+	subject  string
+	name     string
+}
+
+func newMockOidcServer(t *testing.T) *mockOidcServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOidcServer{
+		key:      key,
+		clientId: "cardcool",
+		code:     "test-code",
+		audience: "cardcool",
+		subject:  "user-1",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"userinfo_endpoint":      m.URL + "/userinfo",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != m.code || r.PostForm.Get("client_id") != m.clientId {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   m.URL,
+			"sub":   m.subject,
+			"aud":   m.audience,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": m.nonce,
+		}
+		if m.name != "" {
+			claims["name"] = m.name
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		idToken, err := token.SignedString(m.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access-" + m.subject,
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-"+m.subject {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"sub":     m.subject,
+			"name":    "userinfo name",
+			"picture": "https://example.com/a.png",
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOidcServer) provider() *Oidc {
+	return NewOidc(setting.OidcSetting{
+		Name:         "mock",
+		Issuer:       m.URL,
+		ClientId:     m.clientId,
+		ClientSecret: "secret",
+		RedirectURI:  "https://i.cardcool.top/callback",
+	})
+}
+
+func TestOidcAuthURL(t *testing.T) {
+	m := newMockOidcServer(t)
+	u, err := url.Parse(m.provider().AuthURL("state1", "nonce1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u.String(), m.URL+"/authorize?") {
+		t.Fatalf("unexpected auth url: %s", u)
+	}
+	q := u.Query()
+	if q.Get("client_id") != "cardcool" || q.Get("state") != "state1" || q.Get("nonce") != "nonce1" || q.Get("response_type") != "code" {
+		t.Fatalf("unexpected auth params: %v", q)
+	}
+}
+
+func TestOidcExchange(t *testing.T) {
+	m := newMockOidcServer(t)
+	m.nonce = "nonce1"
+	m.name = "id token name"
+	identity, err := m.provider().Exchange(context.Background(), m.code, "nonce1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Provider != "mock" || identity.Subject != "user-1" || identity.Username != "id token name" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestOidcExchangeUserinfo(t *testing.T) {
+	m := newMockOidcServer(t)
+	m.nonce = "nonce1"
+	identity, err := m.provider().Exchange(context.Background(), m.code, "nonce1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Username != "userinfo name" || identity.Avatar != "https://example.com/a.png" {
+		t.Fatalf("userinfo not applied: %+v", identity)
+	}
+}
+
+func TestOidcExchangeRejects(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(m *mockOidcServer)
+		code  string
+	}{
+		{"nonce", func(m *mockOidcServer) { m.nonce = "other" }, "test-code"},
+		{"missing nonce", func(m *mockOidcServer) { m.nonce = "" }, "test-code"},
+		{"audience", func(m *mockOidcServer) { m.nonce = "nonce1"; m.audience = "other" }, "test-code"},
+		{"code", func(m *mockOidcServer) { m.nonce = "nonce1" }, "bad-code"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newMockOidcServer(t)
+			c.setup(m)
+			if _, err := m.provider().Exchange(context.Background(), c.code, "nonce1"); err == nil {
+				t.Fatal("expected exchange to fail")
+			}
+		})
+	}
+}
+
+func TestOidcExchangeRejectsForeignKey(t *testing.T) {
+	m := newMockOidcServer(t)
+	m.nonce = "nonce1"
+	// 使用其他私钥签名的 id_token 不能通过校验
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.key = other
+	if _, err := m.provider().Exchange(context.Background(), m.code, "nonce1"); err == nil {
+		t.Fatal("expected signature check to fail")
+	}
+}
@@ -0,0 +1,60 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"cc/be/setting"
+
+	"github.com/chanxuehong/wechat/oauth2"
+	openoauth2 "github.com/chanxuehong/wechat/open/oauth2"
+)
+
+// 微信开放平台网站应用扫码登录
+type Wechat struct {
+	setting setting.WechatSetting
+}
+
+func NewWechat(s setting.WechatSetting) *Wechat {
+	return &Wechat{setting: s}
+}
+
+func (w *Wechat) Name() string {
+	return PROVIDER_WECHAT
+}
+
+func (w *Wechat) AuthURL(state, nonce string) string {
+	return openoauth2.AuthCodeURL(w.setting.AppId, w.setting.RedirectURI, "snsapi_login", state)
+}
+
+func (w *Wechat) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	client := oauth2.Client{
+		Endpoint: openoauth2.NewEndpoint(w.setting.AppId, w.setting.AppSecret),
+	}
+	token, err := client.ExchangeToken(code)
+	if err != nil {
+		log.Printf("换取微信 token 异常: %s", err)
+		return nil, errors.New("换取token异常")
+	}
+	if token.OpenId == "" {
+		return nil, errors.New("获取OpenID异常")
+	}
+	identity := &Identity{
+		Provider: PROVIDER_WECHAT,
+		Subject:  token.OpenId,
+		Unionid:  token.UnionId,
+	}
+	// 用户信息获取失败不影响登录
+	userinfo, err := openoauth2.GetUserInfo(token.AccessToken, token.OpenId, "", nil)
+	if err != nil {
+		log.Printf("获取微信用户信息异常: %s", err)
+		return identity, nil
+	}
+	identity.Username = userinfo.Nickname
+	identity.Avatar = userinfo.HeadImageURL
+	if identity.Unionid == "" {
+		identity.Unionid = userinfo.UnionId
+	}
+	return identity, nil
+}
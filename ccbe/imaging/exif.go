@@ -0,0 +1,240 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF 中的方向和 GPS 信息标签
+const tagOrientation = 0x0112
+const tagGPSInfo = 0x8825
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// XMP 中可能包含拍摄位置，整段删除
+var xmpHeaders = [][]byte{
+	[]byte("http://ns.adobe.com/xap/1.0/\x00"),
+	[]byte("http://ns.adobe.com/xmp/extension/\x00"),
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// 各数据类型的字节数，下标为 TIFF 数据类型
+var tiffTypeSize = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// JPEG 中的数据段，data 不包含标记和长度
+type jpegSegment struct {
+	start, end int
+	marker     byte
+	data       []byte
+}
+
+// 遍历 JPEG 压缩数据之前的数据段，格式不正确时返回 false
+func jpegSegments(b []byte, fn func(s jpegSegment) bool) bool {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return false
+	}
+	i := 2
+	for i+4 <= len(b) {
+		if b[i] != 0xFF {
+			return false
+		}
+		marker := b[i+1]
+		// SOS 之后为压缩数据
+		if marker == 0xDA || marker == 0xD9 {
+			return true
+		}
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if n < 2 || i+2+n > len(b) {
+			return false
+		}
+		if !fn(jpegSegment{start: i, end: i + 2 + n, marker: marker, data: b[i+4 : i+2+n]}) {
+			return true
+		}
+		i += 2 + n
+	}
+	return false
+}
+
+// TIFF 结构的 EXIF 数据
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func newTiff(b []byte) *tiff {
+	if len(b) < 8 {
+		return nil
+	}
+	switch string(b[:4]) {
+	case "II*\x00":
+		return &tiff{b: b, order: binary.LittleEndian}
+	case "MM\x00*":
+		return &tiff{b: b, order: binary.BigEndian}
+	}
+	return nil
+}
+
+// 遍历 IFD 中的条目，off 为条目在 b 中的位置
+func (t *tiff) entries(ifd int, fn func(tag, typ uint16, count uint32, off int)) int {
+	if ifd < 8 || ifd+2 > len(t.b) {
+		return 0
+	}
+	n := int(t.order.Uint16(t.b[ifd:]))
+	if ifd+2+n*12 > len(t.b) {
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		off := ifd + 2 + i*12
+		fn(t.order.Uint16(t.b[off:]), t.order.Uint16(t.b[off+2:]), t.order.Uint32(t.b[off+4:]), off)
+	}
+	return n
+}
+
+func (t *tiff) ifd0() int {
+	return int(t.order.Uint32(t.b[4:]))
+}
+
+// 图片方向，1-8，没有记录时为 1
+func (t *tiff) orientation() int {
+	o := 1
+	t.entries(t.ifd0(), func(tag, typ uint16, count uint32, off int) {
+		if tag == tagOrientation && typ == 3 {
+			if v := int(t.order.Uint16(t.b[off+8:])); v >= 1 && v <= 8 {
+				o = v
+			}
+		}
+	})
+	return o
+}
+
+// 清空 GPS 信息，包括条目和条目引用的数据，返回是否有修改
+func (t *tiff) clearGPS() bool {
+	gps := 0
+	t.entries(t.ifd0(), func(tag, typ uint16, count uint32, off int) {
+		if tag == tagGPSInfo {
+			gps = int(t.order.Uint32(t.b[off+8:]))
+		}
+	})
+	changed := false
+	n := t.entries(gps, func(tag, typ uint16, count uint32, off int) {
+		if int(typ) < len(tiffTypeSize) {
+			size := tiffTypeSize[typ] * int(count)
+			if size > 4 {
+				start := int(t.order.Uint32(t.b[off+8:]))
+				if start >= 8 && size <= len(t.b) && start+size <= len(t.b) {
+					zero(t.b[start : start+size])
+				}
+			}
+		}
+		zero(t.b[off : off+12])
+		changed = true
+	})
+	if n > 0 {
+		t.order.PutUint16(t.b[gps:], 0)
+	}
+	return changed
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// 读取 JPEG 中记录的图片方向
+func Orientation(b []byte) int {
+	o := 1
+	jpegSegments(b, func(s jpegSegment) bool {
+		if s.marker == 0xE1 && bytes.HasPrefix(s.data, exifHeader) {
+			if t := newTiff(s.data[len(exifHeader):]); t != nil {
+				o = t.orientation()
+			}
+			return false
+		}
+		return true
+	})
+	return o
+}
+
+// 去除图片中的位置信息: 清空 EXIF 中的 GPS 信息并删除 XMP，保留方向等其他信息
+// 不重新编码图片，返回处理后的内容和是否有修改，不支持的格式原样返回
+func StripLocation(b []byte) ([]byte, bool) {
+	if bytes.HasPrefix(b, pngSignature) {
+		return stripPNG(b)
+	}
+	out := make([]byte, 0, len(b))
+	last := 0
+	changed := false
+	ok := jpegSegments(b, func(s jpegSegment) bool {
+		if s.marker != 0xE1 {
+			return true
+		}
+		for _, h := range xmpHeaders {
+			if bytes.HasPrefix(s.data, h) {
+				out = append(out, b[last:s.start]...)
+				last = s.end
+				changed = true
+				return true
+			}
+		}
+		if bytes.HasPrefix(s.data, exifHeader) {
+			seg := append([]byte(nil), b[s.start:s.end]...)
+			if t := newTiff(seg[4+len(exifHeader):]); t != nil && t.clearGPS() {
+				out = append(out, b[last:s.start]...)
+				out = append(out, seg...)
+				last = s.end
+				changed = true
+			}
+		}
+		return true
+	})
+	if !ok || !changed {
+		return b, false
+	}
+	return append(out, b[last:]...), true
+}
+
+// PNG 中可能包含位置信息的数据块: eXIf 和 XMP、EXIF 文本块
+func stripPNG(b []byte) ([]byte, bool) {
+	out := append([]byte(nil), b[:len(pngSignature)]...)
+	changed := false
+	i := len(pngSignature)
+	for i+12 <= len(b) {
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		if n < 0 || i+12+n > len(b) {
+			return b, false
+		}
+		typ := string(b[i+4 : i+8])
+		data := b[i+8 : i+8+n]
+		end := i + 12 + n
+		if typ == "eXIf" || isMetaText(typ, data) {
+			changed = true
+		} else {
+			out = append(out, b[i:end]...)
+		}
+		i = end
+		if typ == "IEND" {
+			break
+		}
+	}
+	if !changed {
+		return b, false
+	}
+	return append(out, b[i:]...), true
+}
+
+func isMetaText(typ string, data []byte) bool {
+	if typ != "tEXt" && typ != "zTXt" && typ != "iTXt" {
+		return false
+	}
+	key := data
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		key = data[:i]
+	}
+	switch string(key) {
+	case "XML:com.adobe.xmp", "Raw profile type exif", "Raw profile type xmp":
+		return true
+	}
+	return false
+}
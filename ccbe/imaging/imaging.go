@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// 支持处理的图片格式
+const FORMAT_JPEG = "jpeg"
+const FORMAT_PNG = "png"
+
+// 默认 JPEG 压缩质量
+const DEFAULT_QUALITY = 82
+
+var ErrTooLarge = errors.New("图片像素数超出限制")
+
+// 根据 MIME 类型判断图片格式，不支持处理的格式返回空
+func Format(mime string) string {
+	switch mime {
+	case "image/jpeg":
+		return FORMAT_JPEG
+	case "image/png":
+		return FORMAT_PNG
+	}
+	return ""
+}
+
+// 图片显示时的宽高，按 EXIF 方向旋转后的尺寸
+func Size(b []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return 0, 0, err
+	}
+	if Orientation(b) >= 5 {
+		return cfg.Height, cfg.Width, nil
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// 解码图片，像素数超出 maxPixels 时返回 ErrTooLarge，避免解码超大图片占用内存
+func Decode(b []byte, maxPixels int) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// 按最长边等比缩小到 max 以内并按 EXIF 方向旋转，小于 max 时只旋转
+func Fit(img image.Image, max, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > max || h > max {
+		if w >= h {
+			w, h = max, h*max/w
+		} else {
+			w, h = w*max/h, max
+		}
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+		img = resize(img, w, h)
+	}
+	return orient(img, orientation)
+}
+
+// 区域平均缩小图片
+func resize(img image.Image, w, h int) *image.RGBA {
+	sb := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, sb.Dx(), sb.Dy()))
+		draw.Draw(src, src.Bounds(), img, sb.Min, draw.Src)
+	}
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// 按 EXIF 方向旋转或翻转图片，5-8 旋转后宽高互换
+func orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+	sb := img.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	w, h := sw, sh
+	if o >= 5 {
+		w, h = sh, sw
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, w-1-x
+			case 7:
+				sx, sy = h-1-y, w-1-x
+			case 8:
+				sx, sy = h-1-y, x
+			}
+			dst.Set(x, y, img.At(sb.Min.X+sx, sb.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// 按原图格式编码，PNG 保留透明通道
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	if format == FORMAT_PNG {
+		return png.Encode(w, img)
+	}
+	if quality <= 0 || quality > 100 {
+		quality = DEFAULT_QUALITY
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// 构造包含方向和 GPS 纬度的 EXIF 数据段
+func exifSegment(orientation uint16, lat []byte) []byte {
+	t := []byte("II*\x00")
+	t = appendU32(binary.LittleEndian, t, 8)
+	// IFD0: 方向和 GPS 信息偏移
+	t = appendU16(binary.LittleEndian, t, 2)
+	t = appendU16(binary.LittleEndian, t, tagOrientation)
+	t = appendU16(binary.LittleEndian, t, 3)
+	t = appendU32(binary.LittleEndian, t, 1)
+	t = appendU16(binary.LittleEndian, t, orientation)
+	t = appendU16(binary.LittleEndian, t, 0)
+	t = appendU16(binary.LittleEndian, t, tagGPSInfo)
+	t = appendU16(binary.LittleEndian, t, 4)
+	t = appendU32(binary.LittleEndian, t, 1)
+	t = appendU32(binary.LittleEndian, t, 38)
+	t = appendU32(binary.LittleEndian, t, 0)
+	// GPS IFD: 纬度为 3 个 RATIONAL，数据在 IFD 之后
+	t = appendU16(binary.LittleEndian, t, 1)
+	t = appendU16(binary.LittleEndian, t, 2)
+	t = appendU16(binary.LittleEndian, t, 5)
+	t = appendU32(binary.LittleEndian, t, 3)
+	t = appendU32(binary.LittleEndian, t, 56)
+	t = appendU32(binary.LittleEndian, t, 0)
+	t = append(t, lat...)
+	data := append(append([]byte(nil), exifHeader...), t...)
+	return segment(0xE1, data)
+}
+
+func appendU16(order binary.ByteOrder, b []byte, v uint16) []byte {
+	buf := make([]byte, 2)
+	order.PutUint16(buf, v)
+	return append(b, buf...)
+}
+
+func appendU32(order binary.ByteOrder, b []byte, v uint32) []byte {
+	buf := make([]byte, 4)
+	order.PutUint32(buf, v)
+	return append(b, buf...)
+}
+
+func segment(marker byte, data []byte) []byte {
+	s := []byte{0xFF, marker}
+	s = appendU16(binary.BigEndian, s, uint16(len(data)+2))
+	return append(s, data...)
+}
+
+func testJPEG(t *testing.T, w, h int, extra ...[]byte) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	out := append([]byte(nil), b[:2]...)
+	for _, e := range extra {
+		out = append(out, e...)
+	}
+	return append(out, b[2:]...)
+}
+
+func TestStripLocationJPEG(t *testing.T) {
+	lat := bytes.Repeat([]byte{0x5A}, 24)
+	xmp := segment(0xE1, append(append([]byte(nil), xmpHeaders[0]...), "<gps/>"...))
+	b := testJPEG(t, 4, 2, exifSegment(6, lat), xmp)
+	out, changed := StripLocation(b)
+	if !changed {
+		t.Fatal("未去除位置信息")
+	}
+	if bytes.Contains(out, lat) || bytes.Contains(out, []byte("<gps/>")) {
+		t.Fatal("仍包含位置信息")
+	}
+	if Orientation(out) != 6 {
+		t.Fatalf("Orientation = %d", Orientation(out))
+	}
+	if w, h, err := Size(out); err != nil || w != 2 || h != 4 {
+		t.Fatalf("Size = %d %d %v", w, h, err)
+	}
+	if _, changed := StripLocation(out); changed {
+		t.Fatal("重复处理不应有修改")
+	}
+	plain := testJPEG(t, 4, 2)
+	if _, changed := StripLocation(plain); changed {
+		t.Fatal("没有位置信息时不应修改")
+	}
+}
+
+func TestStripLocationPNG(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	// 在 IEND 之前插入 eXIf 数据块
+	data := []byte("II*\x00gps")
+	chunk := appendU32(binary.BigEndian, nil, uint32(len(data)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, data...)
+	chunk = appendU32(binary.BigEndian, chunk, crc32.ChecksumIEEE(chunk[4:]))
+	withExif := append(append(append([]byte(nil), b[:len(b)-12]...), chunk...), b[len(b)-12:]...)
+	out, changed := StripLocation(withExif)
+	if !changed || !bytes.Equal(out, b) {
+		t.Fatal("未删除 eXIf 数据块")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	red := color.RGBA{255, 0, 0, 255}
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, red)
+		}
+	}
+	out := Fit(img, 200, 1)
+	if b := out.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Fatalf("Fit = %v", b)
+	}
+	if c := color.RGBAModel.Convert(out.At(0, 0)).(color.RGBA); c != red {
+		t.Fatalf("At(0, 0) = %v", c)
+	}
+	// 方向 6 需顺时针旋转 90 度，左上角移到右上角
+	out = Fit(img, 200, 6)
+	if b := out.Bounds(); b.Dx() != 100 || b.Dy() != 200 {
+		t.Fatalf("Fit = %v", b)
+	}
+	if c := color.RGBAModel.Convert(out.At(99, 0)).(color.RGBA); c != red {
+		t.Fatalf("At(99, 0) = %v", c)
+	}
+	// 小于 max 时不放大
+	if b := Fit(img, 1000, 1).Bounds(); b.Dx() != 400 {
+		t.Fatalf("Fit = %v", b)
+	}
+}
@@ -5,12 +5,17 @@ import (
 	"net/http"
 	"time"
 
+	"cc/be/app"
 	"cc/be/cache"
 	"cc/be/global"
+	"cc/be/idp"
 	"cc/be/model"
 	"cc/be/router"
+	"cc/be/sender"
 	"cc/be/server"
+	"cc/be/service"
 	"cc/be/setting"
+	"cc/be/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -21,6 +26,10 @@ func init() {
 	if err != nil {
 		log.Fatalf("init Setting err: %v", err)
 	}
+	err = app.InitJwtKeys(global.JwtSetting)
+	if err != nil {
+		log.Fatalf("init JwtKeys err: %v", err)
+	}
 	err = initDBEngine()
 	if err != nil {
 		log.Fatalf("init DBEngine err: %v", err)
@@ -31,6 +40,15 @@ func init() {
 	// }
 	initSSE()
 	initLogger()
+	idp.Init(global.IdentitySetting)
+	err = sender.Init(global.SenderSetting)
+	if err != nil {
+		log.Fatalf("init Sender err: %v", err)
+	}
+	err = storage.Init(global.StorageSetting)
+	if err != nil {
+		log.Fatalf("init Storage err: %v", err)
+	}
 }
 
 func initSetting() error {
@@ -62,7 +80,23 @@ func initSetting() error {
 	if err != nil {
 		return err
 	}
-	err = setting.ReadSection("Qiniu", &global.QiniuSetting)
+	err = setting.ReadSection("Storage", &global.StorageSetting)
+	if err != nil {
+		return err
+	}
+	err = setting.ReadSection("Quota", &global.QuotaSetting)
+	if err != nil {
+		return err
+	}
+	err = setting.ReadSection("Identity", &global.IdentitySetting)
+	if err != nil {
+		return err
+	}
+	err = setting.ReadSection("Sender", &global.SenderSetting)
+	if err != nil {
+		return err
+	}
+	err = setting.ReadSection("Share", &global.ShareSetting)
 	if err != nil {
 		return err
 	}
@@ -94,6 +128,10 @@ func initSSE() {
 
 func main() {
 	gin.SetMode(global.ServerSetting.RunMode)
+	// 注销账号清除任务
+	go service.RunDeletionWorker()
+	// 未引用文件清理任务
+	go service.RunFileGCWorker()
 
 	go func() {
 		sseserver := &http.Server{
@@ -1,15 +1,20 @@
 package middleware
 
 import (
+	"errors"
+	"strconv"
+
 	"cc/be/app"
+	"cc/be/cache"
 	"cc/be/errcode"
 	"cc/be/global"
+	"cc/be/service"
 
 	"github.com/gin-gonic/gin"
 	jwt "github.com/golang-jwt/jwt/v4"
 )
 
-// token 校验中间件
+// token 校验中间件，同时支持登录 JWT 和个人访问令牌
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var (
@@ -18,20 +23,31 @@ func Auth() gin.HandlerFunc {
 		)
 		uid := 0
 		rid := ""
-		if auth == "" {
+		var scopes []string
+		if len(auth) <= 7 {
 			ecode = errcode.TokenParamEmpty
 		} else {
 			// 截取前面的 Bearer
 			token := auth[7:]
-			if auth == "" || token == "" {
-				ecode = errcode.TokenParamEmpty
+			if service.IsAccessToken(token) {
+				srv := service.New(c.Request.Context())
+				at, err := srv.ParseAccessToken(token)
+				if errors.Is(err, service.ErrAccessTokenExpired) {
+					ecode = errcode.TokenExpired
+				} else if err != nil {
+					ecode = errcode.TokenParseError
+				} else {
+					uid = at.Uid
+					rid = "pat" + strconv.Itoa(at.Id)
+					scopes = at.ScopeList()
+				}
 			} else {
 				claims, err := app.ParseToken(token)
 				if err != nil {
-					switch err.(*jwt.ValidationError).Errors {
-					case jwt.ValidationErrorExpired:
+					ve, ok := err.(*jwt.ValidationError)
+					if ok && ve.Errors == jwt.ValidationErrorExpired {
 						ecode = errcode.TokenExpired
-					default:
+					} else {
 						ecode = errcode.TokenParseError
 					}
 				} else {
@@ -40,13 +56,31 @@ func Auth() gin.HandlerFunc {
 				}
 			}
 		}
+		// 已注销账号的 token 不再有效
+		if ecode == errcode.Success && cache.IsUserDeleted(uid) {
+			ecode = errcode.TokenParseError
+		}
 		if ecode != errcode.Success {
 			app.NewResponse(c).Error(ecode, nil)
 			c.Abort()
 			return
 		}
+		// 权限范围只保存在请求上下文中，避免并发请求互相覆盖
+		app.SetAuth(c, &app.Auth{Uid: uid, Rid: rid, Scopes: scopes})
 		global.Uid = uid
 		global.Rid = rid
 		c.Next()
 	}
 }
+
+// 仅允许登录会话访问，拒绝个人访问令牌
+func Session() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth := app.GetAuth(c); auth == nil || auth.Scopes != nil {
+			app.NewResponse(c).Error(errcode.TokenScopeError, nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
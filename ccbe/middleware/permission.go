@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"cc/be/app"
+	"cc/be/errcode"
+	"cc/be/global"
+	"cc/be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限校验中间件，校验通过的特权操作会记录审计日志
+func Permission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		srv := service.New(c.Request.Context())
+		if !srv.HasPermission(global.Uid, perm) {
+			app.NewResponse(c).Error(errcode.PermissionError, nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+		srv.Audit(global.Uid, perm, c.FullPath(), c.GetString(app.AUDIT_TARGET_KEY), c.ClientIP(), c.GetInt(app.RESP_CODE_KEY))
+	}
+}
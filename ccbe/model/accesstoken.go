@@ -0,0 +1,104 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"cc/be/global"
+)
+
+// 个人访问令牌的权限范围
+const SCOPE_READ_CARDS = "read:cards"
+const SCOPE_WRITE_CARDS = "write:cards"
+const SCOPE_READ_VIEWS = "read:views"
+const SCOPE_WRITE_VIEWS = "write:views"
+
+var AllScopes = []string{SCOPE_READ_CARDS, SCOPE_WRITE_CARDS, SCOPE_READ_VIEWS, SCOPE_WRITE_VIEWS}
+
+// 判断权限范围是否存在
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断是否拥有指定权限范围，scopes 为 nil 表示登录会话，拥有全部权限
+func HasScope(scopes []string, scope string) bool {
+	if scopes == nil {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type Accesstoken struct {
+	Id           int    `gorm:"primary_key" json:"id"`
+	Uid          int    `json:"uid"`
+	Name         string `json:"name"`
+	Prefix       string `json:"prefix"`
+	Hash         string `json:"-"`
+	Scopes       string `json:"scopes"`
+	ExpireTime   int    `json:"expire_time"`
+	LastUsedTime int    `json:"last_used_time"`
+	Status       int8   `json:"status"`
+	CreateTime   int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime   int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Accesstoken) TableName() string {
+	return "accesstoken"
+}
+
+func (a *Accesstoken) ScopeList() []string {
+	if a.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(a.Scopes, ",")
+}
+
+// 判断令牌是否已过期
+func (a *Accesstoken) IsExpired() bool {
+	return a.ExpireTime > 0 && int64(a.ExpireTime) < time.Now().Unix()
+}
+
+func (a *Accesstoken) GetByHash(hash string) error {
+	return global.DBEngine.Select("id,uid,name,scopes,expire_time,last_used_time,status").Where("hash", hash).Take(a).Error
+}
+
+// 获取用户的令牌列表
+func (a *Accesstoken) GetAccesstokens(uid int) (*[]Accesstoken, error) {
+	var list []Accesstoken
+	err := global.DBEngine.Select("id,name,prefix,scopes,expire_time,last_used_time,status,create_time").Where("uid", uid).Where("status", 1).Order("id desc").Find(&list).Error
+	return &list, err
+}
+
+func (a *Accesstoken) CreateAccesstoken(uid int, name, prefix, hash, scopes string, expireTime int) error {
+	a.Uid = uid
+	a.Name = name
+	a.Prefix = prefix
+	a.Hash = hash
+	a.Scopes = scopes
+	a.ExpireTime = expireTime
+	a.Status = 1
+	return global.DBEngine.Create(a).Error
+}
+
+// 撤销令牌
+func (a *Accesstoken) RevokeAccesstoken(uid, id int) (int64, error) {
+	res := global.DBEngine.Model(a).Where("uid", uid).Where("id", id).Where("status", 1).Updates(map[string]interface{}{
+		"status": 0,
+	})
+	return res.RowsAffected, res.Error
+}
+
+// 更新令牌最近使用时间
+func (a *Accesstoken) UpdateLastUsedTime(id, t int) error {
+	return global.DBEngine.Model(a).Where("id", id).UpdateColumn("last_used_time", t).Error
+}
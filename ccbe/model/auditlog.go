@@ -0,0 +1,47 @@
+package model
+
+import (
+	"cc/be/global"
+	"cc/be/utils"
+)
+
+type Auditlog struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Action     string `json:"action"`
+	Path       string `json:"path"`
+	Target     string `json:"target"`
+	Ip         string `json:"ip"`
+	Code       int    `json:"code"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Auditlog) TableName() string {
+	return "auditlog"
+}
+
+func (a *Auditlog) CreateAuditlog() error {
+	return global.DBEngine.Create(a).Error
+}
+
+// 分页查询审计日志，uid 和 action 为空值时不作为筛选条件
+func (a *Auditlog) GetAuditlogs(uid int, action string, page, pageSize int) (*[]Auditlog, int64, error) {
+	var list []Auditlog
+	var total int64
+	db := global.DBEngine.Model(a)
+	if uid > 0 {
+		db = db.Where("uid", uid)
+	}
+	if action != "" {
+		db = db.Where("action", action)
+	}
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Order("id desc").Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &list, total, nil
+}
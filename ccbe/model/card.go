@@ -43,9 +43,9 @@ func (s *Card) GetCardCheckMap(uid int, ids []string) (*map[string]int64, error)
 }
 
 // 获取节点分组列表
-func (s *Card) GetCards(uid int, updateTime int64, limit int) (*[]Card, error) {
+func (s *Card) GetCards(scope *SyncScope, updateTime int64, limit int) (*[]Card, error) {
 	var cards []Card
-	err := global.DBEngine.Table("card").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&cards).Error
+	err := global.DBEngine.Table("card").Select("*").Scopes(scope.BySpace()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&cards).Error
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func (s *Card) GetCards(uid int, updateTime int64, limit int) (*[]Card, error) {
 	if len(cards) == limit {
 		// 获取剩余记录
 		var reminds []Card
-		err = global.DBEngine.Table("card").Select("*").Where("uid", uid).Where("update_time", cards[limit-1].UpdateTime).Where("unid > ?", cards[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("card").Select("*").Scopes(scope.BySpace()).Where("update_time", cards[limit-1].UpdateTime).Where("unid > ?", cards[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -87,3 +87,28 @@ func (s *Card) GetPropextCardIds(uid int, ids *[]string) (*map[string]int8, erro
 	}
 	return idMap(&cards), nil
 }
+
+// 获取空间内未删除的卡片，按更新时间倒序
+func (s *Card) GetSpaceCards(uid int, spaceId string) (*[]Card, error) {
+	var cards []Card
+	err := global.DBEngine.Where("uid", uid).Where("space_id", spaceId).Where("is_deleted", 0).Order("update_time desc").Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cards, nil
+}
+
+// 根据 id 批量获取未删除的卡片
+func (s *Card) GetCardsByIds(uid int, ids []string) (*[]Card, error) {
+	var cards []Card
+	err := global.DBEngine.Where("uid", uid).Where("id in ?", ids).Where("is_deleted", 0).Find(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cards, nil
+}
+
+// 获取未删除的卡片
+func (s *Card) GetCardById(uid int, cardId string) error {
+	return global.DBEngine.Where("uid", uid).Where("id", cardId).Where("is_deleted", 0).Take(s).Error
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"cc/be/global"
+
+	"gorm.io/gorm"
+)
+
+// 扫描文件引用时读取的内容，Unid 为所在表的主键，Uid 为内容所属的用户
+type ContentRow struct {
+	Unid    int
+	Uid     int
+	Content string
+}
+
+// 可能引用上传文件的内容来源，只扫描未删除的数据
+type contentSource struct {
+	name  string
+	key   string
+	query func(db *gorm.DB) *gorm.DB
+}
+
+// 扩展信息按 uid 和 id 关联所属的卡片、视图或节点
+func propextOf(table string, typeId int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Table("propext p").Select("p.unid, p.uid, p.props as content").
+			Joins("JOIN `"+table+"` t ON t.uid = p.uid AND t.id = p.id").
+			Where("p.type_id", typeId).Where("t.is_deleted", 0).Where("t.deleted", 0)
+	}
+}
+
+var contentSources = []contentSource{
+	{"card", "unid", func(db *gorm.DB) *gorm.DB {
+		return db.Table("card").Select("unid, uid, content").Where("is_deleted", 0).Where("deleted", 0).Where("content <> ''")
+	}},
+	{"card_content", "p.unid", propextOf("card", TYPE_CARD_CONTENT)},
+	{"doc_content", "p.unid", propextOf("view", TYPE_DOC_CONTENT)},
+	{"viewnode", "unid", func(db *gorm.DB) *gorm.DB {
+		return db.Table("viewnode").Select("unid, uid, content").Where("is_deleted", 0).Where("deleted", 0).Where("content <> ''")
+	}},
+	// 超长的节点内容保存为 TYPE_VIEW_CONFIG 类型的扩展信息
+	{"viewnode_content", "p.unid", propextOf("viewnode", TYPE_VIEW_CONFIG)},
+	// 分享页面和历史版本中的内容仍可公开访问
+	{"share", "id", func(db *gorm.DB) *gorm.DB {
+		return db.Table("share").Select("id as unid, uid, content").Where("content <> ''")
+	}},
+	{"sharerevision", "id", func(db *gorm.DB) *gorm.DB {
+		return db.Table("sharerevision").Select("id as unid, uid, content").Where("content <> ''")
+	}},
+	// 用户头像保存为上传文件的路径 img/{uid}/{etag}
+	{"user", "id", func(db *gorm.DB) *gorm.DB {
+		return db.Table("user").Select("id as unid, id as uid, avatar as content").Where("avatar <> ''")
+	}},
+}
+
+// 内容来源名称
+func ContentSources() []string {
+	names := make([]string, 0, len(contentSources))
+	for _, s := range contentSources {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// 按主键分批读取内容来源中的内容，lastId 为上一批最后一条记录的主键
+func GetContents(source string, lastId, limit int) (*[]ContentRow, error) {
+	var list []ContentRow
+	for _, s := range contentSources {
+		if s.name != source {
+			continue
+		}
+		err := s.query(global.DBEngine).Where(s.key+" > ?", lastId).Order(s.key).Limit(limit).Find(&list).Error
+		return &list, err
+	}
+	return &list, nil
+}
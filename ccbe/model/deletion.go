@@ -0,0 +1,101 @@
+package model
+
+import (
+	"cc/be/global"
+
+	"gorm.io/gorm"
+)
+
+// 注销申请状态：0-等待清除，1-已撤销，2-已清除
+const DELETION_STATUS_PENDING = 0
+const DELETION_STATUS_CANCELED = 1
+const DELETION_STATUS_PURGED = 2
+
+// 注销账号时按 uid 清除数据的表
+var PurgeTables = []string{
+	"space", "type", "card", "tag", "view", "viewnode", "viewedge", "propext", "share", "filelog",
+	"accesstoken", "identity", "totp", "recoverycode", "userrole", "shareview",
+	"spacemember", "sharerevision", "userquota",
+}
+
+type Deletion struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Status     int8   `json:"status"`
+	PurgeTime  int    `json:"purge_time"`
+	FinishTime int    `json:"finish_time"`
+	Receipt    string `json:"receipt"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Deletion) TableName() string {
+	return "deletion"
+}
+
+// 查询用户等待清除的注销申请
+func (d *Deletion) SelectPending(uid int) error {
+	return global.DBEngine.Select("id,uid,status,purge_time,create_time").Where("uid", uid).Where("status", DELETION_STATUS_PENDING).Take(d).Error
+}
+
+func (d *Deletion) CreateDeletion(uid, purgeTime int) error {
+	d.Uid = uid
+	d.Status = DELETION_STATUS_PENDING
+	d.PurgeTime = purgeTime
+	return global.DBEngine.Create(d).Error
+}
+
+// 撤销注销申请
+func (d *Deletion) CancelDeletion(uid int) (int64, error) {
+	res := global.DBEngine.Model(d).Where("uid", uid).Where("status", DELETION_STATUS_PENDING).Updates(map[string]interface{}{
+		"status": DELETION_STATUS_CANCELED,
+	})
+	return res.RowsAffected, res.Error
+}
+
+// 获取已到清除时间的注销申请
+func (d *Deletion) GetDueDeletions(t, limit int) (*[]Deletion, error) {
+	var list []Deletion
+	err := global.DBEngine.Select("id,uid,purge_time,create_time").Where("status", DELETION_STATUS_PENDING).Where("purge_time <= ?", t).Order("id").Limit(limit).Find(&list).Error
+	return &list, err
+}
+
+// 记录清除结果
+func (d *Deletion) FinishDeletion(id, t int, receipt string) error {
+	return global.DBEngine.Model(d).Where("id", id).Updates(map[string]interface{}{
+		"status":      DELETION_STATUS_PURGED,
+		"finish_time": t,
+		"receipt":     receipt,
+	}).Error
+}
+
+// 清除用户数据并匿名化账号，返回各表删除的行数
+func (d *Deletion) PurgeUserData(uid int) (map[string]int64, error) {
+	rows := map[string]int64{}
+	err := global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		for _, table := range PurgeTables {
+			res := tx.Exec("DELETE FROM `"+table+"` WHERE uid = ?", uid)
+			if res.Error != nil {
+				return res.Error
+			}
+			rows[table] = res.RowsAffected
+		}
+		// 移除用户共享空间的其他成员
+		res := tx.Exec("DELETE FROM `spacemember` WHERE owner_uid = ?", uid)
+		if res.Error != nil {
+			return res.Error
+		}
+		rows["spacemember"] += res.RowsAffected
+		// 保留账号记录用于邀请关系和审计，清除个人信息
+		return tx.Model(&User{}).Where("id", uid).Updates(map[string]interface{}{
+			"mobile":     "",
+			"password":   "",
+			"dbpassword": "",
+			"username":   "已注销",
+			"avatar":     "/cc/avatar.png",
+			"config":     "{}",
+			"status":     -1,
+		}).Error
+	})
+	return rows, err
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 文件内容记录，相同内容的文件只保存一份，RefCount 为持有该文件的用户数
+// Variants 为已生成的缩略图规格名称，以逗号分隔
+type Fileblob struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Hash       string `json:"hash"`
+	Key        string `gorm:"column:file_key" json:"key"`
+	Size       int64  `json:"size"`
+	Variants   string `json:"variants"`
+	RefCount   int    `json:"ref_count"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Fileblob) TableName() string {
+	return "fileblob"
+}
+
+func (b *Fileblob) CreateFileblob() error {
+	return global.DBEngine.Create(b).Error
+}
+
+func (b *Fileblob) GetByHash(hash string) error {
+	return global.DBEngine.Where("hash", hash).Take(b).Error
+}
+
+// 记录已生成的缩略图
+func (b *Fileblob) UpdateVariants(hash, key, variants string) error {
+	return global.DBEngine.Model(&Fileblob{}).Where("hash", hash).Where("file_key", key).Update("variants", variants).Error
+}
+
+// 删除没有用户持有的文件内容记录，删除前重新被持有时保留
+func (b *Fileblob) DeleteUnused() (int64, error) {
+	res := global.DBEngine.Where("id", b.Id).Where("ref_count", 0).Delete(&Fileblob{})
+	return res.RowsAffected, res.Error
+}
+
+// 查询更新时间早于 before 且没有用户持有的文件内容
+func (b *Fileblob) GetUnused(before, limit int) (*[]Fileblob, error) {
+	var list []Fileblob
+	err := global.DBEngine.Where("ref_count", 0).Where("update_time < ?", before).Order("id").Limit(limit).Find(&list).Error
+	return &list, err
+}
+
+// 统计保存在指定目录下的文件内容数
+func (b *Fileblob) CountByPrefix(prefix string) (int64, error) {
+	var total int64
+	err := global.DBEngine.Model(&Fileblob{}).Where("file_key LIKE ?", prefix+"%").Count(&total).Error
+	return total, err
+}
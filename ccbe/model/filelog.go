@@ -1,15 +1,31 @@
 package model
 
 import (
+	"errors"
+
 	"cc/be/global"
+	"cc/be/utils"
+
+	"gorm.io/gorm"
 )
 
+var ErrBlobReleased = errors.New("文件内容已被删除")
+
+// 用户持有的文件，文件内容保存在 Fileblob 中，Size 计入该用户的存储用量
 type Filelog struct {
 	Id         int    `gorm:"primary_key" json:"id"`
 	Uid        int    `json:"uid"`
 	Hash       string `json:"hash"`
+	Key        string `gorm:"column:file_key" json:"key"`
+	Name       string `json:"name"`
+	Mime       string `json:"mime"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	RefCount   int    `json:"ref_count"`
 	Size       int64  `json:"size"`
+	OrphanTime int    `json:"orphan_time"`
 	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
 }
 
 func (Filelog) TableName() string {
@@ -19,10 +35,137 @@ func (f *Filelog) CreateFilelog() error {
 	return global.DBEngine.Create(f).Error
 }
 
-func (f *Filelog) ExistByHash(hash string) bool {
-	nf := &Filelog{}
-	res := global.DBEngine.Select("id").Where("hash", hash).Take(nf)
-	return res.RowsAffected > 0
+// 登记用户持有的文件，同时增加文件内容的持有数
+// 文件内容在此之前已被删除时返回 ErrBlobReleased
+func (f *Filelog) CreateWithBlob(blobId int) error {
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(f).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&Fileblob{}).Where("id", blobId).UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrBlobReleased
+		}
+		return nil
+	})
+}
+
+// 删除用户持有的文件记录，同时减少文件内容的持有数
+// orphanTime 大于 0 时只删除仍处于未引用状态的记录，返回是否删除
+func (f *Filelog) Release(orphanTime int) (bool, error) {
+	released := false
+	err := global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		db := tx.Where("id", f.Id)
+		if orphanTime > 0 {
+			db = db.Where("orphan_time", orphanTime)
+		}
+		res := db.Delete(&Filelog{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		released = true
+		return tx.Model(&Fileblob{}).Where("hash", f.Hash).Where("ref_count > 0").UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+	})
+	return released, err
+}
+
+// 同一用户相同内容的文件只记录一次
+func (f *Filelog) GetByHash(uid int, hash string) error {
+	return global.DBEngine.Where("uid", uid).Where("hash", hash).Take(f).Error
+}
+
+// 重复上传相同文件时增加引用计数并取消未引用标记，旧记录缺少的文件信息使用新上传的信息补全
+func (f *Filelog) AddRef(nf *Filelog) error {
+	data := map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1"), "orphan_time": 0}
+	if f.Key == "" && nf.Key != "" {
+		data["file_key"] = nf.Key
+		f.Key = nf.Key
+	}
+	if f.Name == "" && nf.Name != "" {
+		data["name"] = nf.Name
+		f.Name = nf.Name
+	}
+	if f.Mime == "" && nf.Mime != "" {
+		data["mime"] = nf.Mime
+		f.Mime = nf.Mime
+	}
+	if f.Width == 0 && nf.Width > 0 {
+		data["width"] = nf.Width
+		data["height"] = nf.Height
+		f.Width = nf.Width
+		f.Height = nf.Height
+	}
+	err := global.DBEngine.Model(f).Updates(data).Error
+	if err != nil {
+		return err
+	}
+	f.RefCount++
+	f.OrphanTime = 0
+	return nil
+}
+
+// 减少引用计数
+func (f *Filelog) ReleaseRef() error {
+	err := global.DBEngine.Model(f).Where("ref_count > 0").Update("ref_count", gorm.Expr("ref_count - 1")).Error
+	if err != nil {
+		return err
+	}
+	f.RefCount--
+	return nil
+}
+
+// 分页查询用户的文件，keyword 匹配文件名，mimePrefix 按 MIME 类型筛选
+func (f *Filelog) GetFiles(uid int, keyword, mimePrefix string, page, pageSize int) (*[]Filelog, int64, error) {
+	var list []Filelog
+	var total int64
+	db := global.DBEngine.Model(&Filelog{}).Where("uid", uid)
+	if keyword != "" {
+		db = db.Where("name LIKE ?", "%"+escapeLike(keyword)+"%")
+	}
+	if mimePrefix != "" {
+		db = db.Where("mime LIKE ?", mimePrefix+"%")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := db.Order("id desc").Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	return &list, total, err
+}
+
+// 查询用户指定 id 的文件，不属于该用户的 id 会被忽略
+func (f *Filelog) GetByIds(uid int, ids []int) (*[]Filelog, error) {
+	var list []Filelog
+	err := global.DBEngine.Where("uid", uid).Where("id IN ?", ids).Find(&list).Error
+	return &list, err
+}
+
+// 统计用户已上传文件的总大小和文件数
+func (f *Filelog) GetUsage(uid int) (int64, int64, error) {
+	var usage struct {
+		Total int64
+		Files int64
+	}
+	err := global.DBEngine.Model(&Filelog{}).Select("COALESCE(sum(size), 0) as total, count(*) as files").Where("uid", uid).Scan(&usage).Error
+	return usage.Total, usage.Files, err
+}
+
+// 按 id 分批查询文件，uid 为 0 时查询全部用户，lastId 为上一批最后一条记录的 id
+func (f *Filelog) GetFilelogs(uid, lastId, limit int) (*[]Filelog, error) {
+	var list []Filelog
+	db := global.DBEngine.Where("id > ?", lastId)
+	if uid > 0 {
+		db = db.Where("uid", uid)
+	}
+	err := db.Order("id").Limit(limit).Find(&list).Error
+	return &list, err
+}
+
+// 设置文件未被引用的时间，t 为 0 时取消标记
+func (f *Filelog) SetOrphanTime(ids []int, t int) error {
+	return global.DBEngine.Model(&Filelog{}).Where("id IN ?", ids).UpdateColumn("orphan_time", t).Error
 }
 
 func (f *Filelog) SumSize(uid int) int64 {
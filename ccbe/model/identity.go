@@ -0,0 +1,74 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 用户关联的第三方身份，一个账号可以关联多个身份
+type Identity struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Provider   string `json:"provider"`
+	Subject    string `json:"subject"`
+	Unionid    string `json:"unionid"`
+	Username   string `json:"username"`
+	Avatar     string `json:"avatar"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Identity) TableName() string {
+	return "identity"
+}
+
+// 根据提供方和身份标识查询
+func (i *Identity) SelectBySubject(provider, subject string) error {
+	return global.DBEngine.Select("id,uid,provider,subject,unionid,username,avatar").Where("provider", provider).Where("subject", subject).Take(i).Error
+}
+
+func (i *Identity) ExistBySubject(provider, subject string) bool {
+	ni := &Identity{}
+	res := global.DBEngine.Select("id").Where("provider", provider).Where("subject", subject).Take(ni)
+	return res.RowsAffected > 0
+}
+
+// 获取用户在指定提供方的身份标识，没有则返回空字符串
+func (i *Identity) GetSubject(uid int, provider string) string {
+	res := global.DBEngine.Select("subject").Where("uid", uid).Where("provider", provider).Take(i)
+	if res.RowsAffected == 0 {
+		return ""
+	}
+	return i.Subject
+}
+
+// 获取用户关联的身份列表
+func (i *Identity) GetIdentities(uid int) (*[]Identity, error) {
+	var list []Identity
+	err := global.DBEngine.Select("id,provider,subject,username,avatar,create_time").Where("uid", uid).Order("id").Find(&list).Error
+	return &list, err
+}
+
+func (i *Identity) CountByUid(uid int) int64 {
+	var count int64
+	global.DBEngine.Model(i).Where("uid", uid).Count(&count)
+	return count
+}
+
+func (i *Identity) CreateIdentity() error {
+	return global.DBEngine.Create(i).Error
+}
+
+// 更新身份的昵称和头像
+func (i *Identity) UpdateProfile(id int, username, avatar string) error {
+	identity := Identity{
+		Username: username,
+		Avatar:   avatar,
+	}
+	return global.DBEngine.Select("username", "avatar", "update_time").Where("id", id).Updates(identity).Error
+}
+
+// 解除身份关联
+func (i *Identity) DeleteIdentity(uid, id int) (int64, error) {
+	res := global.DBEngine.Where("uid", uid).Where("id", id).Delete(i)
+	return res.RowsAffected, res.Error
+}
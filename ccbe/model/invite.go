@@ -1,15 +1,37 @@
 package model
 
 import (
+	"errors"
+
 	"cc/be/global"
+	"cc/be/utils"
+
+	"gorm.io/gorm"
 )
 
+// 邀请码状态：0-正常，1-已用完，-1-已作废
+const INVITE_STATUS_NORMAL = 0
+const INVITE_STATUS_USED = 1
+const INVITE_STATUS_REVOKED = -1
+
+// 邀请码类型：0-无限制，1-一次性
+const INVITE_LIMIT_NONE = 0
+const INVITE_LIMIT_ONCE = 1
+
+// 实际的最大使用次数，增加 max_uses 字段前生成的一次性邀请码 max_uses 为 0，按一次计算
+const inviteUseLimitSQL = "IF(limit_type = 1 AND max_uses = 0, 1, max_uses)"
+
+// 邀请码已作废或可用次数已用完
+var ErrInviteUsedUp = errors.New("invite used up")
+
 type Invite struct {
 	Id         int    `gorm:"primary_key" json:"id"`
 	Code       string `json:"code"`
 	LimitType  int8   `json:"limit_type"`
 	StartTime  int    `json:"start_time"`
 	EndTime    int    `json:"end_time"`
+	MaxUses    int    `json:"max_uses"`
+	UsedCount  int    `json:"used_count"`
 	Status     int8   `json:"status"`
 	CreateUid  int    `json:"create_uid"`
 	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
@@ -20,8 +42,16 @@ func (Invite) TableName() string {
 	return "invite"
 }
 
+// 最大使用次数，0 表示不限
+func (i *Invite) UseLimit() int {
+	if i.LimitType == INVITE_LIMIT_ONCE && i.MaxUses == 0 {
+		return 1
+	}
+	return i.MaxUses
+}
+
 func (i *Invite) GetByCode(code string) error {
-	return global.DBEngine.Select("id,limit_type,start_time,end_time,status,create_uid").Where("code", code).Take(i).Error
+	return global.DBEngine.Select("id,code,limit_type,start_time,end_time,max_uses,used_count,status,create_uid").Where("code", code).Take(i).Error
 }
 
 func (i *Invite) ExistByCode(code string) bool {
@@ -29,7 +59,12 @@ func (i *Invite) ExistByCode(code string) bool {
 	return res.RowsAffected > 0
 }
 
-func (i *Invite) BatchInsertInvites(uid, startTime, endTime int, limitType int8, codes *[]string) error {
+func (i *Invite) SelectById(id int) error {
+	return global.DBEngine.Where("id", id).Take(i).Error
+}
+
+// maxUses 为 0 表示不限使用次数
+func (i *Invite) BatchInsertInvites(uid, startTime, endTime, maxUses int, limitType int8, codes *[]string) error {
 	var list []*Invite
 	for _, code := range *codes {
 		inv := &Invite{
@@ -37,7 +72,8 @@ func (i *Invite) BatchInsertInvites(uid, startTime, endTime int, limitType int8,
 			LimitType: limitType,
 			StartTime: startTime,
 			EndTime:   endTime,
-			Status:    0,
+			MaxUses:   maxUses,
+			Status:    INVITE_STATUS_NORMAL,
 			CreateUid: uid,
 		}
 		list = append(list, inv)
@@ -45,9 +81,50 @@ func (i *Invite) BatchInsertInvites(uid, startTime, endTime int, limitType int8,
 	return global.DBEngine.Create(list).Error
 }
 
-func (i *Invite) UpdateInviteStatus() error {
-	invite := Invite{
-		Status: 1,
+// 分页查询邀请码，status 为 nil、createUid 和 code 为空值时不作为筛选条件
+func (i *Invite) GetInvites(status *int8, createUid int, code string, page, pageSize int) (*[]Invite, int64, error) {
+	var list []Invite
+	var total int64
+	db := global.DBEngine.Model(i)
+	if status != nil {
+		db = db.Where("status", *status)
+	}
+	if createUid > 0 {
+		db = db.Where("create_uid", createUid)
+	}
+	if code != "" {
+		db = db.Where("code", code)
+	}
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Order("id desc").Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &list, total, nil
+}
+
+// 作废邀请码，返回是否有记录被更新
+func (i *Invite) RevokeInvite(id int) (bool, error) {
+	res := global.DBEngine.Model(i).Where("id = ? AND status <> ?", id, INVITE_STATUS_REVOKED).Update("status", INVITE_STATUS_REVOKED)
+	return res.RowsAffected > 0, res.Error
+}
+
+// 在事务中核销邀请码：使用次数加一，达到上限时标记为已用完，可用次数不足时返回 ErrInviteUsedUp
+// MySQL 按顺序执行 SET 赋值，status 判断时 used_count 已是加一后的值
+func (i *Invite) redeem(tx *gorm.DB) error {
+	limit := inviteUseLimitSQL
+	res := tx.Exec("UPDATE invite SET used_count = used_count + 1, "+
+		"status = IF("+limit+" > 0 AND used_count >= "+limit+", ?, status), update_time = UNIX_TIMESTAMP() "+
+		"WHERE id = ? AND status = ? AND ("+limit+" = 0 OR used_count < "+limit+")",
+		INVITE_STATUS_USED, i.Id, INVITE_STATUS_NORMAL)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInviteUsedUp
 	}
-	return global.DBEngine.Model(i).Updates(invite).Error
+	return nil
 }
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 
 	"cc/be/setting"
 
@@ -80,3 +81,10 @@ func listToUpdateMap(list []Model2) map[string]int64 {
 	// }
 	// return m
 }
+
+// 转义 LIKE 模式中的通配符，用户输入的 % 和 _ 按普通字符匹配
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
@@ -50,8 +50,13 @@ func (p *Propext) UpdatePropexts(propexts *[]*Propext) error {
 
 // 获取列表
 func (p *Propext) GetPropexts(uid int, ids *[]string) (*map[string]string, error) {
+	return p.GetPropextsByUids([]int{uid}, ids)
+}
+
+// 获取多个用户的扩展信息，用于同步共享空间的数据
+func (p *Propext) GetPropextsByUids(uids []int, ids *[]string) (*map[string]string, error) {
 	var propexts []Propext
-	err := global.DBEngine.Table("propext").Select("id,type_id,props").Where("uid", uid).Where("id in ?", *ids).Find(&propexts).Error
+	err := global.DBEngine.Table("propext").Select("id,type_id,props").Where("uid in ?", uids).Where("id in ?", *ids).Find(&propexts).Error
 	if err != nil {
 		return nil, err
 	}
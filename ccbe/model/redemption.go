@@ -0,0 +1,59 @@
+package model
+
+import (
+	"cc/be/global"
+	"cc/be/utils"
+
+	"gorm.io/gorm"
+)
+
+// 邀请码使用记录
+type Redemption struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	InviteId   int    `json:"invite_id"`
+	Code       string `json:"code"`
+	Uid        int    `json:"uid"`
+	Pid        int    `json:"pid"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Redemption) TableName() string {
+	return "redemption"
+}
+
+// 邀请码使用记录及注册用户信息
+type RedemptionUser struct {
+	Redemption
+	Username string `json:"username"`
+	Mobile   string `json:"mobile"`
+}
+
+// 在注册事务中核销邀请码并写入使用记录
+func (r *Redemption) create(tx *gorm.DB, invite *Invite, uid int) error {
+	if err := invite.redeem(tx); err != nil {
+		return err
+	}
+	r.InviteId = invite.Id
+	r.Code = invite.Code
+	r.Uid = uid
+	r.Pid = invite.CreateUid
+	return tx.Create(r).Error
+}
+
+// 分页查询邀请码的使用记录
+func (r *Redemption) GetRedemptions(inviteId, page, pageSize int) (*[]RedemptionUser, int64, error) {
+	var list []RedemptionUser
+	var total int64
+	db := global.DBEngine.Model(r).Where("invite_id", inviteId)
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Select("redemption.*, user.username, user.mobile").
+		Joins("LEFT JOIN user ON user.id = redemption.uid").
+		Order("redemption.id desc").Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &list, total, nil
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"cc/be/global"
+	"cc/be/utils"
+)
+
+// 被邀请用户信息
+type Invitee struct {
+	Id         int    `json:"uid"`
+	Pid        int    `json:"pid"`
+	Username   string `json:"username"`
+	Avatar     string `json:"avatar"`
+	Status     int8   `json:"status"`
+	CreateTime int    `json:"create_time"`
+}
+
+// 邀请人及邀请数量
+type InviterCount struct {
+	Pid      int    `json:"uid"`
+	Username string `json:"username"`
+	Cnt      int64  `json:"cnt"`
+}
+
+// 按时间段统计的数量
+type PeriodCount struct {
+	Period string `json:"period"`
+	Cnt    int64  `json:"cnt"`
+}
+
+// 按时间段统计的注册数量，referred 为通过邀请注册的数量
+type PeriodRegister struct {
+	Period   string `json:"period"`
+	Cnt      int64  `json:"cnt"`
+	Referred int64  `json:"referred"`
+}
+
+// 分页查询用户邀请的账号
+func (u *User) GetInvitees(pid, page, pageSize int) (*[]Invitee, int64, error) {
+	var list []Invitee
+	var total int64
+	db := global.DBEngine.Model(u).Where("pid", pid)
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Select("id,pid,username,avatar,status,create_time").Order("id desc").
+		Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &list, total, nil
+}
+
+// 查询指定用户的邀请关系信息
+func (u *User) GetInviteeById(uid int) (*Invitee, error) {
+	item := &Invitee{}
+	err := global.DBEngine.Model(u).Select("id,pid,username,avatar,status,create_time").
+		Where("id", uid).Take(item).Error
+	return item, err
+}
+
+// 查询多个邀请人邀请的账号，最多返回 limit 条
+func (u *User) GetInviteesByPids(pids []int, limit int) (*[]Invitee, error) {
+	var list []Invitee
+	err := global.DBEngine.Model(u).Select("id,pid,username,avatar,status,create_time").
+		Where("pid IN ?", pids).Order("id").Limit(limit).Find(&list).Error
+	return &list, err
+}
+
+// 分页查询顶层邀请人：自身非受邀注册且邀请过其他用户
+func (u *User) GetRootInviters(page, pageSize int) (*[]Invitee, int64, error) {
+	var list []Invitee
+	var total int64
+	sub := global.DBEngine.Model(u).Distinct("pid").Where("pid > 0")
+	db := global.DBEngine.Model(u).Where("pid = 0 AND id IN (?)", sub)
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = db.Select("id,pid,username,avatar,status,create_time").Order("id").
+		Offset(utils.GetPageOffset(page, pageSize)).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &list, total, nil
+}
+
+// 统计多个用户各自邀请的账号数量
+func (u *User) CountInvitees(pids []int) (map[int]int64, error) {
+	var list []InviterCount
+	err := global.DBEngine.Model(u).Select("pid, count(*) AS cnt").
+		Where("pid IN ?", pids).Group("pid").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int]int64, len(list))
+	for _, item := range list {
+		res[item.Pid] = item.Cnt
+	}
+	return res, nil
+}
+
+// 统计时间范围内邀请注册数量最多的邀请人
+func (u *User) GetTopInviters(startTime, endTime, limit int) (*[]InviterCount, error) {
+	var list []InviterCount
+	err := global.DBEngine.Table("user AS u").Select("u.pid, p.username, count(*) AS cnt").
+		Joins("LEFT JOIN user AS p ON p.id = u.pid").
+		Where("u.pid > 0 AND u.create_time >= ? AND u.create_time < ?", startTime, endTime).
+		Group("u.pid, p.username").Order("cnt desc").Limit(limit).Find(&list).Error
+	return &list, err
+}
+
+// 按时间段统计注册数量和受邀注册数量，format 为 MySQL 日期格式
+func (u *User) GetRegisterStats(startTime, endTime int, format string) (*[]PeriodRegister, error) {
+	var list []PeriodRegister
+	err := global.DBEngine.Model(u).
+		Select("FROM_UNIXTIME(create_time, ?) AS period, count(*) AS cnt, SUM(pid > 0) AS referred", format).
+		Where("create_time >= ? AND create_time < ?", startTime, endTime).
+		Group("period").Order("period").Find(&list).Error
+	return &list, err
+}
+
+// 按时间段统计邀请码生成数量
+func (i *Invite) GetCreateStats(startTime, endTime int, format string) (*[]PeriodCount, error) {
+	var list []PeriodCount
+	err := global.DBEngine.Model(i).
+		Select("FROM_UNIXTIME(create_time, ?) AS period, count(*) AS cnt", format).
+		Where("create_time >= ? AND create_time < ?", startTime, endTime).
+		Group("period").Order("period").Find(&list).Error
+	return &list, err
+}
+
+// 按时间段统计邀请码使用数量
+func (r *Redemption) GetRedeemStats(startTime, endTime int, format string) (*[]PeriodCount, error) {
+	var list []PeriodCount
+	err := global.DBEngine.Model(r).
+		Select("FROM_UNIXTIME(create_time, ?) AS period, count(*) AS cnt", format).
+		Where("create_time >= ? AND create_time < ?", startTime, endTime).
+		Group("period").Order("period").Find(&list).Error
+	return &list, err
+}
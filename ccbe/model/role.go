@@ -0,0 +1,89 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 用户角色: user-普通用户(默认), support-客服, admin-管理员
+const ROLE_USER = "user"
+const ROLE_SUPPORT = "support"
+const ROLE_ADMIN = "admin"
+
+// 权限名称
+const PERM_ACCOUNT_ADD = "account:add"
+const PERM_INVITE_GENERATE = "invite:generate"
+const PERM_CLIENT_UPDATE = "client:update"
+const PERM_ROLE_MANAGE = "role:manage"
+const PERM_AUDIT_READ = "audit:read"
+const PERM_TOTP_RESET = "totp:reset"
+const PERM_LOCKOUT_MANAGE = "lockout:manage"
+const PERM_INVITE_MANAGE = "invite:manage"
+const PERM_REFERRAL_READ = "referral:read"
+const PERM_QUOTA_MANAGE = "quota:manage"
+
+// 角色拥有的权限列表
+var RolePermissions = map[string][]string{
+	ROLE_USER:    {},
+	ROLE_SUPPORT: {PERM_ACCOUNT_ADD, PERM_INVITE_GENERATE, PERM_AUDIT_READ, PERM_LOCKOUT_MANAGE, PERM_INVITE_MANAGE, PERM_REFERRAL_READ},
+	ROLE_ADMIN:   {PERM_ACCOUNT_ADD, PERM_INVITE_GENERATE, PERM_CLIENT_UPDATE, PERM_ROLE_MANAGE, PERM_AUDIT_READ, PERM_TOTP_RESET, PERM_LOCKOUT_MANAGE, PERM_INVITE_MANAGE, PERM_REFERRAL_READ, PERM_QUOTA_MANAGE},
+}
+
+// 判断角色是否存在
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// 判断角色列表是否拥有指定权限
+func RolesHavePermission(roles []string, perm string) bool {
+	for _, role := range roles {
+		for _, p := range RolePermissions[role] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type Userrole struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Role       string `json:"role"`
+	CreateUid  int    `json:"create_uid"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Userrole) TableName() string {
+	return "userrole"
+}
+
+// 获取用户的角色列表
+func (r *Userrole) GetRoles(uid int) ([]string, error) {
+	var roles []string
+	err := global.DBEngine.Model(r).Where("uid", uid).Pluck("role", &roles).Error
+	return roles, err
+}
+
+// 获取用户的角色记录
+func (r *Userrole) GetUserroles(uid int) (*[]Userrole, error) {
+	var list []Userrole
+	err := global.DBEngine.Select("id,uid,role,create_uid,create_time").Where("uid", uid).Order("id").Find(&list).Error
+	return &list, err
+}
+
+func (r *Userrole) ExistRole(uid int, role string) bool {
+	res := global.DBEngine.Select("id").Where("uid", uid).Where("role", role).Take(r)
+	return res.RowsAffected > 0
+}
+
+func (r *Userrole) CreateUserrole(uid int, role string, createUid int) error {
+	r.Uid = uid
+	r.Role = role
+	r.CreateUid = createUid
+	return global.DBEngine.Create(r).Error
+}
+
+func (r *Userrole) DeleteUserrole(uid int, role string) error {
+	return global.DBEngine.Where("uid", uid).Where("role", role).Delete(r).Error
+}
@@ -3,20 +3,36 @@ package model
 import (
 	"cc/be/global"
 	"cc/be/utils"
+
+	"gorm.io/gorm"
 )
 
+// 分享模式：0-快照，分享时上传的视图内容；1-实时，访问时根据视图当前数据生成
+const SHARE_MODE_SNAPSHOT = 0
+const SHARE_MODE_LIVE = 1
+
 type Share struct {
-	Id         int    `gorm:"primary_key" json:"id"`
-	Uuid       string `json:"uuid"`
-	Uid        int    `json:"uid"`
-	ViewId     string `json:"view_id"`
-	Name       string `json:"name"`
-	Type       int8   `json:"type"`
-	Icon       string `json:"icon"`
-	Status     int8   `json:"status"`
-	Content    string `json:"content"`
-	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
-	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+	Id           int    `gorm:"primary_key" json:"id"`
+	Uuid         string `json:"uuid"`
+	Uid          int    `json:"uid"`
+	ViewId       string `json:"view_id"`
+	CardId       string `json:"card_id"`
+	Name         string `json:"name"`
+	Type         int8   `json:"type"`
+	Icon         string `json:"icon"`
+	Status       int8   `json:"status"`
+	Mode         int8   `json:"mode"`
+	Content      string `json:"content"`
+	Password     string `json:"-"`
+	ExpireTime   int    `json:"expire_time"`
+	MaxViews     int    `json:"max_views"`
+	ViewCount    int    `json:"view_count"`
+	Rev          int    `json:"rev"`
+	PinRev       int    `json:"pin_rev"`
+	RevPublic    int8   `json:"rev_public"`
+	EmbedOrigins string `json:"embed_origins"`
+	CreateTime   int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime   int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
 }
 
 func (Share) TableName() string {
@@ -24,13 +40,48 @@ func (Share) TableName() string {
 }
 
 func (s *Share) GetShareInfo(uid int, viewId string) error {
-	return global.DBEngine.Select("id,uuid,status,update_time").Where("uid", uid).Where("view_id", viewId).Take(s).Error
+	return global.DBEngine.Select("id,uuid,uid,status,mode,password,expire_time,max_views,view_count,rev,pin_rev,rev_public,embed_origins,update_time").Where("uid", uid).Where("view_id", viewId).Take(s).Error
 }
 
 func (s *Share) GetShareData(shareId string) error {
-	return global.DBEngine.Select("uuid,view_id,name,type,icon,status,content,update_time").Where("uuid", shareId).Take(s).Error
+	return global.DBEngine.Select("id,uuid,uid,view_id,card_id,name,type,icon,status,mode,content,password,expire_time,max_views,view_count,rev,pin_rev,rev_public,embed_origins,update_time").Where("uuid", shareId).Take(s).Error
+}
+
+// 获取指定卡片的分享信息
+func (s *Share) GetCardShareInfo(uid int, cardId string) error {
+	return global.DBEngine.Select("id,uuid,card_id,status,mode,password,expire_time,max_views,view_count,embed_origins,update_time").Where("uid", uid).Where("card_id", cardId).Take(s).Error
 }
 
+// 是否为单张卡片的分享
+func (s *Share) IsCardShare() bool {
+	return s.CardId != ""
+}
+
+// 是否允许嵌入到其他网站
+func (s *Share) CanEmbed() bool {
+	return s.EmbedOrigins != ""
+}
+
+// 是否设置了访问密码
+func (s *Share) HasPassword() bool {
+	return s.Password != ""
+}
+
+// 访问次数加一，已达到最大访问次数时返回 false
+func (s *Share) IncrViewCount() (bool, error) {
+	res := global.DBEngine.Model(s).Where("max_views = 0 OR view_count < max_views").
+		UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+	s.ViewCount++
+	return true, nil
+}
+
+// 创建分享，分享模式、访问密码、过期时间和最大访问次数需提前设置
 func (s *Share) CreateShare(uid int, t int8, viewId, name, icon, content string) error {
 	s.Uid = uid
 	s.Uuid = utils.UnidByNum(24)
@@ -43,15 +94,42 @@ func (s *Share) CreateShare(uid int, t int8, viewId, name, icon, content string)
 	return global.DBEngine.Create(s).Error
 }
 
+// 创建卡片分享，卡片分享的内容总是在访问时实时生成
+func (s *Share) CreateCardShare(uid int, cardId, name, icon string) error {
+	s.Uid = uid
+	s.Uuid = utils.UnidByNum(24)
+	s.CardId = cardId
+	s.Name = name
+	s.Icon = icon
+	s.Status = 1
+	s.Mode = SHARE_MODE_LIVE
+	return global.DBEngine.Create(s).Error
+}
+
 func (s *Share) UpdateShare(id int, name, icon, content string) error {
 	s.Name = name
 	s.Icon = icon
 	s.Status = 1
 	s.Content = content
-	return global.DBEngine.Select("name", "icon", "status", "content", "update_time").Updates(s).Error
+	return global.DBEngine.Select("name", "icon", "status", "mode", "content", "password", "expire_time", "max_views", "rev", "pin_rev", "rev_public", "embed_origins", "update_time").Updates(s).Error
+}
+
+// 更新分享的公开内容和版本号
+func (s *Share) UpdateShareRevision(rv *Sharerevision, pinRev int) error {
+	s.Name = rv.Name
+	s.Icon = rv.Icon
+	s.Type = rv.Type
+	s.Content = rv.Content
+	s.PinRev = pinRev
+	return global.DBEngine.Select("name", "icon", "type", "content", "rev", "pin_rev", "update_time").Updates(s).Error
 }
 
 func (s *Share) UpdateShareStatus(uid int, viewId string, status int8) error {
 	s.Status = status
 	return global.DBEngine.Select("status", "update_time").Where("uid", uid).Where("view_id", viewId).Updates(s).Error
 }
+
+func (s *Share) UpdateCardShareStatus(uid int, cardId string, status int8) error {
+	s.Status = status
+	return global.DBEngine.Select("status", "update_time").Where("uid", uid).Where("card_id", cardId).Updates(s).Error
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 快照分享的历史版本，每次刷新分享时保存，保存后不再修改
+type Sharerevision struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	ShareId    int    `json:"share_id"`
+	Uid        int    `json:"uid"`
+	Rev        int    `json:"rev"`
+	Name       string `json:"name"`
+	Type       int8   `json:"type"`
+	Icon       string `json:"icon"`
+	Content    string `json:"content,omitempty"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Sharerevision) TableName() string {
+	return "sharerevision"
+}
+
+// 获取分享的历史版本列表，不包含版本内容
+func (r *Sharerevision) GetRevisions(shareId int) (*[]Sharerevision, error) {
+	var list []Sharerevision
+	err := global.DBEngine.Select("id,rev,name,type,icon,create_time").Where("share_id", shareId).Order("rev desc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *Sharerevision) GetRevision(shareId, rev int) error {
+	return global.DBEngine.Where("share_id", shareId).Where("rev", rev).Take(r).Error
+}
+
+func (r *Sharerevision) CreateRevision() error {
+	return global.DBEngine.Create(r).Error
+}
+
+// 删除早于 minRev 的历史版本，保留固定公开的版本
+func (r *Sharerevision) PruneRevisions(shareId, minRev, pinRev int) error {
+	return global.DBEngine.Where("share_id", shareId).Where("rev < ?", minRev).Where("rev <> ?", pinRev).Delete(r).Error
+}
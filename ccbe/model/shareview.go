@@ -0,0 +1,67 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 分享访问记录，IP 已匿名化，visitor 为匿名 IP 和 UA 的摘要用于统计独立访客
+type Shareview struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	ShareId    int    `json:"share_id"`
+	Uid        int    `json:"uid"`
+	Ip         string `json:"ip"`
+	Visitor    string `json:"visitor"`
+	Referer    string `json:"referer"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Shareview) TableName() string {
+	return "shareview"
+}
+
+// 按天统计的访问数据
+type ShareviewDaily struct {
+	Date     string `json:"date"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+}
+
+// 来源统计
+type ShareviewReferer struct {
+	Referer string `json:"referer"`
+	Views   int64  `json:"views"`
+}
+
+func (s *Shareview) CreateShareview() error {
+	return global.DBEngine.Create(s).Error
+}
+
+// 统计分享自 startTime 起的访问次数和独立访客数
+func (s *Shareview) CountShareviews(shareId, startTime int) (int64, int64, error) {
+	var res struct {
+		Views    int64
+		Visitors int64
+	}
+	err := global.DBEngine.Model(s).Select("count(*) AS views, count(DISTINCT visitor) AS visitors").
+		Where("share_id = ? AND create_time >= ?", shareId, startTime).Take(&res).Error
+	return res.Views, res.Visitors, err
+}
+
+// 按天统计分享的访问次数和独立访客数
+func (s *Shareview) GetDailyStats(shareId, startTime int) (*[]ShareviewDaily, error) {
+	var list []ShareviewDaily
+	err := global.DBEngine.Model(s).
+		Select("FROM_UNIXTIME(create_time, '%Y-%m-%d') AS date, count(*) AS views, count(DISTINCT visitor) AS visitors").
+		Where("share_id = ? AND create_time >= ?", shareId, startTime).
+		Group("date").Order("date").Find(&list).Error
+	return &list, err
+}
+
+// 统计访问来源，按访问次数倒序
+func (s *Shareview) GetTopReferers(shareId, startTime, limit int) (*[]ShareviewReferer, error) {
+	var list []ShareviewReferer
+	err := global.DBEngine.Model(s).Select("referer, count(*) AS views").
+		Where("share_id = ? AND create_time >= ?", shareId, startTime).
+		Group("referer").Order("views desc").Limit(limit).Find(&list).Error
+	return &list, err
+}
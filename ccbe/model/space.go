@@ -29,9 +29,9 @@ func (s *Space) GetSpaceCheckMap(uid int, ids []string) (*map[string]int64, erro
 }
 
 // 获取节点分组列表
-func (s *Space) GetSpaces(uid int, updateTime int64, limit int) (*[]Space, error) {
+func (s *Space) GetSpaces(scope *SyncScope, updateTime int64, limit int) (*[]Space, error) {
 	var spaces []Space
-	err := global.DBEngine.Table("space").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&spaces).Error
+	err := global.DBEngine.Table("space").Select("*").Scopes(scope.Space()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&spaces).Error
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +39,7 @@ func (s *Space) GetSpaces(uid int, updateTime int64, limit int) (*[]Space, error
 	if len(spaces) == limit {
 		// 获取剩余记录
 		var reminds []Space
-		err = global.DBEngine.Table("space").Select("*").Where("uid", uid).Where("update_time", spaces[limit-1].UpdateTime).Where("unid > ?", spaces[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("space").Select("*").Scopes(scope.Space()).Where("update_time", spaces[limit-1].UpdateTime).Where("unid > ?", spaces[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -68,3 +68,8 @@ func (s *Space) UpdateSpaces(spaces *[]*Space) error {
 	}
 	return nil
 }
+
+// 获取未删除的空间
+func (s *Space) GetSpaceById(uid int, spaceId string) error {
+	return global.DBEngine.Where("uid", uid).Where("id", spaceId).Where("is_deleted", 0).Take(s).Error
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 共享空间成员角色: 1-所有者，2-编辑者，3-查看者
+// 所有者即空间数据所在的 uid，不保存成员记录
+const SPACE_ROLE_OWNER = 1
+const SPACE_ROLE_EDITOR = 2
+const SPACE_ROLE_VIEWER = 3
+
+// 成员状态: 0-已邀请待接受，1-已加入
+const SPACE_MEMBER_INVITED = 0
+const SPACE_MEMBER_JOINED = 1
+
+type Spacemember struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	SpaceId    string `json:"space_id"`
+	OwnerUid   int    `json:"owner_uid"`
+	Uid        int    `json:"uid"`
+	Role       int8   `json:"role"`
+	Status     int8   `json:"status"`
+	InviteUid  int    `json:"invite_uid"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Spacemember) TableName() string {
+	return "spacemember"
+}
+
+// 空间成员及用户信息
+type SpacememberUser struct {
+	Spacemember
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+}
+
+// 待接受的空间邀请
+type SpaceInvite struct {
+	Spacemember
+	SpaceName string `json:"space_name"`
+	SpaceIcon string `json:"space_icon"`
+	OwnerName string `json:"owner_name"`
+}
+
+func IsValidSpaceRole(role int8) bool {
+	return role == SPACE_ROLE_EDITOR || role == SPACE_ROLE_VIEWER
+}
+
+// 获取用户在指定空间的成员记录
+func (m *Spacemember) GetMember(spaceId string, uid int) error {
+	return global.DBEngine.Where("space_id", spaceId).Where("uid", uid).Take(m).Error
+}
+
+func (m *Spacemember) SelectById(id int) error {
+	return global.DBEngine.Where("id", id).Take(m).Error
+}
+
+// 获取用户已加入的共享空间
+func (m *Spacemember) GetJoinedMembers(uid int) (*[]Spacemember, error) {
+	var list []Spacemember
+	err := global.DBEngine.Where("uid", uid).Where("status", SPACE_MEMBER_JOINED).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// 获取空间的成员列表，包含待接受邀请的成员
+func (m *Spacemember) GetSpaceMembers(ownerUid int, spaceId string) (*[]SpacememberUser, error) {
+	var list []SpacememberUser
+	err := global.DBEngine.Table("spacemember m").
+		Select("m.*, u.username, u.avatar").
+		Joins("LEFT JOIN user u ON u.id = m.uid").
+		Where("m.owner_uid", ownerUid).Where("m.space_id", spaceId).
+		Order("m.id").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// 获取指定空间已加入成员的 uid
+func (m *Spacemember) GetMemberUids(ownerUid int, spaceIds []string) ([]int, error) {
+	uids := []int{}
+	err := global.DBEngine.Table("spacemember").Distinct("uid").
+		Where("owner_uid", ownerUid).Where("space_id in ?", spaceIds).
+		Where("status", SPACE_MEMBER_JOINED).Pluck("uid", &uids).Error
+	return uids, err
+}
+
+// 获取用户待接受的空间邀请
+func (m *Spacemember) GetInvites(uid int) (*[]SpaceInvite, error) {
+	var list []SpaceInvite
+	err := global.DBEngine.Table("spacemember m").
+		Select("m.*, s.name AS space_name, s.icon AS space_icon, u.username AS owner_name").
+		Joins("JOIN space s ON s.uid = m.owner_uid AND s.id = m.space_id AND s.is_deleted = 0").
+		Joins("LEFT JOIN user u ON u.id = m.owner_uid").
+		Where("m.uid", uid).Where("m.status", SPACE_MEMBER_INVITED).
+		Order("m.id desc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (m *Spacemember) CreateMember() error {
+	return global.DBEngine.Create(m).Error
+}
+
+func (m *Spacemember) UpdateRole(role int8) error {
+	m.Role = role
+	return global.DBEngine.Model(m).Select("role", "update_time").Updates(m).Error
+}
+
+func (m *Spacemember) Join() error {
+	m.Status = SPACE_MEMBER_JOINED
+	return global.DBEngine.Model(m).Select("status", "update_time").Updates(m).Error
+}
+
+func (m *Spacemember) DeleteMember() error {
+	return global.DBEngine.Delete(m).Error
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"cc/be/global"
+
+	"gorm.io/gorm"
+)
+
+// 数据同步范围：用户自己的数据，以及已加入的共享空间中保存在所有者名下的数据
+type SyncScope struct {
+	Uid int
+	// 共享空间所有者 uid -> 空间 id 列表
+	Shared map[int][]string
+}
+
+func NewSyncScope(uid int) *SyncScope {
+	return &SyncScope{Uid: uid, Shared: map[int][]string{}}
+}
+
+// 添加已加入的共享空间
+func (s *SyncScope) AddSpace(ownerUid int, spaceId string) {
+	s.Shared[ownerUid] = append(s.Shared[ownerUid], spaceId)
+}
+
+// 范围内所有数据所在的 uid
+func (s *SyncScope) Uids() []int {
+	uids := []int{s.Uid}
+	for uid := range s.Shared {
+		if uid != s.Uid {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+func (s *SyncScope) where(owner func(uid int, spaceIds []string) *gorm.DB) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(s.Shared) == 0 {
+			return db.Where("uid", s.Uid)
+		}
+		cond := global.DBEngine.Where("uid", s.Uid)
+		for uid, spaceIds := range s.Shared {
+			cond = cond.Or(owner(uid, spaceIds))
+		}
+		return db.Where(cond)
+	}
+}
+
+// 空间表，按空间 id 筛选
+func (s *SyncScope) Space() func(*gorm.DB) *gorm.DB {
+	return s.where(func(uid int, spaceIds []string) *gorm.DB {
+		return global.DBEngine.Where("uid", uid).Where("id in ?", spaceIds)
+	})
+}
+
+// 有 space_id 字段的数据表: card/tag/view
+func (s *SyncScope) BySpace() func(*gorm.DB) *gorm.DB {
+	return s.where(func(uid int, spaceIds []string) *gorm.DB {
+		return global.DBEngine.Where("uid", uid).Where("space_id in ?", spaceIds)
+	})
+}
+
+// 属于视图的数据表: viewnode/viewedge
+func (s *SyncScope) ByView() func(*gorm.DB) *gorm.DB {
+	return s.where(func(uid int, spaceIds []string) *gorm.DB {
+		views := global.DBEngine.Table("view").Select("id").Where("uid", uid).Where("space_id in ?", spaceIds)
+		return global.DBEngine.Where("uid", uid).Where("view_id in (?)", views)
+	})
+}
+
+// 类型表，共享空间中只包含空间内卡片使用的类型，类型可能属于创建卡片的其他成员
+// 卡片的 type_id 由客户端写入，只查询空间所有者和已加入成员的类型
+func (s *SyncScope) ByCardType() func(*gorm.DB) *gorm.DB {
+	return s.where(func(uid int, spaceIds []string) *gorm.DB {
+		types := global.DBEngine.Table("card").Distinct("type_id").Where("uid", uid).Where("space_id in ?", spaceIds)
+		members := global.DBEngine.Table("spacemember").Select("uid").
+			Where("owner_uid", uid).Where("space_id in ?", spaceIds).Where("status", SPACE_MEMBER_JOINED)
+		return global.DBEngine.Where("id in (?)", types).Where(global.DBEngine.Where("uid", uid).Or("uid in (?)", members))
+	})
+}
+
+// 已存在数据所在的空间
+type RowSpace struct {
+	Id      string
+	SpaceId string
+}
+
+func rowSpaceMap(list []RowSpace) map[string]string {
+	m := make(map[string]string, len(list))
+	for _, v := range list {
+		m[v.Id] = v.SpaceId
+	}
+	return m
+}
+
+// 查询已存在数据所在的空间，table 为有 space_id 字段的数据表: card/tag/view
+func GetRowSpaces(table string, uid int, ids []string) (map[string]string, error) {
+	var list []RowSpace
+	err := global.DBEngine.Table(table).Select("id,space_id").Where("uid", uid).Where("id in ?", ids).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return rowSpaceMap(list), nil
+}
+
+// 查询已存在数据所属视图的空间，table 为属于视图的数据表: viewnode/viewedge
+// 视图不存在时空间为空
+func GetViewRowSpaces(table string, uid int, ids []string) (map[string]string, error) {
+	var list []RowSpace
+	err := global.DBEngine.Table(table+" t").Select("t.id,ifnull(v.space_id,'') space_id").
+		Joins("left join view v on v.uid = t.uid and v.id = t.view_id").
+		Where("t.uid", uid).Where("t.id in ?", ids).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return rowSpaceMap(list), nil
+}
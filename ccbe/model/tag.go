@@ -28,9 +28,9 @@ func (t *Tag) GetTagCheckMap(uid int, ids []string) (*map[string]int64, error) {
 }
 
 // 获取节点分组列表
-func (t *Tag) GetTags(uid int, updateTime int64, limit int) (*[]Tag, error) {
+func (t *Tag) GetTags(scope *SyncScope, updateTime int64, limit int) (*[]Tag, error) {
 	var tags []Tag
-	err := global.DBEngine.Table("tag").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&tags).Error
+	err := global.DBEngine.Table("tag").Select("*").Scopes(scope.BySpace()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&tags).Error
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +38,7 @@ func (t *Tag) GetTags(uid int, updateTime int64, limit int) (*[]Tag, error) {
 	if len(tags) == limit {
 		// 获取剩余记录
 		var reminds []Tag
-		err = global.DBEngine.Table("tag").Select("*").Where("uid", uid).Where("update_time", tags[limit-1].UpdateTime).Where("unid > ?", tags[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("tag").Select("*").Scopes(scope.BySpace()).Where("update_time", tags[limit-1].UpdateTime).Where("unid > ?", tags[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"cc/be/global"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证状态：0-待激活，1-已启用
+const TOTP_STATUS_PENDING = 0
+const TOTP_STATUS_ENABLED = 1
+
+type Totp struct {
+	Id          int    `gorm:"primary_key" json:"id"`
+	Uid         int    `json:"uid"`
+	Secret      string `json:"-"`
+	Status      int8   `json:"status"`
+	LastCounter int64  `json:"-"`
+	CreateTime  int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime  int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Totp) TableName() string {
+	return "totp"
+}
+
+func (t *Totp) SelectByUid(uid int) error {
+	return global.DBEngine.Select("id,uid,secret,status,last_counter,update_time").Where("uid", uid).Take(t).Error
+}
+
+// 判断用户是否已启用两步验证
+func (t *Totp) IsEnabled(uid int) bool {
+	nt := &Totp{}
+	res := global.DBEngine.Select("id").Where("uid", uid).Where("status", TOTP_STATUS_ENABLED).Take(nt)
+	return res.RowsAffected > 0
+}
+
+// 保存待激活的密钥，已存在未激活记录时覆盖
+func (t *Totp) SavePending(uid int, secret string) error {
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uid", uid).Delete(&Totp{}).Error; err != nil {
+			return err
+		}
+		t.Uid = uid
+		t.Secret = secret
+		t.Status = TOTP_STATUS_PENDING
+		return tx.Create(t).Error
+	})
+}
+
+// 激活两步验证，同时写入恢复码
+func (t *Totp) Enable(id int, counter int64, uid int, hashes []string) error {
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Totp{}).Where("id", id).Updates(map[string]interface{}{
+			"status":       TOTP_STATUS_ENABLED,
+			"last_counter": counter,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoverycodes(tx, uid, hashes)
+	})
+}
+
+// 更新最近一次使用的步数，只允许递增，防止同一验证码重复使用
+func (t *Totp) UpdateLastCounter(id int, counter int64) (int64, error) {
+	res := global.DBEngine.Model(&Totp{}).Where("id", id).Where("last_counter < ?", counter).UpdateColumn("last_counter", counter)
+	return res.RowsAffected, res.Error
+}
+
+// 删除两步验证及恢复码
+func (t *Totp) DeleteByUid(uid int) error {
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uid", uid).Delete(&Totp{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("uid", uid).Delete(&Recoverycode{}).Error
+	})
+}
+
+// 两步验证的一次性恢复码，仅保存摘要
+type Recoverycode struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Hash       string `json:"-"`
+	UsedTime   int    `json:"used_time"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+}
+
+func (Recoverycode) TableName() string {
+	return "recoverycode"
+}
+
+func replaceRecoverycodes(tx *gorm.DB, uid int, hashes []string) error {
+	if err := tx.Where("uid", uid).Delete(&Recoverycode{}).Error; err != nil {
+		return err
+	}
+	list := make([]Recoverycode, len(hashes))
+	for i, hash := range hashes {
+		list[i] = Recoverycode{Uid: uid, Hash: hash}
+	}
+	return tx.Create(&list).Error
+}
+
+// 重新生成恢复码，原有恢复码全部失效
+func (r *Recoverycode) ReplaceRecoverycodes(uid int, hashes []string) error {
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoverycodes(tx, uid, hashes)
+	})
+}
+
+// 核销恢复码，返回是否核销成功
+func (r *Recoverycode) UseRecoverycode(uid int, hash string, t int) bool {
+	res := global.DBEngine.Model(r).Where("uid", uid).Where("hash", hash).Where("used_time", 0).UpdateColumn("used_time", t)
+	return res.Error == nil && res.RowsAffected > 0
+}
+
+// 统计剩余可用的恢复码数量
+func (r *Recoverycode) CountUnused(uid int) int64 {
+	var count int64
+	global.DBEngine.Model(r).Where("uid", uid).Where("used_time", 0).Count(&count)
+	return count
+}
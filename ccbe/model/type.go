@@ -31,9 +31,9 @@ func (s *Type) GetTypeCheckMap(uid int, ids []string) (*map[string]int64, error)
 }
 
 // 获取节点分组列表
-func (s *Type) GetTypes(uid int, updateTime int64, limit int) (*[]Type, error) {
+func (s *Type) GetTypes(scope *SyncScope, updateTime int64, limit int) (*[]Type, error) {
 	var types []Type
-	err := global.DBEngine.Table("type").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&types).Error
+	err := global.DBEngine.Table("type").Select("*").Scopes(scope.ByCardType()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&types).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +41,7 @@ func (s *Type) GetTypes(uid int, updateTime int64, limit int) (*[]Type, error) {
 	if len(types) == limit {
 		// 获取剩余记录
 		var reminds []Type
-		err = global.DBEngine.Table("type").Select("*").Where("uid", uid).Where("update_time", types[limit-1].UpdateTime).Where("unid > ?", types[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("type").Select("*").Scopes(scope.ByCardType()).Where("update_time", types[limit-1].UpdateTime).Where("unid > ?", types[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -64,3 +64,13 @@ func (s *Type) UpdateTypes(types *[]*Type) error {
 	}
 	return nil
 }
+
+// 根据 id 批量获取类型
+func (s *Type) GetTypesByIds(uid int, ids []string) (*[]Type, error) {
+	var types []Type
+	err := global.DBEngine.Select("id,name,icon,props").Where("uid", uid).Where("id in ?", ids).Find(&types).Error
+	if err != nil {
+		return nil, err
+	}
+	return &types, nil
+}
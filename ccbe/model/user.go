@@ -3,13 +3,13 @@ package model
 import (
 	"cc/be/global"
 	"cc/be/utils"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
 	Id         int    `gorm:"primary_key" json:"id"`
 	Mobile     string `json:"mobile"`
-	Openid     string `json:"openid"`
-	Unionid    string `json:"unionid"`
 	Username   string `json:"username"`
 	Avatar     string `json:"avatar"`
 	Password   string `json:"password"`
@@ -27,7 +27,7 @@ func (User) TableName() string {
 }
 
 func (u *User) SelectById(uid int) error {
-	return global.DBEngine.Select("id,mobile,openid,unionid,username,avatar,password,dbpassword,code,config").Where("id", uid).Take(u).Error
+	return global.DBEngine.Select("id,mobile,username,avatar,password,dbpassword,code,config").Where("id", uid).Take(u).Error
 }
 
 func (u *User) SelectByCode(code string) bool {
@@ -51,23 +51,13 @@ func (u *User) ExistByMobile(mobile string) bool {
 	return res.RowsAffected > 0
 }
 
-func (u *User) ExistByOpenid(openid string) bool {
-	nu := &User{}
-	res := global.DBEngine.Select("id").Where("openid", openid).Take(nu)
-	return res.RowsAffected > 0
-}
-
 // 根据 login_code 查询登陆码信息
 func (u *User) SelectByMobile(mobile string) error {
-	return global.DBEngine.Select("id,mobile,openid,unionid,username,avatar,password,dbpassword,code,config").Where("mobile = ?", mobile).Take(u).Error
-}
-
-// 根据 openid 查询用户信息
-func (u *User) SelectByOpenId(openid string) error {
-	return global.DBEngine.Select("id,mobile,openid,unionid,username,avatar,password,dbpassword,code,config").Where("openid = ?", openid).Take(u).Error
+	return global.DBEngine.Select("id,mobile,username,avatar,password,dbpassword,code,config").Where("mobile = ?", mobile).Take(u).Error
 }
 
-func (u *User) InsertUser(mobile, password, dbpassword, code string, pid int) error {
+// invite 不为空时在同一事务中核销邀请码
+func (u *User) InsertUser(mobile, password, dbpassword, code string, pid int, invite *Invite) error {
 	u.Mobile = mobile
 	u.Password = password
 	u.Dbpassword = dbpassword
@@ -76,19 +66,38 @@ func (u *User) InsertUser(mobile, password, dbpassword, code string, pid int) er
 	u.Code = code
 	u.Pid = pid
 	u.Config = "{}"
-	return global.DBEngine.Create(u).Error
-}
-
-func (u *User) InsertWechatUser(openid, unionid, username, avatar, dbpassword, code string, pid int) error {
-	u.Openid = openid
-	u.Unionid = unionid
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		if invite != nil {
+			return (&Redemption{}).create(tx, invite, u.Id)
+		}
+		return nil
+	})
+}
+
+// 根据第三方身份创建账号，同时写入身份关联，invite 不为空时在同一事务中核销邀请码
+func (u *User) InsertIdentityUser(identity *Identity, dbpassword, code string, pid int, invite *Invite) error {
 	u.Dbpassword = dbpassword
-	u.Username = utils.IfThen[string](username == "", "未命名", username)
-	u.Avatar = utils.IfThen[string](avatar == "", "/cc/avatar.png", avatar)
+	u.Username = utils.IfThen[string](identity.Username == "", "未命名", identity.Username)
+	u.Avatar = utils.IfThen[string](identity.Avatar == "", "/cc/avatar.png", identity.Avatar)
 	u.Code = code
 	u.Pid = pid
 	u.Config = "{}"
-	return global.DBEngine.Create(u).Error
+	return global.DBEngine.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		identity.Uid = u.Id
+		if err := tx.Create(identity).Error; err != nil {
+			return err
+		}
+		if invite != nil {
+			return (&Redemption{}).create(tx, invite, u.Id)
+		}
+		return nil
+	})
 }
 
 func (u *User) UpdateUser(mobile, username, password string) error {
@@ -104,14 +113,6 @@ func (u *User) UpdateUser(mobile, username, password string) error {
 	return global.DBEngine.Model(u).Updates(user).Error
 }
 
-func (u *User) BindWechat(uid int, openid, unionid string) error {
-	user := User{
-		Openid:  openid,
-		Unionid: unionid,
-	}
-	return global.DBEngine.Select("openid", "unionid", "update_time").Where("id", uid).Updates(user).Error
-}
-
 func (u *User) UpdateMobileAccount(uid int, mobile, password string) error {
 	user := User{
 		Mobile:   mobile,
@@ -120,22 +121,19 @@ func (u *User) UpdateMobileAccount(uid int, mobile, password string) error {
 	return global.DBEngine.Select("mobile", "password", "update_time").Where("id", uid).Updates(user).Error
 }
 
-func (u *User) UpdateUserinfo(uid int, username, avatar string) error {
+func (u *User) UpdatePassword(uid int, password string) error {
 	user := User{
-		Username: username,
-		Avatar:   avatar,
+		Password: password,
 	}
-	return global.DBEngine.Select("username", "avatar", "update_time").Where("id", uid).Updates(user).Error
+	return global.DBEngine.Select("password", "update_time").Where("id", uid).Updates(user).Error
 }
 
-func (u *User) UpdateUserBind(uid int, openid, unionid, username, avatar string) error {
+func (u *User) UpdateUserinfo(uid int, username, avatar string) error {
 	user := User{
-		Openid:   openid,
-		Unionid:  unionid,
 		Username: username,
 		Avatar:   avatar,
 	}
-	return global.DBEngine.Select("openid", "unionid", "username", "avatar", "update_time").Where("id", uid).Updates(user).Error
+	return global.DBEngine.Select("username", "avatar", "update_time").Where("id", uid).Updates(user).Error
 }
 
 func (u *User) UpdateConfig(uid int, config string) error {
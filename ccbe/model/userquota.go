@@ -0,0 +1,33 @@
+package model
+
+import (
+	"cc/be/global"
+)
+
+// 用户存储额度，Plan 为空时使用默认套餐，HardLimit/SoftLimit 为 0 时使用套餐的额度
+type Userquota struct {
+	Id         int    `gorm:"primary_key" json:"id"`
+	Uid        int    `json:"uid"`
+	Plan       string `json:"plan"`
+	HardLimit  int64  `json:"hard_limit"`
+	SoftLimit  int64  `json:"soft_limit"`
+	UpdateUid  int    `json:"update_uid"`
+	CreateTime int    `gorm:"autoCreateTime" json:"create_time,omitempty"`
+	UpdateTime int    `gorm:"autoUpdateTime" json:"update_time,omitempty"`
+}
+
+func (Userquota) TableName() string {
+	return "userquota"
+}
+
+func (q *Userquota) GetByUid(uid int) error {
+	return global.DBEngine.Where("uid", uid).Take(q).Error
+}
+
+// 保存用户额度，记录不存在时新增
+func (q *Userquota) SaveQuota() error {
+	if q.Id == 0 {
+		return global.DBEngine.Create(q).Error
+	}
+	return global.DBEngine.Model(q).Select("plan", "hard_limit", "soft_limit", "update_uid", "update_time").Updates(q).Error
+}
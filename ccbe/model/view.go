@@ -43,9 +43,9 @@ func (m *View) GetViewCheckMap(uid int, ids []string) (*map[string]int64, error)
 }
 
 // 获取节点分组列表
-func (m *View) GetViews(uid int, updateTime int64, limit int) (*[]View, error) {
+func (m *View) GetViews(scope *SyncScope, updateTime int64, limit int) (*[]View, error) {
 	var views []View
-	err := global.DBEngine.Table("view").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&views).Error
+	err := global.DBEngine.Table("view").Select("*").Scopes(scope.BySpace()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&views).Error
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func (m *View) GetViews(uid int, updateTime int64, limit int) (*[]View, error) {
 	if len(views) == limit {
 		// 获取剩余记录
 		var reminds []View
-		err = global.DBEngine.Table("view").Select("*").Where("uid", uid).Where("update_time", views[limit-1].UpdateTime).Where("unid > ?", views[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("view").Select("*").Scopes(scope.BySpace()).Where("update_time", views[limit-1].UpdateTime).Where("unid > ?", views[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -82,3 +82,28 @@ func (m *View) ExistView(uid int, viewId string) bool {
 	res := global.DBEngine.Select("unid").Where("uid", uid).Where("id", viewId).Take(m)
 	return res.RowsAffected > 0
 }
+
+// 获取未删除的视图
+func (m *View) GetViewById(uid int, viewId string) error {
+	return global.DBEngine.Where("uid", uid).Where("id", viewId).Where("is_deleted", 0).Take(m).Error
+}
+
+// 获取视图所在的用户和空间，用于校验共享空间的写入权限
+func (m *View) GetViewSpaces(uids []int, ids []string) (*[]View, error) {
+	var views []View
+	err := global.DBEngine.Select("uid,id,space_id").Where("uid in ?", uids).Where("id in ?", ids).Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+	return &views, nil
+}
+
+// 根据 id 批量获取未删除的视图
+func (m *View) GetViewsByIds(uid int, ids []string) (*[]View, error) {
+	var views []View
+	err := global.DBEngine.Select("id,name,space_id,pid,type,inline_type,icon,`desc`,update_time").Where("uid", uid).Where("id in ?", ids).Where("is_deleted", 0).Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+	return &views, nil
+}
@@ -33,9 +33,9 @@ func (s *Viewedge) GetViewedgeCheckMap(uid int, ids []string) (*map[string]int64
 }
 
 // 获取节点分组列表
-func (s *Viewedge) GetViewedges(uid int, updateTime int64, limit int) (*[]Viewedge, error) {
+func (s *Viewedge) GetViewedges(scope *SyncScope, updateTime int64, limit int) (*[]Viewedge, error) {
 	var viewedges []Viewedge
-	err := global.DBEngine.Table("viewedge").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&viewedges).Error
+	err := global.DBEngine.Table("viewedge").Select("*").Scopes(scope.ByView()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&viewedges).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +43,7 @@ func (s *Viewedge) GetViewedges(uid int, updateTime int64, limit int) (*[]Viewed
 	if len(viewedges) == limit {
 		// 获取剩余记录
 		var reminds []Viewedge
-		err = global.DBEngine.Table("viewedge").Select("*").Where("uid", uid).Where("update_time", viewedges[limit-1].UpdateTime).Where("unid > ?", viewedges[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("viewedge").Select("*").Scopes(scope.ByView()).Where("update_time", viewedges[limit-1].UpdateTime).Where("unid > ?", viewedges[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -67,3 +67,13 @@ func (s *Viewedge) UpdateViewedges(viewedges *[]*Viewedge) error {
 	}
 	return nil
 }
+
+// 获取视图中未删除的所有关联
+func (s *Viewedge) GetViewedgesByViewId(uid int, viewId string) (*[]Viewedge, error) {
+	var viewedges []Viewedge
+	err := global.DBEngine.Where("uid", uid).Where("view_id", viewId).Where("is_deleted", 0).Order("id").Find(&viewedges).Error
+	if err != nil {
+		return nil, err
+	}
+	return &viewedges, nil
+}
@@ -33,9 +33,9 @@ func (s *Viewnode) GetViewnodeCheckMap(uid int, ids []string) (*map[string]int64
 }
 
 // 获取节点分组列表
-func (s *Viewnode) GetViewnodes(uid int, updateTime int64, limit int) (*[]Viewnode, error) {
+func (s *Viewnode) GetViewnodes(scope *SyncScope, updateTime int64, limit int) (*[]Viewnode, error) {
 	var viewnodes []Viewnode
-	err := global.DBEngine.Table("viewnode").Select("*").Where("uid", uid).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&viewnodes).Error
+	err := global.DBEngine.Table("viewnode").Select("*").Scopes(scope.ByView()).Where("update_time > ?", updateTime).Limit(limit).Order("update_time").Find(&viewnodes).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +43,7 @@ func (s *Viewnode) GetViewnodes(uid int, updateTime int64, limit int) (*[]Viewno
 	if len(viewnodes) == limit {
 		// 获取剩余记录
 		var reminds []Viewnode
-		err = global.DBEngine.Table("viewnode").Select("*").Where("uid", uid).Where("update_time", viewnodes[limit-1].UpdateTime).Where("unid > ?", viewnodes[limit-1].Unid).Find(&reminds).Error
+		err = global.DBEngine.Table("viewnode").Select("*").Scopes(scope.ByView()).Where("update_time", viewnodes[limit-1].UpdateTime).Where("unid > ?", viewnodes[limit-1].Unid).Find(&reminds).Error
 		if err != nil {
 			return nil, err
 		}
@@ -67,3 +67,20 @@ func (s *Viewnode) UpdateViewnodes(viewnodes *[]*Viewnode) error {
 	}
 	return nil
 }
+
+// 获取视图中未删除的所有节点
+func (s *Viewnode) GetViewnodesByViewId(uid int, viewId string) (*[]Viewnode, error) {
+	var viewnodes []Viewnode
+	err := global.DBEngine.Where("uid", uid).Where("view_id", viewId).Where("is_deleted", 0).Order("id").Find(&viewnodes).Error
+	if err != nil {
+		return nil, err
+	}
+	return &viewnodes, nil
+}
+
+// 获取白板中所有卡片节点的卡片 id
+func (s *Viewnode) GetCardIds(uid int, viewId string) ([]string, error) {
+	ids := []string{}
+	err := global.DBEngine.Table("viewnode").Where("uid", uid).Where("view_id", viewId).Where("node_type", 1).Where("is_deleted", 0).Pluck("node_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,239 @@
+package render
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 卡片属性类型，name/tags/content 为固定属性，password 类型不对外展示
+const (
+	PROP_NAME     = "name"
+	PROP_TAGS     = "tags"
+	PROP_CONTENT  = "content"
+	PROP_PASSWORD = "password"
+	PROP_SELECT   = "select"
+	PROP_MSELECT  = "mselect"
+	PROP_LINK     = "link"
+)
+
+// 卡片属性选项
+type PropOption struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// 卡片属性及属性值
+type CardProp struct {
+	Id      string        `json:"id"`
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Val     interface{}   `json:"val"`
+	Options []*PropOption `json:"options"`
+}
+
+// 分享内容中的卡片
+type Card struct {
+	Id         string      `json:"id"`
+	Name       string      `json:"name"`
+	Tags       []string    `json:"tags"`
+	Props      []*CardProp `json:"props"`
+	Content    *Node       `json:"content"`
+	UpdateTime int64       `json:"update_time"`
+}
+
+// 分享内容，列表视图只有 cards，白板视图另有 nodes/edges
+type Content struct {
+	Cards []*Card         `json:"cards"`
+	Nodes json.RawMessage `json:"nodes"`
+	Edges json.RawMessage `json:"edges"`
+}
+
+// 表格列：按卡片中出现的顺序合并各类型的属性
+type column struct {
+	name  string
+	props map[string]bool
+}
+
+var linkRegexp = regexp.MustCompile(`^\[(.*?)\]\((.*?)\)$`)
+
+// 可在表格中展示的属性
+func tableProp(p *CardProp) bool {
+	switch p.Id {
+	case PROP_NAME, PROP_TAGS, PROP_CONTENT:
+		return false
+	}
+	return p.Type != PROP_PASSWORD && p.Type != PROP_CONTENT
+}
+
+func tableColumns(cards []*Card) []*column {
+	var cols []*column
+	index := map[string]*column{}
+	for _, c := range cards {
+		for _, p := range c.Props {
+			if !tableProp(p) {
+				continue
+			}
+			col, ok := index[p.Name]
+			if !ok {
+				col = &column{name: p.Name, props: map[string]bool{}}
+				index[p.Name] = col
+				cols = append(cols, col)
+			}
+			col.props[p.Id] = true
+		}
+	}
+	return cols
+}
+
+// 渲染卡片表格，卡片名称链接到下方的卡片详情
+func (r *docRenderer) renderCardTable(cards []*Card) {
+	cols := tableColumns(cards)
+	r.b.WriteString(`<table class="cards"><thead><tr><th>名称</th><th>标签</th>`)
+	for _, col := range cols {
+		r.b.WriteString("<th>" + html.EscapeString(col.name) + "</th>")
+	}
+	r.b.WriteString("</tr></thead><tbody>")
+	for _, c := range cards {
+		r.b.WriteString(`<tr><td><a href="#card-` + html.EscapeString(c.Id) + `">` + html.EscapeString(c.Name) + "</a></td>")
+		r.b.WriteString("<td>" + html.EscapeString(strings.Join(c.Tags, ", ")) + "</td>")
+		for _, col := range cols {
+			r.b.WriteString("<td>")
+			for _, p := range c.Props {
+				if col.props[p.Id] && tableProp(p) {
+					r.renderPropVal(p)
+					break
+				}
+			}
+			r.b.WriteString("</td>")
+		}
+		r.b.WriteString("</tr>")
+	}
+	r.b.WriteString("</tbody></table>")
+}
+
+// 渲染卡片详情
+func (r *docRenderer) renderCards(cards []*Card) {
+	for _, c := range cards {
+		r.b.WriteString(`<article class="card" id="card-` + html.EscapeString(c.Id) + `">`)
+		r.b.WriteString("<h2>" + html.EscapeString(c.Name) + "</h2>")
+		r.text.WriteString(c.Name + " ")
+		if len(c.Tags) > 0 {
+			r.b.WriteString(`<ul class="tags">`)
+			for _, t := range c.Tags {
+				r.b.WriteString("<li>" + html.EscapeString(t) + "</li>")
+			}
+			r.b.WriteString("</ul>")
+		}
+		r.renderPropList(c.Props)
+		if c.Content != nil {
+			r.b.WriteString(`<div class="content">`)
+			r.render(c.Content)
+			r.b.WriteString("</div>")
+		}
+		r.b.WriteString("</article>")
+	}
+}
+
+func (r *docRenderer) renderPropList(props []*CardProp) {
+	var list []*CardProp
+	for _, p := range props {
+		if tableProp(p) && PropText(p) != "" {
+			list = append(list, p)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+	r.b.WriteString(`<dl class="props">`)
+	for _, p := range list {
+		r.b.WriteString("<dt>" + html.EscapeString(p.Name) + "</dt><dd>")
+		r.renderPropVal(p)
+		r.b.WriteString("</dd>")
+	}
+	r.b.WriteString("</dl>")
+}
+
+func (r *docRenderer) renderPropVal(p *CardProp) {
+	if p.Type == PROP_LINK {
+		text, link := parseLink(p.Val)
+		if href := safeURL(link); href != "" {
+			if text == "" {
+				text = link
+			}
+			r.b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">` + html.EscapeString(text) + "</a>")
+			return
+		}
+	}
+	r.b.WriteString(html.EscapeString(PropText(p)))
+}
+
+// 属性值转为展示文本，选项类型转换为选项名称
+func PropText(p *CardProp) string {
+	switch p.Type {
+	case PROP_PASSWORD:
+		return ""
+	case PROP_SELECT, PROP_MSELECT:
+		var labels []string
+		for _, id := range valStrings(p.Val) {
+			for _, opt := range p.Options {
+				if opt.Id == id {
+					labels = append(labels, opt.Label)
+					break
+				}
+			}
+		}
+		return strings.Join(labels, ", ")
+	case PROP_LINK:
+		text, link := parseLink(p.Val)
+		if text != "" {
+			return text
+		}
+		return link
+	}
+	return strings.Join(valStrings(p.Val), ", ")
+}
+
+func valStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if val == "" {
+			return nil
+		}
+		return []string{val}
+	case float64:
+		return []string{strconv.FormatFloat(val, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(val)}
+	case []interface{}:
+		var res []string
+		for _, item := range val {
+			res = append(res, valStrings(item)...)
+		}
+		return res
+	case map[string]interface{}:
+		// 链接类型的对象值
+		if link := attrString(val, "link"); link != "" {
+			return []string{link}
+		}
+	}
+	return nil
+}
+
+// 解析链接属性值，支持 [文本](地址) 格式和 {text, link} 对象
+func parseLink(v interface{}) (string, string) {
+	switch val := v.(type) {
+	case string:
+		if m := linkRegexp.FindStringSubmatch(val); m != nil {
+			return m[1], m[2]
+		}
+		return "", val
+	case map[string]interface{}:
+		return attrString(val, "text"), attrString(val, "link")
+	}
+	return "", ""
+}
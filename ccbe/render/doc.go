@@ -0,0 +1,281 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 编辑器节点类型，与前端 Tiptap 扩展名称一致
+const (
+	NODE_DOC             = "doc"
+	NODE_PARAGRAPH       = "paragraph"
+	NODE_TEXT            = "text"
+	NODE_HEADING         = "heading"
+	NODE_BULLET_LIST     = "bulletList"
+	NODE_NEW_BULLET_LIST = "nbl"
+	NODE_ORDERED_LIST    = "orderedList"
+	NODE_LIST_ITEM       = "listItem"
+	NODE_NEW_LIST_ITEM   = "nli"
+	NODE_TASK_LIST       = "taskList"
+	NODE_TASK_ITEM       = "taskItem"
+	NODE_BLOCKQUOTE      = "blockquote"
+	NODE_CODE_BLOCK      = "codeBlock"
+	NODE_HORIZONTAL_RULE = "horizontalRule"
+	NODE_HARD_BREAK      = "hardBreak"
+	NODE_IMAGE           = "image"
+	NODE_IMAGE_RESIZE    = "imageResize"
+	NODE_MENTION         = "mention"
+)
+
+// 提及节点类型：1-卡片，2-视图
+const MENTION_CARD = 1
+const MENTION_VIEW = 2
+
+// 编辑器文档节点
+type Node struct {
+	Type    string                 `json:"type"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Content []*Node                `json:"content"`
+	Marks   []*Mark                `json:"marks"`
+	Text    string                 `json:"text"`
+}
+
+// 行内样式
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs"`
+}
+
+// 文档渲染器，同时收集纯文本和首张图片用于页面摘要
+type docRenderer struct {
+	opts  *Options
+	cards map[string]bool
+	b     strings.Builder
+	text  strings.Builder
+	image string
+}
+
+func newDocRenderer(opts *Options, cards map[string]bool) *docRenderer {
+	return &docRenderer{opts: opts, cards: cards}
+}
+
+// 渲染文档为 HTML，所有文本和属性均经过转义，未知节点只渲染其子节点
+func (r *docRenderer) render(n *Node) {
+	if n == nil {
+		return
+	}
+	switch n.Type {
+	case NODE_TEXT:
+		r.renderText(n)
+	case NODE_DOC:
+		r.children(n)
+	case NODE_PARAGRAPH:
+		r.wrap("p", "", n)
+		r.text.WriteString(" ")
+	case NODE_HEADING:
+		level := attrInt(n.Attrs, "level", 1)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		r.wrap("h"+strconv.Itoa(level), "", n)
+		r.text.WriteString(" ")
+	case NODE_BULLET_LIST, NODE_NEW_BULLET_LIST:
+		r.wrap("ul", "", n)
+	case NODE_ORDERED_LIST:
+		start := attrInt(n.Attrs, "start", 1)
+		if start > 1 {
+			r.wrap("ol", ` start="`+strconv.Itoa(start)+`"`, n)
+		} else {
+			r.wrap("ol", "", n)
+		}
+	case NODE_LIST_ITEM, NODE_NEW_LIST_ITEM:
+		r.wrap("li", "", n)
+	case NODE_TASK_LIST:
+		r.wrap("ul", ` class="task-list"`, n)
+	case NODE_TASK_ITEM:
+		checked := attrBool(n.Attrs, "checked")
+		r.b.WriteString(`<li class="task-item">`)
+		if checked {
+			r.b.WriteString(`<input type="checkbox" disabled checked>`)
+		} else {
+			r.b.WriteString(`<input type="checkbox" disabled>`)
+		}
+		r.children(n)
+		r.b.WriteString("</li>")
+	case NODE_BLOCKQUOTE:
+		r.wrap("blockquote", "", n)
+	case NODE_CODE_BLOCK:
+		lang := attrString(n.Attrs, "language")
+		r.b.WriteString("<pre><code")
+		if lang != "" {
+			r.b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+		}
+		r.b.WriteString(">")
+		r.children(n)
+		r.b.WriteString("</code></pre>")
+		r.text.WriteString(" ")
+	case NODE_HORIZONTAL_RULE:
+		r.b.WriteString("<hr>")
+	case NODE_HARD_BREAK:
+		r.b.WriteString("<br>")
+		r.text.WriteString(" ")
+	case NODE_IMAGE, NODE_IMAGE_RESIZE:
+		r.renderImage(n)
+	case NODE_MENTION:
+		r.renderMention(n)
+	default:
+		r.children(n)
+	}
+}
+
+func (r *docRenderer) children(n *Node) {
+	for _, c := range n.Content {
+		r.render(c)
+	}
+}
+
+func (r *docRenderer) wrap(tag, attrs string, n *Node) {
+	r.b.WriteString("<" + tag + attrs + ">")
+	r.children(n)
+	r.b.WriteString("</" + tag + ">")
+}
+
+func (r *docRenderer) renderText(n *Node) {
+	r.text.WriteString(n.Text)
+	var opens, closes []string
+	for _, m := range n.Marks {
+		var start, end string
+		switch m.Type {
+		case "bold":
+			start, end = "<strong>", "</strong>"
+		case "italic":
+			start, end = "<em>", "</em>"
+		case "underline":
+			start, end = "<u>", "</u>"
+		case "strike":
+			start, end = "<s>", "</s>"
+		case "code":
+			start, end = "<code>", "</code>"
+		case "highlight":
+			start, end = "<mark>", "</mark>"
+		case "subscript":
+			start, end = "<sub>", "</sub>"
+		case "superscript":
+			start, end = "<sup>", "</sup>"
+		case "link":
+			href := safeURL(attrString(m.Attrs, "href"))
+			if href == "" {
+				continue
+			}
+			start = `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">`
+			end = "</a>"
+		default:
+			continue
+		}
+		opens = append(opens, start)
+		closes = append(closes, end)
+	}
+	for _, s := range opens {
+		r.b.WriteString(s)
+	}
+	r.b.WriteString(html.EscapeString(n.Text))
+	for i := len(closes) - 1; i >= 0; i-- {
+		r.b.WriteString(closes[i])
+	}
+}
+
+func (r *docRenderer) renderImage(n *Node) {
+	src := r.opts.assetURL(attrString(n.Attrs, "src"))
+	if src == "" {
+		return
+	}
+	// base64 图片不作为分享图片
+	if r.image == "" && !strings.HasPrefix(src, "data:") {
+		r.image = src
+	}
+	r.b.WriteString(`<img src="` + html.EscapeString(src) + `"`)
+	if alt := attrString(n.Attrs, "alt"); alt != "" {
+		r.b.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+	}
+	if title := attrString(n.Attrs, "title"); title != "" {
+		r.b.WriteString(` title="` + html.EscapeString(title) + `"`)
+	}
+	r.b.WriteString(` loading="lazy">`)
+}
+
+// 提及的卡片在当前分享中时链接到对应卡片，否则只显示名称
+func (r *docRenderer) renderMention(n *Node) {
+	id := attrString(n.Attrs, "id")
+	label := attrString(n.Attrs, "label")
+	if label == "" {
+		label = id
+	}
+	r.text.WriteString(label)
+	if attrInt(n.Attrs, "type", 0) == MENTION_CARD && r.cards[id] {
+		r.b.WriteString(`<a class="mention" href="#card-` + html.EscapeString(id) + `">` + html.EscapeString(label) + `</a>`)
+		return
+	}
+	r.b.WriteString(`<span class="mention">` + html.EscapeString(label) + `</span>`)
+}
+
+// 纯文本摘要，合并连续空白
+func (r *docRenderer) plainText() string {
+	return strings.Join(strings.Fields(r.text.String()), " ")
+}
+
+// 只允许 http(s)、mailto、tel 和站内相对地址
+func safeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "tel":
+		return u.String()
+	case "":
+		if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "#") {
+			return u.String()
+		}
+	}
+	return ""
+}
+
+func attrString(attrs map[string]interface{}, key string) string {
+	v, ok := attrs[key]
+	if !ok || v == nil {
+		return ""
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprint(v)
+}
+
+func attrInt(attrs map[string]interface{}, key string, def int) int {
+	switch val := attrs[key].(type) {
+	case float64:
+		return int(val)
+	case string:
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+func attrBool(attrs map[string]interface{}, key string) bool {
+	v, _ := attrs[key].(bool)
+	return v
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"html/template"
+	"io"
+)
+
+// 嵌入页面主题：light-浅色，dark-深色，auto-跟随系统
+const THEME_LIGHT = "light"
+const THEME_DARK = "dark"
+const THEME_AUTO = "auto"
+
+type embedData struct {
+	Title string
+	URL   string
+	Theme string
+	Body  template.HTML
+}
+
+// 主题名称无效时使用浅色主题
+func EmbedTheme(theme string) string {
+	switch theme {
+	case THEME_DARK, THEME_AUTO:
+		return theme
+	}
+	return THEME_LIGHT
+}
+
+// 将分享内容渲染为可嵌入 iframe 的精简页面，不包含 Open Graph 信息
+func RenderEmbed(w io.Writer, s *Share, opts *Options, theme string) error {
+	r, err := renderShare(s, opts)
+	if err != nil {
+		return err
+	}
+	return embedTemplate.Execute(w, &embedData{
+		Title: s.Name,
+		URL:   opts.ShareURL + s.Uuid,
+		Theme: EmbedTheme(theme),
+		Body:  template.HTML(r.b.String()),
+	})
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="zh-CN" class="theme-{{.Theme}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex,nofollow">
+<title>{{.Title}} - CardCool</title>
+<style>
+:root{--fg:#262626;--muted:#8c8c8c;--bg:#fff;--line:#f0f0f0;--code:#f5f5f5;--link:#1677ff}
+.theme-dark{--fg:#e6e6e6;--muted:#8c8c8c;--bg:#141414;--line:#303030;--code:#1f1f1f;--link:#4096ff}
+@media (prefers-color-scheme:dark){.theme-auto{--fg:#e6e6e6;--muted:#8c8c8c;--bg:#141414;--line:#303030;--code:#1f1f1f;--link:#4096ff}}
+html,body{margin:0;background:var(--bg);color:var(--fg)}
+body{padding:12px 16px;font:14px/1.6 -apple-system,BlinkMacSystemFont,"PingFang SC","Microsoft YaHei",sans-serif}
+h1{font-size:18px;margin:0 0 8px}
+a{color:var(--link)}
+img{max-width:100%;height:auto}
+table.cards{width:100%;border-collapse:collapse;margin:8px 0 16px}
+table.cards th,table.cards td{border:1px solid var(--line);padding:4px 8px;text-align:left;vertical-align:top}
+article.card{border-top:1px solid var(--line);padding:12px 0}
+ul.tags{list-style:none;padding:0;margin:0 0 8px}
+ul.tags li{display:inline-block;margin-right:8px;padding:0 8px;border-radius:4px;background:var(--code);font-size:12px}
+dl.props dt{float:left;clear:left;width:96px;color:var(--muted)}
+dl.props dd{margin-left:104px}
+ul.task-list{list-style:none;padding-left:4px}
+pre{background:var(--code);padding:12px;overflow:auto}
+blockquote{margin:0;padding-left:12px;border-left:3px solid var(--line);color:var(--muted)}
+.mention{color:var(--link)}
+footer{border-top:1px solid var(--line);padding-top:8px;font-size:12px;color:var(--muted)}
+</style>
+</head>
+<body>
+{{.Body}}
+<footer>
+{{- if .URL}}<a href="{{.URL}}" target="_blank" rel="noopener">在 CardCool 中查看</a>{{else}}CardCool{{end -}}
+</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// 视图类型：0-列表，1-白板
+const VIEW_LIST = 0
+const VIEW_GRAPH = 1
+
+// 页面描述最大字符数
+const DESCRIPTION_MAX_LEN = 160
+
+// 渲染配置
+type Options struct {
+	// 分享页面地址前缀，与分享 uuid 拼接生成 og:url
+	ShareURL string
+	// 静态资源域名，用于补全 /img 开头的图片地址
+	StaticURL string
+	// 默认分享图片
+	DefaultImage string
+}
+
+// 待渲染的分享，IsCard 为单张卡片的分享
+type Share struct {
+	Uuid    string
+	Name    string
+	Type    int8
+	IsCard  bool
+	Content string
+}
+
+type pageData struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+	Body        template.HTML
+	Robots      string
+}
+
+// 补全图片地址并校验协议，允许 base64 图片
+func (o *Options) assetURL(src string) string {
+	if strings.HasPrefix(src, "/img") && o.StaticURL != "" {
+		src = strings.TrimSuffix(o.StaticURL, "/") + src
+	}
+	if strings.HasPrefix(src, "data:image/") && !strings.HasPrefix(src, "data:image/svg") {
+		return src
+	}
+	return safeURL(src)
+}
+
+// 渲染分享的正文内容
+func renderShare(s *Share, opts *Options) (*docRenderer, error) {
+	content := &Content{}
+	if err := json.Unmarshal([]byte(s.Content), content); err != nil {
+		return nil, err
+	}
+	cards := make(map[string]bool, len(content.Cards))
+	for _, c := range content.Cards {
+		cards[c.Id] = true
+	}
+	r := newDocRenderer(opts, cards)
+	r.b.WriteString("<h1>" + template.HTMLEscapeString(s.Name) + "</h1>")
+	if s.Type == VIEW_LIST && !s.IsCard && len(content.Cards) > 0 {
+		r.renderCardTable(content.Cards)
+	}
+	r.renderCards(content.Cards)
+	return r, nil
+}
+
+// 将分享内容渲染为 HTML 页面
+func Render(w io.Writer, s *Share, opts *Options) error {
+	r, err := renderShare(s, opts)
+	if err != nil {
+		return err
+	}
+	image := r.image
+	if image == "" {
+		image = opts.DefaultImage
+	}
+	return pageTemplate.Execute(w, &pageData{
+		Title:       s.Name,
+		Description: truncate(r.plainText(), DESCRIPTION_MAX_LEN),
+		URL:         opts.ShareURL + s.Uuid,
+		Image:       image,
+		Body:        template.HTML(r.b.String()),
+		Robots:      "index,follow",
+	})
+}
+
+// 渲染提示页面，用于分享不存在、已过期或需要密码等情况，不允许搜索引擎收录
+func RenderMessage(w io.Writer, title, message string) error {
+	return pageTemplate.Execute(w, &pageData{
+		Title:       title,
+		Description: message,
+		Body:        template.HTML("<h1>" + template.HTMLEscapeString(title) + "</h1><p>" + template.HTMLEscapeString(message) + "</p>"),
+		Robots:      "noindex,nofollow",
+	})
+}
+
+// 按字符截断文本
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+var pageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="{{.Robots}}">
+<title>{{.Title}} - CardCool</title>
+<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="article">
+<meta property="og:site_name" content="CardCool">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+{{- if .URL}}
+<meta property="og:url" content="{{.URL}}">
+<link rel="canonical" href="{{.URL}}">
+{{- end}}
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+<style>
+body{max-width:860px;margin:0 auto;padding:24px 16px;font:16px/1.7 -apple-system,BlinkMacSystemFont,"PingFang SC","Microsoft YaHei",sans-serif;color:#262626}
+img{max-width:100%;height:auto}
+table.cards{width:100%;border-collapse:collapse;margin:16px 0 32px}
+table.cards th,table.cards td{border:1px solid #f0f0f0;padding:6px 10px;text-align:left;vertical-align:top}
+article.card{border-top:1px solid #f0f0f0;padding:16px 0}
+ul.tags{list-style:none;padding:0;margin:0 0 8px}
+ul.tags li{display:inline-block;margin-right:8px;padding:0 8px;border-radius:4px;background:#f5f5f5;font-size:13px}
+dl.props dt{float:left;clear:left;width:96px;color:#8c8c8c}
+dl.props dd{margin-left:104px}
+ul.task-list{list-style:none;padding-left:4px}
+pre{background:#f5f5f5;padding:12px;overflow:auto}
+blockquote{margin:0;padding-left:12px;border-left:3px solid #d9d9d9;color:#595959}
+.mention{color:#1677ff}
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
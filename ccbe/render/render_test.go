@@ -0,0 +1,129 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新 golden 文件")
+
+var testOptions = &Options{
+	ShareURL:  "https://cardcool.top/s/",
+	StaticURL: "https://static.cardcool.top",
+}
+
+type fixture struct {
+	Uuid    string          `json:"uuid"`
+	Name    string          `json:"name"`
+	Type    int8            `json:"type"`
+	Content json.RawMessage `json:"content"`
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败: %s", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s 渲染结果与 golden 文件不一致，使用 -update 更新\n got:\n%s", name, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, name := range []string{"list", "graph"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := &fixture{}
+			if err := json.Unmarshal(data, f); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			err = Render(&buf, &Share{Uuid: f.Uuid, Name: f.Name, Type: f.Type, Content: string(f.Content)}, testOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, name, buf.Bytes())
+		})
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderMessage(&buf, "分享需要密码", "请在 <CardCool> 中输入访问密码"); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "message", buf.Bytes())
+}
+
+func TestRenderInvalidContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, &Share{Name: "x", Content: "not json"}, testOptions); err == nil {
+		t.Error("无效内容应返回错误")
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/a?b=1": "https://example.com/a?b=1",
+		"mailto:a@example.com":      "mailto:a@example.com",
+		"/s/abc":                    "/s/abc",
+		"#card-1":                   "#card-1",
+		"javascript:alert(1)":       "",
+		" JavaScript:alert(1)":      "",
+		"//evil.com/x":              "",
+		"data:text/html,<b>":        "",
+		"vbscript:msgbox":           "",
+	}
+	for in, want := range cases {
+		if got := safeURL(in); got != want {
+			t.Errorf("safeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRenderEmbed(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = RenderEmbed(&buf, &Share{Uuid: f.Uuid, Name: f.Name, Type: f.Type, Content: string(f.Content)}, testOptions, THEME_DARK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "embed", buf.Bytes())
+}
+
+func TestEmbedTheme(t *testing.T) {
+	cases := map[string]string{
+		"":               THEME_LIGHT,
+		"light":          THEME_LIGHT,
+		"dark":           THEME_DARK,
+		"auto":           THEME_AUTO,
+		`dark"><script>`: THEME_LIGHT,
+	}
+	for in, want := range cases {
+		if got := EmbedTheme(in); got != want {
+			t.Errorf("EmbedTheme(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
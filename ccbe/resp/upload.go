@@ -1,6 +1,58 @@
 package resp
 
 type UploadToken struct {
-	Token      string `json:"token" redis:"token"`
-	ExpireTime int64  `json:"expire_time" redis:"expire_time"`
+	Token      string   `json:"token" redis:"token"`
+	ExpireTime int64    `json:"expire_time" redis:"expire_time"`
+	Provider   string   `json:"provider" redis:"provider"`
+	UploadURL  string   `json:"upload_url" redis:"upload_url"`
+	Warning    *Warning `json:"warning,omitempty" redis:"-"`
+}
+
+// 成功响应中的提醒，如存储空间即将用完
+type Warning struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// 已登记的上传文件
+type UploadFile struct {
+	Id      int      `json:"id"`
+	Key     string   `json:"key"`
+	Hash    string   `json:"hash"`
+	Fsize   int64    `json:"fsize"`
+	Uid     int      `json:"uid"`
+	Name    string   `json:"name"`
+	Mime    string   `json:"mime"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Url     string   `json:"url"`
+	Warning *Warning `json:"warning,omitempty"`
+}
+
+// 文件库中的文件，RefCount 为重复上传的次数，OrphanTime 为文件不再被内容引用的时间
+type File struct {
+	Id         int    `json:"id"`
+	Key        string `json:"key"`
+	Hash       string `json:"hash"`
+	Name       string `json:"name"`
+	Mime       string `json:"mime"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Size       int64  `json:"size"`
+	RefCount   int    `json:"ref_count"`
+	OrphanTime int    `json:"orphan_time"`
+	Url        string `json:"url"`
+	CreateTime int    `json:"create_time"`
+}
+
+// 分片上传任务，Uploaded 为已上传的分片序号
+type UploadSession struct {
+	UploadId   string   `json:"upload_id"`
+	Filename   string   `json:"filename"`
+	Size       int64    `json:"size"`
+	ChunkSize  int64    `json:"chunk_size"`
+	Chunks     int      `json:"chunks"`
+	ExpireTime int64    `json:"expire_time"`
+	Uploaded   []int    `json:"uploaded"`
+	Warning    *Warning `json:"warning,omitempty"`
 }
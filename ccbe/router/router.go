@@ -3,9 +3,14 @@ package router
 import (
 	"io"
 	"log"
+	"path/filepath"
 	"cc/be/api"
+	"cc/be/app"
 	"cc/be/global"
 	"cc/be/middleware"
+	"cc/be/model"
+	"cc/be/server"
+	"cc/be/storage"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -13,9 +18,6 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// 将新事件消息广播到所有已注册的客户端连接通道
-type ClientChan chan int64
-
 // 新建路由
 func NewRouter() *gin.Engine {
 	r := gin.New()
@@ -24,13 +26,17 @@ func NewRouter() *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-type", "Referer", "User-Agent"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-type", "Referer", "User-Agent", "X-Share-Token"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	a := r.Group("/api")
 	tokenApi := api.NewTokenApi()
+	// token 校验公钥
+	r.GET("/.well-known/jwks.json", tokenApi.Jwks)
+
+	a := r.Group("/api")
+	uploadApi := api.NewUploadApi()
 	// 视图分享逻辑
 	shareApi := api.NewShareApi()
 	{
@@ -38,30 +44,61 @@ func NewRouter() *gin.Engine {
 		a.POST("/register", tokenApi.Register)
 		// 登录账号
 		a.POST("/login", tokenApi.Login)
-		// 微信扫码登录回调
+		// 两步验证登录
+		a.POST("/loginTotp", tokenApi.LoginTotp)
+		// 获取第三方登录授权地址
+		a.GET("/authorize/:provider", tokenApi.Authorize)
+		// 第三方授权登录回调
 		a.POST("/callback", tokenApi.Callback)
-		// 根据微信扫码授权回调和邀请码注册账号
-		// a.POST("/regcode", tokenApi.RegisterByCode)
-		// 根据微信扫码授权回调绑定原手机账号
+		// 根据授权回调的身份凭证和邀请码注册账号
+		a.POST("/regcode", tokenApi.RegisterByCode)
+		// 根据授权回调的身份凭证绑定原手机账号
 		a.POST("/bind", tokenApi.BindUser)
+		// 获取注册或重置密码的验证码
+		a.POST("/sendCode", tokenApi.SendCode)
 		// 忘记密码
-		// a.POST("/register", userApi.Register)
+		a.POST("/resetPassword", tokenApi.ResetPassword)
 		// 七牛云文件上传回调
 		a.POST("/qiniucallback", tokenApi.QiniuCallback)
+		// 上传文件到本地存储，使用上传凭证校验
+		a.POST("/upload", uploadApi.Upload)
+		// 文件下载，可按规格获取图片缩略图
+		a.GET("/download/*key", uploadApi.Download)
 		// 超时模拟
 		a.POST("/timeout", tokenApi.Timeout)
 		a.GET("/shareData/:shareId", shareApi.GetShareData)
+		// 输入分享访问密码
+		a.POST("/shareAccess", shareApi.CreateShareAccess)
+		// 分享链接的 oEmbed 接口
+		a.GET("/oembed", shareApi.OEmbed)
 		// 客户端下载
 		a.GET("/client", tokenApi.Client)
 	}
-	a.Use(middleware.Auth())
+	// 服务端渲染的分享页面
+	r.GET("/s/:shareId", shareApi.RenderSharePage)
+	// 可嵌入 iframe 的分享页面
+	r.GET("/e/:shareId", shareApi.RenderShareEmbed)
+	// 本地存储的文件下载
+	if p, ok := storage.Default().(*storage.LocalProvider); ok {
+		r.Static("/"+storage.KEY_PREFIX, filepath.Join(p.Root(), storage.KEY_PREFIX))
+	}
+	// 个人访问令牌仅可访问 GraphQL 接口
+	a.Use(middleware.Auth(), middleware.Session())
 	// 用户信息接口
 	userApi := api.NewUserApi()
 	{
 		// 获取服务端信息
 		a.GET("/info", userApi.GetInfo)
-		// 绑定微信
-		a.POST("/bindWechat", userApi.BindWechat)
+		// 获取绑定第三方账号的授权地址
+		a.GET("/authorizeBind/:provider", userApi.AuthorizeBind)
+		// 绑定第三方账号
+		a.POST("/bindIdentity", userApi.BindIdentity)
+		// 已绑定的第三方账号列表
+		a.GET("/identities", userApi.GetIdentities)
+		// 解除第三方账号绑定
+		a.POST("/unbindIdentity", userApi.UnbindIdentity)
+		// 获取修改手机号的验证码
+		a.POST("/sendMobileCode", userApi.SendMobileCode)
 		// 更新手机账号
 		a.POST("/updateMobileAccount", userApi.UpdateMobileAccount)
 		// 更新用户信息
@@ -70,41 +107,156 @@ func NewRouter() *gin.Engine {
 		a.POST("/updateConfig", userApi.UpdateConfig)
 		// 修改账号
 		a.POST("/change", userApi.Change)
+		// 查询注销申请
+		a.GET("/deletion", userApi.GetDeletion)
+		// 申请注销账号
+		a.POST("/requestDeletion", userApi.RequestDeletion)
+		// 撤销注销申请
+		a.POST("/cancelDeletion", userApi.CancelDeletion)
 		// 修改密码
 		// a.POST("/register", userApi.Register)
 	}
-	// 视图分享逻辑
+	// 视图和卡片分享逻辑
 	{
 		// 获取指定视图的分享信息
 		a.GET("/shareInfo/:viewId", shareApi.GetShareInfo)
+		// 获取指定视图分享的访问统计
+		a.GET("/shareStats/:viewId", shareApi.GetShareStats)
 		// 创建或刷新视图分享
 		a.POST("/share", shareApi.CreateShare)
 		// 更新视图分享状态
 		a.POST("/updateShareStatus", shareApi.UpdateShareStatus)
+		// 获取视图分享的历史版本
+		a.GET("/shareRevisions/:viewId", shareApi.GetShareRevisions)
+		// 固定公开的分享版本
+		a.POST("/pinShareRevision", shareApi.PinShareRevision)
+		// 回滚分享版本
+		a.POST("/rollbackShareRevision", shareApi.RollbackShareRevision)
+		// 获取指定卡片的分享信息
+		a.GET("/cardShareInfo/:cardId", shareApi.GetCardShareInfo)
+		// 创建或刷新卡片分享
+		a.POST("/cardShare", shareApi.CreateCardShare)
+		// 更新卡片分享状态
+		a.POST("/updateCardShareStatus", shareApi.UpdateCardShareStatus)
+	}
+	// 共享空间成员
+	spaceApi := api.NewSpaceApi()
+	{
+		// 获取空间成员列表
+		a.GET("/spaceMembers/:spaceId", spaceApi.GetSpaceMembers)
+		// 邀请成员加入空间
+		a.POST("/inviteSpaceMember", spaceApi.InviteSpaceMember)
+		// 修改成员角色
+		a.POST("/updateSpaceMember", spaceApi.UpdateSpaceMember)
+		// 移除成员或退出空间
+		a.POST("/removeSpaceMember", spaceApi.RemoveSpaceMember)
+		// 获取待接受的空间邀请
+		a.GET("/spaceInvites", spaceApi.GetSpaceInvites)
+		// 接受空间邀请
+		a.POST("/acceptSpaceInvite", spaceApi.AcceptSpaceInvite)
+		// 拒绝空间邀请
+		a.POST("/declineSpaceInvite", spaceApi.DeclineSpaceInvite)
+	}
+	// 个人访问令牌管理
+	accessTokenApi := api.NewAccessTokenApi()
+	{
+		// 获取令牌列表
+		a.GET("/accessTokens", accessTokenApi.GetAccessTokens)
+		// 创建令牌
+		a.POST("/accessToken", accessTokenApi.CreateAccessToken)
+		// 撤销令牌
+		a.POST("/revokeAccessToken", accessTokenApi.RevokeAccessToken)
+	}
+	// 两步验证
+	totpApi := api.NewTotpApi()
+	{
+		// 获取两步验证状态
+		a.GET("/totp", totpApi.GetTotpStatus)
+		// 绑定认证器
+		a.POST("/enrollTotp", totpApi.EnrollTotp)
+		// 激活两步验证
+		a.POST("/activateTotp", totpApi.ActivateTotp)
+		// 关闭两步验证
+		a.POST("/disableTotp", totpApi.DisableTotp)
+		// 重新生成恢复码
+		a.POST("/recoveryCodes", totpApi.RegenerateRecoveryCodes)
+	}
+	// 邀请记录
+	referralApi := api.NewReferralApi()
+	{
+		// 查询我邀请的用户
+		a.GET("/invitees", referralApi.GetInvitees)
 	}
 	// 文件上传凭证接口
-	uploadApi := api.NewUploadApi()
+	quotaApi := api.NewQuotaApi()
 	{
 		// 获取时间
 		a.POST("/uploadToken", uploadApi.GetUploadToken)
+		// 登记客户端直传完成的文件
+		a.POST("/uploadComplete", uploadApi.CompleteUpload)
+		// 直接上传文件到服务端
+		a.POST("/uploadFile", uploadApi.UploadFile)
+		// 分片上传: 创建任务、上传分片、查询进度、合并、取消
+		a.POST("/initChunkUpload", uploadApi.InitChunkUpload)
+		a.POST("/uploadChunk", uploadApi.UploadChunk)
+		a.GET("/chunkUpload/:uploadId", uploadApi.GetChunkUpload)
+		a.POST("/completeChunkUpload", uploadApi.CompleteChunkUpload)
+		a.POST("/abortChunkUpload", uploadApi.AbortChunkUpload)
+		// 删除已上传的文件
+		a.POST("/deleteFile", uploadApi.DeleteFile)
+		// 文件库: 查询、搜索和批量删除文件
+		a.GET("/files", uploadApi.GetFiles)
+		a.POST("/deleteFiles", uploadApi.DeleteFiles)
+		// 查询存储用量
+		a.GET("/storageUsage", quotaApi.GetStorageUsage)
 	}
 
-	// 超级管理员权限校验
+	// 管理后台接口，按权限名称校验并记录审计日志
 	ad := r.Group("/admin")
-	ad.Use(middleware.Auth(), middleware.Admin())
+	ad.Use(middleware.Auth(), middleware.Session())
 	inviteApi := api.NewInviteApi()
+	roleApi := api.NewRoleApi()
+	lockoutApi := api.NewLockoutApi()
 	{
 		// 添加账号
-		ad.POST("/addAccount", userApi.AddAccount)
+		ad.POST("/addAccount", middleware.Permission(model.PERM_ACCOUNT_ADD), userApi.AddAccount)
 		// 生成邀请码
-		ad.POST("/generateCodes", inviteApi.GenerateCodes)
+		ad.POST("/generateCodes", middleware.Permission(model.PERM_INVITE_GENERATE), inviteApi.GenerateCodes)
+		// 查询邀请码
+		ad.GET("/invites", middleware.Permission(model.PERM_INVITE_MANAGE), inviteApi.GetInvites)
+		// 作废邀请码
+		ad.POST("/revokeInvite", middleware.Permission(model.PERM_INVITE_MANAGE), inviteApi.RevokeInvite)
+		// 查询邀请码使用记录
+		ad.GET("/inviteRedemptions/:id", middleware.Permission(model.PERM_INVITE_MANAGE), inviteApi.GetRedemptions)
+		// 查询邀请关系树
+		ad.GET("/referralTree", middleware.Permission(model.PERM_REFERRAL_READ), referralApi.GetReferralTree)
+		// 查询邀请统计
+		ad.GET("/referralStats", middleware.Permission(model.PERM_REFERRAL_READ), referralApi.GetReferralStats)
 		// 更新客户端版本
-		ad.POST("/updateClient", userApi.UpdateClient)
+		ad.POST("/updateClient", middleware.Permission(model.PERM_CLIENT_UPDATE), userApi.UpdateClient)
+		// 授予用户角色
+		ad.POST("/grantRole", middleware.Permission(model.PERM_ROLE_MANAGE), roleApi.GrantRole)
+		// 撤销用户角色
+		ad.POST("/revokeRole", middleware.Permission(model.PERM_ROLE_MANAGE), roleApi.RevokeRole)
+		// 查询用户角色
+		ad.GET("/userRoles/:uid", middleware.Permission(model.PERM_ROLE_MANAGE), roleApi.GetUserRoles)
+		// 查询审计日志
+		ad.GET("/auditLogs", middleware.Permission(model.PERM_AUDIT_READ), roleApi.GetAuditlogs)
+		// 重置用户两步验证
+		ad.POST("/resetTotp", middleware.Permission(model.PERM_TOTP_RESET), totpApi.ResetTotp)
+		// 查询登录、注册锁定
+		ad.GET("/lockouts", middleware.Permission(model.PERM_LOCKOUT_MANAGE), lockoutApi.GetLockouts)
+		// 解除锁定
+		ad.POST("/unlock", middleware.Permission(model.PERM_LOCKOUT_MANAGE), lockoutApi.Unlock)
+		// 查询用户存储额度
+		ad.GET("/userQuota/:uid", middleware.Permission(model.PERM_QUOTA_MANAGE), quotaApi.GetUserQuota)
+		// 设置用户存储额度
+		ad.POST("/updateQuota", middleware.Permission(model.PERM_QUOTA_MANAGE), quotaApi.UpdateQuota)
 	}
 
 	// GraphQL
 	gql := r.Group("/graph")
-	// token 校验中间件
+	// token 校验中间件，个人访问令牌的权限范围在 resolver 中校验
 	gql.Use(middleware.Auth())
 	gqlApi := api.NewGraphQLApi()
 	{
@@ -129,7 +281,7 @@ func NewSSERouter() *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 	a := r.Group("/sse")
-	a.Use(middleware.Auth())
+	a.Use(middleware.Auth(), middleware.Session())
 	// 用户信息接口
 	// userApi := api.NewUserApi()
 	{
@@ -139,26 +291,31 @@ func NewSSERouter() *gin.Engine {
 			if !ok {
 				return
 			}
-			clientChan, ok := v.(ClientChan)
+			client, ok := v.(*server.Client)
 			if !ok {
 				return
 			}
 			// 1s 后下发 retry time 给前端，更新前端的重试时间
 			go func() {
 				time.Sleep(time.Second * 1)
-				clientChan <- 0
+				client.Send(0)
 			}()
 			c.Stream(func(w io.Writer) bool {
 				// Stream message to client from message channel
-				if msg, ok := <-clientChan; ok {
+				select {
+				case msg := <-client.Msg:
 					c.Render(-1, sse.Event{
 						Event: "message",
 						Data:  msg,
 						Retry: 60000,
 					})
 					return true
+				case <-client.Done:
+					// 连接被服务端断开，如账号已注销
+					return false
+				case <-c.Request.Context().Done():
+					return false
 				}
-				return false
 			})
 		})
 
@@ -168,18 +325,22 @@ func NewSSERouter() *gin.Engine {
 
 func serveHTTP() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 初始化一个客户端 channel
-		clientChan := make(ClientChan)
+		auth := app.GetAuth(c)
+		if auth == nil {
+			c.Abort()
+			return
+		}
+		// 初始化一个客户端连接
+		client := server.NewClient()
 		// 保存新客户端连接
-		global.SSEClientMap.AddClient(global.Uid, global.Rid, clientChan)
-		log.Println("Client added. ", global.SSEClientMap)
+		global.SSEClientMap.AddClient(auth.Uid, auth.Rid, client)
+		log.Println("Client added. ", auth.Uid, auth.Rid, global.SSEClientMap.Count())
 		defer func() {
-			// 链接断开时，通知连接关闭
-			global.SSEClientMap.DelClient(global.Uid, global.Rid)
-			close(clientChan)
-			log.Println("Client deleted. ", global.SSEClientMap)
+			// 链接断开时删除连接，消息通道不关闭
+			global.SSEClientMap.DelClient(auth.Uid, auth.Rid, client)
+			log.Println("Client deleted. ", auth.Uid, auth.Rid, global.SSEClientMap.Count())
 		}()
-		c.Set("clientChan", clientChan)
+		c.Set("clientChan", client)
 		c.Next()
 	}
 }
@@ -0,0 +1,59 @@
+package sender
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 控制台发送，仅打印日志
+type ConsoleSender struct{}
+
+func (s *ConsoleSender) SendSms(mobile, content string) error {
+	log.Printf("[sms] %s: %s", mobile, content)
+	return nil
+}
+
+func (s *ConsoleSender) SendEmail(to, subject, content string) error {
+	log.Printf("[email] %s: %s\n%s", to, subject, content)
+	return nil
+}
+
+// 文件发送，追加写入到本地文件
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	if path == "" {
+		path = "storage/logs/sender.log"
+	}
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) write(line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(time.Now().Format("2006-01-02 15:04:05") + " " + line + "\n")
+	return err
+}
+
+func (s *FileSender) SendSms(mobile, content string) error {
+	return s.write(fmt.Sprintf("[sms] %s: %s", mobile, content))
+}
+
+func (s *FileSender) SendEmail(to, subject, content string) error {
+	return s.write(fmt.Sprintf("[email] %s: %s %q", to, subject, content))
+}
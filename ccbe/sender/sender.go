@@ -0,0 +1,69 @@
+package sender
+
+import (
+	"errors"
+
+	"cc/be/setting"
+)
+
+// 发送方式
+const TYPE_CONSOLE = "console"
+const TYPE_FILE = "file"
+const TYPE_SMTP = "smtp"
+
+// 短信发送接口，接入短信服务商时实现该接口
+type SmsSender interface {
+	SendSms(mobile, content string) error
+}
+
+// 邮件发送接口
+type EmailSender interface {
+	SendEmail(to, subject, content string) error
+}
+
+// 默认输出到控制台，便于本地开发
+var (
+	smsSender   SmsSender   = &ConsoleSender{}
+	emailSender EmailSender = &ConsoleSender{}
+)
+
+func Sms() SmsSender {
+	return smsSender
+}
+
+func Email() EmailSender {
+	return emailSender
+}
+
+// 根据配置初始化发送方式
+func Init(s *setting.SenderSetting) error {
+	if s == nil {
+		return nil
+	}
+	var file *FileSender
+	getFile := func() *FileSender {
+		if file == nil {
+			file = NewFileSender(s.FilePath)
+		}
+		return file
+	}
+	switch s.Sms {
+	case "", TYPE_CONSOLE:
+		smsSender = &ConsoleSender{}
+	case TYPE_FILE:
+		smsSender = getFile()
+	default:
+		return errors.New("不支持的短信发送方式: " + s.Sms)
+	}
+	switch s.Email {
+	case "", TYPE_CONSOLE:
+		emailSender = &ConsoleSender{}
+	case TYPE_FILE:
+		emailSender = getFile()
+	case TYPE_SMTP:
+		emailSender = NewSmtpSender(s.Smtp)
+	default:
+		return errors.New("不支持的邮件发送方式: " + s.Email)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package sender
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"cc/be/setting"
+)
+
+// SMTP 邮件发送
+type SmtpSender struct {
+	setting setting.SmtpSetting
+}
+
+func NewSmtpSender(s setting.SmtpSetting) *SmtpSender {
+	return &SmtpSender{setting: s}
+}
+
+func (s *SmtpSender) SendEmail(to, subject, content string) error {
+	addr := net.JoinHostPort(s.setting.Host, strconv.Itoa(s.setting.Port))
+	var auth smtp.Auth
+	if s.setting.Username != "" {
+		auth = smtp.PlainAuth("", s.setting.Username, s.setting.Password, s.setting.Host)
+	}
+	msg := strings.Join([]string{
+		"From: " + s.setting.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		content,
+	}, "\r\n")
+	return smtp.SendMail(addr, auth, s.setting.From, []string{to}, []byte(msg))
+}
@@ -1,52 +1,118 @@
 package server
 
 import (
-	"log"
+	"sync"
 )
 
-// 所有客户端连接 map
-type RidClientMap map[string]chan int64
-type ClientMap map[int]RidClientMap
+// 客户端消息通道的缓冲大小，通道已满时丢弃新消息，客户端收到下一条消息时会拉取全部更新
+const CLIENT_MSG_BUFFER = 16
+
+// 客户端连接，Msg 为消息通道，Done 关闭时通知连接断开
+// 消息通道不会被关闭，避免向已关闭的通道发送消息
+type Client struct {
+	Msg  chan int64
+	Done chan struct{}
+}
+
+func NewClient() *Client {
+	return &Client{
+		Msg:  make(chan int64, CLIENT_MSG_BUFFER),
+		Done: make(chan struct{}),
+	}
+}
+
+// 发送消息，连接已断开或通道已满时不阻塞
+func (c *Client) Send(msg int64) bool {
+	select {
+	case <-c.Done:
+		return false
+	default:
+	}
+	select {
+	case c.Msg <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// 所有客户端连接，uid -> rid -> 连接
+type RidClientMap map[string]*Client
+type ClientMap struct {
+	mu      sync.RWMutex
+	clients map[int]RidClientMap
+}
 
 // 初始化客户端连接映射
 func NewSSEClientMap() *ClientMap {
-	m := make(ClientMap)
-	return &m
+	return &ClientMap{clients: make(map[int]RidClientMap)}
 }
 
-func (m *ClientMap) AddClient(uid int, rid string, client chan int64) {
+func (m *ClientMap) AddClient(uid int, rid string, client *Client) {
 	if uid == 0 || rid == "" {
 		return
 	}
-	if _, ok := (*m)[uid]; !ok {
-		(*m)[uid] = make(RidClientMap)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[uid]; !ok {
+		m.clients[uid] = make(RidClientMap)
 	}
-	(*m)[uid][rid] = client
+	m.clients[uid][rid] = client
 }
 
-func (m *ClientMap) DelClient(uid int, rid string) {
+// 删除客户端连接，同一 rid 已重新连接时不删除新的连接
+func (m *ClientMap) DelClient(uid int, rid string, client *Client) {
 	if uid == 0 || rid == "" {
 		return
 	}
-	delete((*m)[uid], rid)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm, ok := m.clients[uid]
+	if !ok || rm[rid] != client {
+		return
+	}
+	delete(rm, rid)
+	if len(rm) == 0 {
+		delete(m.clients, uid)
+	}
+}
+
+// 断开用户的全部客户端连接
+func (m *ClientMap) CloseClients(uid int) {
+	m.mu.Lock()
+	rm := m.clients[uid]
+	delete(m.clients, uid)
+	m.mu.Unlock()
+	for _, client := range rm {
+		close(client.Done)
+	}
 }
 
+// 通知用户的其他客户端连接
 func (m *ClientMap) PushMsg(uid int, rid string, msg int64) {
 	if uid == 0 || rid == "" {
 		return
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			log.Println("PushMsg ex. ", uid, rid, msg, r)
-		}
-	}()
-	if rm, ok := (*m)[uid]; ok {
-		// fmt.Println("PushMsg", uid, rid, msg, rm)
-		for ri, client := range rm {
-			// fmt.Printf("%d - %s: %d\n", uid, rid, client)
-			if ri != rid && client != nil {
-				client <- msg
-			}
+	m.mu.RLock()
+	clients := make([]*Client, 0, len(m.clients[uid]))
+	for ri, client := range m.clients[uid] {
+		if ri != rid {
+			clients = append(clients, client)
 		}
 	}
+	m.mu.RUnlock()
+	for _, client := range clients {
+		client.Send(msg)
+	}
+}
+
+// 当前连接数
+func (m *ClientMap) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, rm := range m.clients {
+		n += len(rm)
+	}
+	return n
 }
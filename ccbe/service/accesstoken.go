@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/utils"
+	"cc/be/validreq"
+
+	"gorm.io/gorm"
+)
+
+// 个人访问令牌前缀，用于与 JWT 区分
+const ACCESS_TOKEN_PREFIX = "cc_"
+
+// 最近使用时间的更新间隔(s)，避免每次请求都写库
+const ACCESS_TOKEN_TOUCH_INTERVAL = 60
+
+var ErrAccessTokenExpired = errors.New("访问令牌已过期")
+
+// 判断是否为个人访问令牌
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, ACCESS_TOKEN_PREFIX)
+}
+
+// 校验个人访问令牌，返回令牌记录
+func (srv *Service) ParseAccessToken(token string) (*model.Accesstoken, error) {
+	ma := &model.Accesstoken{}
+	err := ma.GetByHash(utils.Sha256Hex(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("访问令牌不存在")
+	} else if err != nil {
+		return nil, errors.New("查询访问令牌异常")
+	}
+	if ma.Status != 1 {
+		return nil, errors.New("访问令牌已撤销")
+	}
+	if ma.IsExpired() {
+		return nil, ErrAccessTokenExpired
+	}
+	t := int(time.Now().Unix())
+	if t-ma.LastUsedTime > ACCESS_TOKEN_TOUCH_INTERVAL {
+		ma.UpdateLastUsedTime(ma.Id, t)
+	}
+	return ma, nil
+}
+
+// 获取当前用户的访问令牌列表
+func (srv *Service) GetAccessTokens() (*[]model.Accesstoken, error) {
+	ma := &model.Accesstoken{}
+	list, err := ma.GetAccesstokens(global.Uid)
+	if err != nil {
+		return nil, errors.New("查询访问令牌异常")
+	}
+	return list, nil
+}
+
+// 创建访问令牌，明文令牌仅在创建时返回一次
+func (srv *Service) CreateAccessToken(param *validreq.CreateAccessTokenReq) (*model.Accesstoken, string, error) {
+	if len(param.Scopes) == 0 {
+		return nil, "", errors.New("请选择令牌权限范围")
+	}
+	for _, scope := range param.Scopes {
+		if !model.IsValidScope(scope) {
+			return nil, "", errors.New("令牌权限范围异常: " + scope)
+		}
+	}
+	if param.ExpireTime != 0 && int64(param.ExpireTime) <= time.Now().Unix() {
+		return nil, "", errors.New("过期时间需晚于当前时间")
+	}
+	token := ACCESS_TOKEN_PREFIX + utils.SecureRandStr(40)
+	prefix := token[:len(ACCESS_TOKEN_PREFIX)+6]
+	ma := &model.Accesstoken{}
+	err := ma.CreateAccesstoken(global.Uid, param.Name, prefix, utils.Sha256Hex(token), strings.Join(param.Scopes, ","), param.ExpireTime)
+	if err != nil {
+		return nil, "", errors.New("创建访问令牌失败")
+	}
+	return ma, token, nil
+}
+
+// 撤销访问令牌
+func (srv *Service) RevokeAccessToken(id int) error {
+	ma := &model.Accesstoken{}
+	cnt, err := ma.RevokeAccesstoken(global.Uid, id)
+	if err != nil {
+		return errors.New("撤销访问令牌失败")
+	}
+	if cnt == 0 {
+		return errors.New("访问令牌不存在")
+	}
+	return nil
+}
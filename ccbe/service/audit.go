@@ -0,0 +1,33 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"cc/be/model"
+)
+
+// 记录特权操作审计日志
+func (srv *Service) Audit(uid int, action, path, target, ip string, code int) {
+	a := &model.Auditlog{
+		Uid:    uid,
+		Action: action,
+		Path:   path,
+		Target: target,
+		Ip:     ip,
+		Code:   code,
+	}
+	if err := a.CreateAuditlog(); err != nil {
+		log.Printf("保存审计日志异常: %s", err)
+	}
+}
+
+// 分页查询审计日志
+func (srv *Service) GetAuditlogs(uid int, action string, page, pageSize int) (*[]model.Auditlog, int64, error) {
+	a := &model.Auditlog{}
+	list, total, err := a.GetAuditlogs(uid, action, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.New("查询审计日志异常")
+	}
+	return list, total, nil
+}
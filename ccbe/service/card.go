@@ -125,9 +125,9 @@ func (srv *Service) UpdateCards(uid int, cards *[]*model.Card, cardIds *[]string
 }
 
 // 获取节点分组列表
-func (srv *Service) GetCards(uid int, updateTime int64, limit int) (*[]model.Card, error) {
+func (srv *Service) GetCards(scope *model.SyncScope, updateTime int64, limit int) (*[]model.Card, error) {
 	s := &model.Card{}
-	list, err := s.GetCards(uid, updateTime, limit)
+	list, err := s.GetCards(scope, updateTime, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +144,7 @@ func (srv *Service) GetCards(uid int, updateTime int64, limit int) (*[]model.Car
 		return list, nil
 	}
 	p := &model.Propext{}
-	propMap, err := p.GetPropexts(uid, &cardIds)
+	propMap, err := p.GetPropextsByUids(scope.Uids(), &cardIds)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/validreq"
+
+	"gorm.io/gorm"
+)
+
+// 注销申请的冷静期，期间可以撤销
+const ACCOUNT_DELETE_GRACE = 7 * 24 * time.Hour
+
+// 清除任务的执行间隔和单次处理数量
+const DELETION_WORKER_INTERVAL = time.Hour
+const DELETION_WORKER_BATCH = 20
+
+// 审计日志中的注销操作名称
+const AUDIT_ACCOUNT_DELETE_REQUEST = "account:delete_request"
+const AUDIT_ACCOUNT_DELETE_CANCEL = "account:delete_cancel"
+const AUDIT_ACCOUNT_PURGE = "account:purge"
+
+// 注销回执，清除完成后保存到注销记录并写入审计日志
+type DeletionReceipt struct {
+	Uid       int              `json:"uid"`
+	RequestAt int              `json:"request_time"`
+	PurgeAt   int              `json:"purge_time"`
+	Rows      map[string]int64 `json:"rows"`
+	Objects   int              `json:"objects"`
+}
+
+// 获取当前用户的注销申请，没有时返回 nil
+func (srv *Service) GetDeletion() (*model.Deletion, error) {
+	md := &model.Deletion{}
+	err := md.SelectPending(global.Uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New("查询注销申请异常")
+	}
+	return md, nil
+}
+
+// 申请注销账号，冷静期后清除全部数据
+func (srv *Service) RequestDeletion(param *validreq.RequestDeletionReq, ip string) (*model.Deletion, error) {
+	u, err := srv.GetUser()
+	if err != nil {
+		return nil, err
+	}
+	// 设置了密码的账号需要校验密码
+	if u.Password != "" && u.Password != genPassword(param.Password) {
+		return nil, errors.New("密码不正确")
+	}
+	// 启用两步验证的账号需要校验验证码
+	if srv.IsTotpEnabled(u.Id) && !verifySecondFactor(u.Id, param.Code) {
+		return nil, errors.New("两步验证码不正确")
+	}
+	md := &model.Deletion{}
+	if err := md.SelectPending(u.Id); err == nil {
+		return md, nil
+	}
+	md = &model.Deletion{}
+	err = md.CreateDeletion(u.Id, int(time.Now().Add(ACCOUNT_DELETE_GRACE).Unix()))
+	if err != nil {
+		return nil, errors.New("保存注销申请失败")
+	}
+	srv.Audit(u.Id, AUDIT_ACCOUNT_DELETE_REQUEST, "", "deletion:"+strconv.Itoa(md.Id), ip, 0)
+	return md, nil
+}
+
+// 撤销注销申请
+func (srv *Service) CancelDeletion(ip string) error {
+	md := &model.Deletion{}
+	rows, err := md.CancelDeletion(global.Uid)
+	if err != nil {
+		return errors.New("撤销注销申请失败")
+	}
+	if rows == 0 {
+		return errors.New("没有待处理的注销申请")
+	}
+	srv.Audit(global.Uid, AUDIT_ACCOUNT_DELETE_CANCEL, "", "", ip, 0)
+	return nil
+}
+
+// 清除账号的全部数据：数据库记录、存储文件、缓存和实时连接
+func (srv *Service) PurgeAccount(d *model.Deletion) (*DeletionReceipt, error) {
+	u := &model.User{}
+	err := u.SelectById(d.Uid)
+	if err != nil {
+		return nil, errors.New("查询用户信息异常")
+	}
+	// 先删除存储文件，失败时保留数据等待下次重试
+	objects, err := deleteUserObjects(d.Uid)
+	if err != nil {
+		log.Printf("删除用户存储文件异常 [%d]: %s", d.Uid, err)
+		return nil, errors.New("删除存储文件失败")
+	}
+	md := &model.Deletion{}
+	rows, err := md.PurgeUserData(d.Uid)
+	if err != nil {
+		log.Printf("清除用户数据异常 [%d]: %s", d.Uid, err)
+		return nil, errors.New("清除用户数据失败")
+	}
+	// 拒绝注销前签发的 token，清除缓存并断开实时连接
+	cache.SetUserDeleted(d.Uid)
+	cache.ClearUserCache(d.Uid)
+	cache.ClearUserRoles(d.Uid)
+	if u.Mobile != "" {
+		cache.ClearThrottle(THROTTLE_LOGIN_MOBILE, u.Mobile)
+		for purpose := range verifyPurposeNames {
+			cache.ClearVerifyCode(purpose, u.Mobile)
+		}
+	}
+	global.SSEClientMap.CloseClients(d.Uid)
+	t := int(time.Now().Unix())
+	receipt := &DeletionReceipt{
+		Uid:       d.Uid,
+		RequestAt: d.CreateTime,
+		PurgeAt:   t,
+		Rows:      rows,
+		Objects:   objects,
+	}
+	data, _ := json.Marshal(receipt)
+	err = md.FinishDeletion(d.Id, t, string(data))
+	if err != nil {
+		log.Printf("保存注销回执异常 [%d]: %s %s", d.Uid, err, data)
+	}
+	srv.Audit(d.Uid, AUDIT_ACCOUNT_PURGE, "", "deletion:"+strconv.Itoa(d.Id), "", 0)
+	log.Printf("账号注销完成: %s", data)
+	return receipt, nil
+}
+
+// 清除已到期的注销申请
+func (srv *Service) PurgeDueAccounts() {
+	if !cache.LockDeletionWorker(DELETION_WORKER_INTERVAL) {
+		return
+	}
+	defer cache.UnlockDeletionWorker()
+	md := &model.Deletion{}
+	list, err := md.GetDueDeletions(int(time.Now().Unix()), DELETION_WORKER_BATCH)
+	if err != nil {
+		log.Printf("查询到期注销申请异常: %s", err)
+		return
+	}
+	for i := range *list {
+		srv.PurgeAccount(&(*list)[i])
+	}
+}
+
+// 定时执行注销清除任务
+func RunDeletionWorker() {
+	srv := New(context.Background())
+	ticker := time.NewTicker(DELETION_WORKER_INTERVAL)
+	defer ticker.Stop()
+	for {
+		srv.PurgeDueAccounts()
+		<-ticker.C
+	}
+}
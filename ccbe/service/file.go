@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/resp"
+	"cc/be/storage"
+)
+
+// 文件库可筛选的文件类型，对应 MIME 类型的主类型
+var fileTypes = map[string]bool{
+	"image":       true,
+	"video":       true,
+	"audio":       true,
+	"text":        true,
+	"application": true,
+}
+
+// 分页查询当前用户的文件，keyword 匹配文件名，fileType 按文件类型筛选
+func (srv *Service) GetFiles(keyword, fileType string, page, pageSize int) (*[]resp.File, int64, error) {
+	mimePrefix := ""
+	if fileType != "" {
+		if !fileTypes[fileType] {
+			return nil, 0, errors.New("文件类型不正确")
+		}
+		mimePrefix = fileType + "/"
+	}
+	f := &model.Filelog{}
+	list, total, err := f.GetFiles(global.Uid, keyword, mimePrefix, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.New("查询文件列表异常")
+	}
+	p := storage.Default()
+	files := make([]resp.File, 0, len(*list))
+	for _, v := range *list {
+		file := resp.File{
+			Id:         v.Id,
+			Key:        v.Key,
+			Hash:       v.Hash,
+			Name:       v.Name,
+			Mime:       v.Mime,
+			Width:      v.Width,
+			Height:     v.Height,
+			Size:       v.Size,
+			RefCount:   v.RefCount,
+			OrphanTime: v.OrphanTime,
+			CreateTime: v.CreateTime,
+		}
+		// 早期的上传记录没有保存存储路径
+		if v.Key != "" {
+			file.Url = p.DownloadURL(v.Key)
+		}
+		files = append(files, file)
+	}
+	return &files, total, nil
+}
+
+// 从文件库删除文件，不论引用计数直接删除用户的文件记录，返回删除的文件数
+// 文件内容没有其他用户持有时同时删除存储的文件
+func (srv *Service) DeleteFiles(ids []int) (int64, error) {
+	f := &model.Filelog{}
+	list, err := f.GetByIds(global.Uid, ids)
+	if err != nil {
+		return 0, errors.New("查询文件上传记录失败")
+	}
+	var n int64
+	for i := range *list {
+		released, _, err := releaseFile(&(*list)[i], 0)
+		if released {
+			n++
+		}
+		if err != nil {
+			log.Printf("删除文件异常 [%d]: %s", global.Uid, err)
+			err = errors.New("删除文件异常")
+			if n > 0 {
+				cache.ClearUserInfo(global.Uid)
+			}
+			return n, err
+		}
+	}
+	if n > 0 {
+		cache.ClearUserInfo(global.Uid)
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"cc/be/model"
+	"cc/be/storage"
+
+	"gorm.io/gorm"
+)
+
+// 已保存的相同内容的文件路径，优先使用用户自己持有的文件，没有时返回空
+func existingKey(uid int, hash string) string {
+	f := &model.Filelog{}
+	if err := f.GetByHash(uid, hash); err == nil && f.Key != "" {
+		return f.Key
+	}
+	b := &model.Fileblob{}
+	if err := b.GetByHash(hash); err == nil {
+		return b.Key
+	}
+	return ""
+}
+
+// 查询或登记文件内容，相同内容只保存一份，并发上传时以先登记的为准
+func acquireBlob(hash, key string, size int64) (*model.Fileblob, error) {
+	b := &model.Fileblob{}
+	err := b.GetByHash(hash)
+	if err == nil {
+		return b, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	b = &model.Fileblob{Hash: hash, Key: key, Size: size}
+	if err := b.CreateFileblob(); err != nil {
+		nb := &model.Fileblob{}
+		if err := nb.GetByHash(hash); err != nil {
+			return nil, err
+		}
+		return nb, nil
+	}
+	return b, nil
+}
+
+// 删除本次上传的重复文件，只删除用户目录下未被使用的路径
+func dropDuplicate(uid int, key, keep string) {
+	// 只处理以 etag 命名的文件，缩略图随原图删除
+	if key == "" || key == keep || !storage.IsUserKey(uid, key) || !storage.IsEtag(storage.KeyHash(key)) {
+		return
+	}
+	b := &model.Fileblob{}
+	if err := b.GetByHash(storage.KeyHash(key)); err == nil && b.Key == key {
+		return
+	}
+	deleteObjects(uid, key)
+}
+
+// 删除用户持有的文件记录，文件内容没有其他用户持有时删除存储的文件
+// orphanTime 大于 0 时只删除仍处于未引用状态的记录，返回是否删除记录和删除的文件数
+func releaseFile(f *model.Filelog, orphanTime int) (bool, int, error) {
+	released, err := f.Release(orphanTime)
+	if err != nil || !released {
+		return released, 0, err
+	}
+	objects := 0
+	b := &model.Fileblob{}
+	err = b.GetByHash(f.Hash)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, 0, err
+	}
+	blobKey := ""
+	if err == nil {
+		blobKey = b.Key
+	}
+	// 早期的上传记录没有文件内容记录，保存在用户目录下的文件只属于该用户
+	if f.Key != "" && f.Key != blobKey && storage.IsUserKey(f.Uid, f.Key) {
+		if err := deleteObjects(f.Uid, f.Key); err != nil {
+			return true, objects, err
+		}
+		objects++
+	}
+	if blobKey != "" && b.RefCount <= 0 {
+		n, err := deleteBlob(b)
+		return true, objects + n, err
+	}
+	return true, objects, nil
+}
+
+// 删除没有用户持有的文件内容和存储的文件
+func deleteBlob(b *model.Fileblob) (int, error) {
+	n, err := b.DeleteUnused()
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	p := storage.Default()
+	if err := p.Delete(b.Key); err != nil {
+		log.Printf("删除文件异常 [%s]: %s", b.Hash, err)
+		return 0, err
+	}
+	objects := 1
+	// 服务端生成的缩略图
+	if b.Variants != "" {
+		for _, name := range strings.Split(b.Variants, ",") {
+			if err := p.Delete(variantKey(b.Key, name)); err != nil {
+				log.Printf("删除缩略图异常 [%s]: %s", b.Hash, err)
+				continue
+			}
+			objects++
+		}
+	}
+	return objects, nil
+}
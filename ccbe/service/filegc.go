@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"cc/be/cache"
+	"cc/be/model"
+	"cc/be/storage"
+)
+
+// 未被引用文件的保留时间，期间重新被引用时取消标记
+const FILE_ORPHAN_GRACE = 7 * 24 * time.Hour
+
+// 清理任务的执行间隔和单次读取数量
+const FILE_GC_INTERVAL = 24 * time.Hour
+const FILE_GC_BATCH = 500
+
+// 清理结果，Deleted 为删除的用户文件记录数，Objects 为删除的存储文件数，Freed 为释放的存储用量(B)
+type FileGCResult struct {
+	Scanned  int   `json:"scanned"`
+	Marked   int   `json:"marked"`
+	Restored int   `json:"restored"`
+	Deleted  int   `json:"deleted"`
+	Objects  int   `json:"objects"`
+	Blobs    int   `json:"blobs"`
+	Freed    int64 `json:"freed"`
+}
+
+// 扫描全部内容中引用的文件，按用户和 etag 记录
+// 相同内容的文件可能保存在其他用户的目录下，引用同时记入内容所属的用户和文件路径中的用户
+func collectFileRefs() (map[int]map[string]bool, error) {
+	refs := make(map[int]map[string]bool)
+	for _, source := range model.ContentSources() {
+		lastId := 0
+		for {
+			list, err := model.GetContents(source, lastId, FILE_GC_BATCH)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range *list {
+				for _, ref := range storage.FindRefs(row.Content) {
+					addFileRef(refs, ref.Uid, ref.Hash)
+					addFileRef(refs, row.Uid, ref.Hash)
+				}
+			}
+			if len(*list) < FILE_GC_BATCH {
+				break
+			}
+			lastId = (*list)[len(*list)-1].Unid
+		}
+	}
+	return refs, nil
+}
+
+func addFileRef(refs map[int]map[string]bool, uid int, hash string) {
+	if refs[uid] == nil {
+		refs[uid] = make(map[string]bool)
+	}
+	refs[uid][hash] = true
+}
+
+// 标记未被引用的文件，标记超过保留时间后删除文件和上传记录
+func (srv *Service) CollectOrphanFiles() (*FileGCResult, error) {
+	refs, err := collectFileRefs()
+	if err != nil {
+		log.Printf("扫描文件引用异常: %s", err)
+		return nil, errors.New("扫描文件引用异常")
+	}
+	now := int(time.Now().Unix())
+	expire := now - int(FILE_ORPHAN_GRACE/time.Second)
+	res := &FileGCResult{}
+	users := make(map[int]bool)
+	f := &model.Filelog{}
+	lastId := 0
+	for {
+		list, err := f.GetFilelogs(0, lastId, FILE_GC_BATCH)
+		if err != nil {
+			log.Printf("查询文件上传记录异常: %s", err)
+			return res, errors.New("查询文件上传记录异常")
+		}
+		var marked, restored []int
+		for _, v := range *list {
+			res.Scanned++
+			// 早期的上传记录没有保存存储路径，无法删除文件
+			if v.Key == "" {
+				continue
+			}
+			if refs[v.Uid][v.Hash] {
+				if v.OrphanTime > 0 {
+					restored = append(restored, v.Id)
+				}
+				continue
+			}
+			if v.OrphanTime == 0 {
+				marked = append(marked, v.Id)
+				continue
+			}
+			if v.OrphanTime > expire {
+				continue
+			}
+			// 删除前重新上传过的文件会取消标记而保留，文件内容仍被其他用户持有时只删除记录
+			released, n, err := releaseFile(&v, v.OrphanTime)
+			if err != nil {
+				log.Printf("删除未引用文件异常 [%d]: %s %s", v.Uid, v.Key, err)
+			}
+			if !released {
+				continue
+			}
+			res.Objects += n
+			res.Deleted++
+			res.Freed += v.Size
+			users[v.Uid] = true
+		}
+		if len(marked) > 0 {
+			if err := f.SetOrphanTime(marked, now); err != nil {
+				log.Printf("标记未引用文件异常: %s", err)
+			} else {
+				res.Marked += len(marked)
+			}
+		}
+		if len(restored) > 0 {
+			if err := f.SetOrphanTime(restored, 0); err != nil {
+				log.Printf("取消未引用文件标记异常: %s", err)
+			} else {
+				res.Restored += len(restored)
+			}
+		}
+		if len(*list) < FILE_GC_BATCH {
+			break
+		}
+		lastId = (*list)[len(*list)-1].Id
+	}
+	// 更新用户信息缓存中的文件容量
+	for uid := range users {
+		cache.SetUserFsize(uid, f.SumSize(uid))
+	}
+	// 删除记录后未能删除的文件内容
+	b := &model.Fileblob{}
+	blobs, err := b.GetUnused(expire, FILE_GC_BATCH)
+	if err != nil {
+		log.Printf("查询未持有的文件内容异常: %s", err)
+		return res, nil
+	}
+	for i := range *blobs {
+		n, err := deleteBlob(&(*blobs)[i])
+		if err != nil {
+			continue
+		}
+		res.Blobs += n
+	}
+	return res, nil
+}
+
+// 执行未引用文件清理
+func (srv *Service) CleanOrphanFiles() {
+	if !cache.LockFileGCWorker(FILE_GC_INTERVAL) {
+		return
+	}
+	defer cache.UnlockFileGCWorker()
+	res, err := srv.CollectOrphanFiles()
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(res)
+	log.Printf("未引用文件清理完成: %s", data)
+}
+
+// 定时执行未引用文件清理任务
+func RunFileGCWorker() {
+	srv := New(context.Background())
+	ticker := time.NewTicker(FILE_GC_INTERVAL)
+	defer ticker.Stop()
+	for {
+		srv.CleanOrphanFiles()
+		<-ticker.C
+	}
+}
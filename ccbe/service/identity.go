@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/idp"
+	"cc/be/model"
+	"cc/be/utils"
+	"cc/be/validreq"
+
+	"gorm.io/gorm"
+)
+
+// 获取第三方授权跳转地址，uid 不为 0 时授权结果绑定到该账号
+func (srv *Service) GetAuthURL(provider string, uid int) (string, string, error) {
+	p, err := idp.Get(provider)
+	if err != nil {
+		return "", "", err
+	}
+	state := utils.SecureRandStr(32)
+	nonce := utils.SecureRandStr(32)
+	err = cache.SetOauthState(state, &cache.OauthState{Provider: provider, Uid: uid, Nonce: nonce})
+	if err != nil {
+		log.Printf("保存授权 state 异常: %s", err)
+		return "", "", errors.New("生成授权地址异常")
+	}
+	url := p.AuthURL(state, nonce)
+	if url == "" {
+		return "", "", errors.New("生成授权地址异常")
+	}
+	return url, state, nil
+}
+
+// 校验 state 并使用授权码换取第三方身份
+func (srv *Service) exchangeIdentity(provider, code, state string, uid int) (*idp.Identity, error) {
+	if provider == "" {
+		provider = idp.PROVIDER_WECHAT
+	}
+	s := cache.PopOauthState(state)
+	if s == nil || s.Provider != provider || s.Uid != uid {
+		return nil, errors.New("授权已失效，请重新授权")
+	}
+	p, err := idp.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := p.Exchange(srv.ctx, code, s.Nonce)
+	if err != nil {
+		log.Printf("第三方授权换取身份异常 [%s]: %s", provider, err)
+		return nil, errors.New("获取第三方账号信息异常")
+	}
+	return identity, nil
+}
+
+// 第三方授权登录回调，已关联账号时返回用户，否则返回待绑定的身份凭证
+func (srv *Service) Callback(param *validreq.CallbackReq) (*model.User, string, *idp.Identity, error) {
+	identity, err := srv.exchangeIdentity(param.Provider, param.Code, param.State, 0)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	mi := &model.Identity{}
+	err = mi.SelectBySubject(identity.Provider, identity.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ticket := utils.SecureRandStr(32)
+		err = cache.SetIdentityTicket(ticket, identity)
+		if err != nil {
+			log.Printf("保存身份凭证异常: %s", err)
+			return nil, "", nil, errors.New("保存第三方账号信息异常")
+		}
+		return nil, ticket, identity, nil
+	} else if err != nil {
+		log.Printf("查询第三方身份出现异常: %s", err)
+		return nil, "", nil, errors.New("查询用户账号异常")
+	}
+	u := &model.User{}
+	err = u.SelectById(mi.Uid)
+	if err != nil {
+		log.Printf("查询用户信息出现异常: %s", err)
+		return nil, "", nil, errors.New("查询用户账号异常")
+	}
+	// 判断账号状态
+	if u.Status != 0 {
+		return nil, "", nil, errors.New("账号状态异常")
+	}
+	// 同步第三方账号的昵称和头像
+	if identity.Username != mi.Username || identity.Avatar != mi.Avatar {
+		mi.UpdateProfile(mi.Id, identity.Username, identity.Avatar)
+	}
+	return u, "", nil, nil
+}
+
+// 获取待绑定的身份信息
+func getTicketIdentity(ticket string) (*idp.Identity, error) {
+	identity := &idp.Identity{}
+	if !cache.GetIdentityTicket(ticket, identity) {
+		return nil, errors.New("授权已失效，请重新授权")
+	}
+	return identity, nil
+}
+
+func newIdentity(uid int, identity *idp.Identity) *model.Identity {
+	return &model.Identity{
+		Uid:      uid,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Unionid:  identity.Unionid,
+		Username: identity.Username,
+		Avatar:   identity.Avatar,
+	}
+}
+
+// 根据授权回调的身份凭证绑定原手机账号
+func (srv *Service) BindUser(param *validreq.BindUserReq) (*model.User, error) {
+	identity, err := getTicketIdentity(param.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	u := &model.User{}
+	err = u.SelectByMobile(param.Mobile)
+	if err != nil {
+		log.Printf("查询用户账号出现异常: %s", err)
+		return nil, errors.New("查询用户账号异常")
+	}
+	// 判断账号密码
+	if u.Password != genPassword(param.Password) {
+		return nil, errors.New("账号密码不正确")
+	}
+	// 判断账号状态
+	if u.Status != 0 {
+		return nil, errors.New("账号状态异常")
+	}
+	mi := &model.Identity{}
+	if mi.ExistBySubject(identity.Provider, identity.Subject) {
+		return nil, errors.New("该第三方账号已绑定其他账号")
+	}
+	mi = newIdentity(u.Id, identity)
+	err = mi.CreateIdentity()
+	if err != nil {
+		log.Printf("保存第三方身份出现异常: %s", err)
+		return nil, errors.New("更新用户绑定异常")
+	}
+	cache.ClearIdentityTicket(param.Ticket)
+	// 账号还未设置昵称头像时，使用第三方账号的信息
+	username := u.Username
+	avatar := u.Avatar
+	if username == "未命名" && identity.Username != "" {
+		username = identity.Username
+	}
+	if avatar == "/cc/avatar.png" && identity.Avatar != "" {
+		avatar = identity.Avatar
+	}
+	if username != u.Username || avatar != u.Avatar {
+		err = u.UpdateUserinfo(u.Id, username, avatar)
+		if err == nil {
+			u.Username = username
+			u.Avatar = avatar
+		}
+	}
+	cache.ClearUserInfo(u.Id)
+	return u, nil
+}
+
+// 根据授权回调的身份凭证和邀请码注册账号
+func (srv *Service) RegisterByCode(param *validreq.RegcodeReq) (*model.User, error) {
+	identity, err := getTicketIdentity(param.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	mi := &model.Identity{}
+	if mi.ExistBySubject(identity.Provider, identity.Subject) {
+		return nil, errors.New("已存在账号，请稍后重试")
+	}
+	// 校验邀请码
+	pid, im, err := checkInviteCode(param.Code)
+	if err != nil {
+		return nil, err
+	}
+	u := &model.User{}
+	dbpsw := genPassword("cardcool" + utils.RandStrBySeed(12))
+	code := genCode()
+	err = u.InsertIdentityUser(newIdentity(0, identity), dbpsw, code, pid, im)
+	if errors.Is(err, model.ErrInviteUsedUp) {
+		return nil, errors.New("该邀请码已被使用")
+	} else if err != nil {
+		return nil, errors.New("创建账号失败")
+	}
+	cache.ClearIdentityTicket(param.Ticket)
+	return u, nil
+}
+
+// 已登录用户绑定第三方身份
+func (srv *Service) BindIdentity(param *validreq.BindIdentityReq) (*model.Identity, error) {
+	identity, err := srv.exchangeIdentity(param.Provider, param.Code, param.State, global.Uid)
+	if err != nil {
+		return nil, err
+	}
+	mi := &model.Identity{}
+	if mi.ExistBySubject(identity.Provider, identity.Subject) {
+		return nil, errors.New("该第三方账号已绑定其他账号")
+	}
+	if mi.GetSubject(global.Uid, identity.Provider) != "" {
+		return nil, errors.New("当前账号已绑定该登录方式，请先解除绑定")
+	}
+	mi = newIdentity(global.Uid, identity)
+	err = mi.CreateIdentity()
+	if err != nil {
+		log.Printf("保存第三方身份出现异常: %s", err)
+		return nil, errors.New("保存绑定信息失败")
+	}
+	// 清除缓存信息
+	cache.ClearUserInfo(global.Uid)
+	return mi, nil
+}
+
+// 获取当前用户关联的第三方身份
+func (srv *Service) GetIdentities() (*[]model.Identity, error) {
+	mi := &model.Identity{}
+	list, err := mi.GetIdentities(global.Uid)
+	if err != nil {
+		return nil, errors.New("查询绑定信息异常")
+	}
+	return list, nil
+}
+
+// 解除第三方身份关联，不允许解除账号唯一的登录方式
+func (srv *Service) UnbindIdentity(id int) error {
+	u := &model.User{}
+	err := u.SelectById(global.Uid)
+	if err != nil {
+		return errors.New("查询用户信息异常")
+	}
+	mi := &model.Identity{}
+	if (u.Mobile == "" || u.Password == "") && mi.CountByUid(global.Uid) <= 1 {
+		return errors.New("请先设置手机账号，再解除绑定")
+	}
+	rows, err := mi.DeleteIdentity(global.Uid, id)
+	if err != nil {
+		return errors.New("解除绑定失败")
+	}
+	if rows == 0 {
+		return errors.New("绑定信息不存在")
+	}
+	// 清除缓存信息
+	cache.ClearUserInfo(global.Uid)
+	return nil
+}
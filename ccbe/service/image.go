@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cc/be/global"
+	"cc/be/imaging"
+	"cc/be/model"
+	"cc/be/setting"
+	"cc/be/storage"
+)
+
+// 解码处理的图片像素数上限
+const IMAGE_MAX_PIXELS = 40000000
+
+// 读取到内存处理的图片大小上限: 30M
+const IMAGE_MAX_SIZE = 31457280
+
+// 下载原图的尺寸参数
+const IMAGE_SIZE_ORIGINAL = "original"
+
+var variantNameRegexp = regexp.MustCompile(`^[a-z0-9]{1,16}$`)
+
+// 未配置时生成的缩略图规格
+var defaultImageVariants = []setting.ImageVariantSetting{
+	{Name: "thumb", Width: 320},
+	{Name: "web", Width: 1600},
+}
+
+// 缩略图规格，按宽度从小到大排序，忽略名称不合法的规格
+func imageVariants() []setting.ImageVariantSetting {
+	if global.StorageSetting == nil || len(global.StorageSetting.Image.Variants) == 0 {
+		return defaultImageVariants
+	}
+	list := make([]setting.ImageVariantSetting, 0, len(global.StorageSetting.Image.Variants))
+	for _, v := range global.StorageSetting.Image.Variants {
+		if v.Width > 0 && v.Name != IMAGE_SIZE_ORIGINAL && variantNameRegexp.MatchString(v.Name) {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Width < list[j].Width
+	})
+	return list
+}
+
+func imageMaxPixels() int {
+	if global.StorageSetting == nil || global.StorageSetting.Image.MaxPixels <= 0 {
+		return IMAGE_MAX_PIXELS
+	}
+	return global.StorageSetting.Image.MaxPixels
+}
+
+func imageQuality() int {
+	if global.StorageSetting == nil {
+		return imaging.DEFAULT_QUALITY
+	}
+	return global.StorageSetting.Image.Quality
+}
+
+// 缩略图路径: 原图路径的文件名后加 _规格名称，与原图在同一目录
+func variantKey(key, name string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + name + ext
+}
+
+// 待处理的上传图片
+type uploadImage struct {
+	data   []byte
+	format string
+}
+
+// 读取上传的图片并去除位置信息，内容有修改时重新写入临时文件计算 etag
+// 宽高按 EXIF 方向旋转后记录到 meta，不支持处理的文件返回 nil
+func prepareImage(sp *storage.Spooled, meta *storage.Meta, maxSize int64) (*uploadImage, *storage.Spooled, error) {
+	format := imaging.Format(meta.Mime)
+	if format == "" || sp.Size > IMAGE_MAX_SIZE {
+		return nil, sp, nil
+	}
+	data, err := io.ReadAll(sp.File)
+	if err != nil {
+		return nil, sp, err
+	}
+	if _, err := sp.File.Seek(0, io.SeekStart); err != nil {
+		return nil, sp, err
+	}
+	if w, h, err := imaging.Size(data); err == nil {
+		meta.Width, meta.Height = w, h
+	}
+	img := &uploadImage{data: data, format: format}
+	stripped, changed := imaging.StripLocation(data)
+	if !changed {
+		return img, sp, nil
+	}
+	nsp, err := storage.Spool(bytes.NewReader(stripped), maxSize)
+	if err != nil {
+		return nil, sp, err
+	}
+	sp.Close()
+	img.data = stripped
+	return img, nsp, nil
+}
+
+// 生成缩略图并保存到原图所在目录，原图小于缩略图规格时不生成，返回已生成的规格名称
+func putVariants(key string, img *uploadImage) []string {
+	src, err := imaging.Decode(img.data, imageMaxPixels())
+	if err != nil {
+		log.Printf("解码图片异常 [%s]: %s", key, err)
+		return nil
+	}
+	orientation := imaging.Orientation(img.data)
+	b := src.Bounds()
+	long := b.Dx()
+	if b.Dy() > long {
+		long = b.Dy()
+	}
+	p := storage.Default()
+	var names []string
+	for _, v := range imageVariants() {
+		if long <= v.Width {
+			break
+		}
+		buf := &bytes.Buffer{}
+		if err := imaging.Encode(buf, imaging.Fit(src, v.Width, orientation), img.format, imageQuality()); err != nil {
+			log.Printf("生成缩略图异常 [%s]: %s", key, err)
+			continue
+		}
+		if err := p.Put(variantKey(key, v.Name), buf, int64(buf.Len())); err != nil {
+			log.Printf("保存缩略图异常 [%s]: %s", key, err)
+			continue
+		}
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// 尺寸参数对应的宽度，为缩略图规格名称或宽度(px)，原图或无法识别时为 0
+func variantWidth(size string) int {
+	for _, v := range imageVariants() {
+		if v.Name == size {
+			return v.Width
+		}
+	}
+	w, err := strconv.Atoi(size)
+	if err != nil || w < 0 {
+		return 0
+	}
+	return w
+}
+
+// 按尺寸获取文件下载地址，size 为缩略图规格名称或宽度(px)，为空时返回原图
+// 支持图片处理的存储使用自带的图片处理，其他存储使用服务端生成的缩略图，没有足够大的缩略图时返回原图
+func (srv *Service) GetDownloadURL(key, size string) (string, error) {
+	if !strings.HasPrefix(key, storage.KEY_PREFIX) || strings.Contains(key, "..") || !storage.IsEtag(storage.KeyHash(key)) {
+		return "", errors.New("文件路径不正确")
+	}
+	p := storage.Default()
+	width := variantWidth(size)
+	if width == 0 {
+		return p.DownloadURL(key), nil
+	}
+	if ip, ok := p.(storage.ImageProcessor); ok {
+		return ip.ImageURL(key, width), nil
+	}
+	b := &model.Fileblob{}
+	if err := b.GetByHash(storage.KeyHash(key)); err != nil || b.Key != key || b.Variants == "" {
+		return p.DownloadURL(key), nil
+	}
+	generated := strings.Split(b.Variants, ",")
+	for _, v := range imageVariants() {
+		if v.Width < width {
+			continue
+		}
+		for _, name := range generated {
+			if name == v.Name {
+				return p.DownloadURL(variantKey(key, v.Name)), nil
+			}
+		}
+	}
+	return p.DownloadURL(key), nil
+}
@@ -27,16 +27,56 @@ func genCodes(num int) *[]string {
 	return &codes
 }
 
-// 注册账号
-func (srv *Service) GenerateCodes(limitType int8, startTime, endTime, num int) error {
-	if limitType < 0 || startTime < 0 || endTime < 0 || num <= 0 {
+// 生成邀请码，maxUses 为 0 时不限使用次数，一次性邀请码默认只能使用一次
+func (srv *Service) GenerateCodes(limitType int8, startTime, endTime, maxUses, num int) error {
+	if limitType < 0 || startTime < 0 || endTime < 0 || maxUses < 0 || num <= 0 {
 		return errors.New("参数异常")
 	}
+	if limitType == model.INVITE_LIMIT_ONCE && maxUses == 0 {
+		maxUses = 1
+	}
 	im := &model.Invite{}
 	codes := genCodes(num)
-	err := im.BatchInsertInvites(global.Uid, startTime, endTime, limitType, codes)
+	err := im.BatchInsertInvites(global.Uid, startTime, endTime, maxUses, limitType, codes)
 	if err != nil {
 		return errors.New("生成邀请码失败")
 	}
 	return nil
 }
+
+// 分页查询邀请码
+func (srv *Service) GetInvites(status *int8, createUid int, code string, page, pageSize int) (*[]model.Invite, int64, error) {
+	im := &model.Invite{}
+	list, total, err := im.GetInvites(status, createUid, code, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.New("查询邀请码异常")
+	}
+	return list, total, nil
+}
+
+// 作废邀请码，已作废的邀请码无法再用于注册
+func (srv *Service) RevokeInvite(id int) error {
+	im := &model.Invite{}
+	ok, err := im.RevokeInvite(id)
+	if err != nil {
+		return errors.New("作废邀请码失败")
+	}
+	if !ok {
+		return errors.New("邀请码不存在或已作废")
+	}
+	return nil
+}
+
+// 分页查询邀请码的使用记录
+func (srv *Service) GetRedemptions(inviteId, page, pageSize int) (*[]model.RedemptionUser, int64, error) {
+	im := &model.Invite{}
+	if err := im.SelectById(inviteId); err != nil {
+		return nil, 0, errors.New("邀请码不存在")
+	}
+	r := &model.Redemption{}
+	list, total, err := r.GetRedemptions(inviteId, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.New("查询邀请码使用记录异常")
+	}
+	return list, total, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/setting"
+	"cc/be/storage"
+
+	"gorm.io/gorm"
+)
+
+// 未配置套餐时的默认套餐名称
+const QUOTA_DEFAULT_PLAN = "free"
+
+var ErrQuotaExceeded = errors.New("存储空间已超出额度")
+
+// 用户存储用量，用量从文件上传记录实时统计
+type StorageUsage struct {
+	Uid           int    `json:"uid"`
+	Plan          string `json:"plan"`
+	Used          int64  `json:"used"`
+	Files         int64  `json:"files"`
+	Limit         int64  `json:"limit"`
+	SoftLimit     int64  `json:"soft_limit"`
+	Custom        bool   `json:"custom"`
+	OverSoftLimit bool   `json:"over_soft_limit"`
+	OverLimit     bool   `json:"over_limit"`
+}
+
+// 可用的存储套餐，未配置时只有使用默认额度的 free 套餐
+func quotaPlans() []setting.QuotaPlanSetting {
+	if global.QuotaSetting == nil || len(global.QuotaSetting.Plans) == 0 {
+		return []setting.QuotaPlanSetting{{Name: QUOTA_DEFAULT_PLAN, Limit: SOURCE_LIMIT}}
+	}
+	return global.QuotaSetting.Plans
+}
+
+// 根据名称查询套餐
+func getQuotaPlan(name string) (setting.QuotaPlanSetting, bool) {
+	plans := quotaPlans()
+	for _, p := range plans {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return plans[0], false
+}
+
+// 默认套餐，配置的默认套餐不存在时使用第一个套餐
+func defaultQuotaPlan() setting.QuotaPlanSetting {
+	if global.QuotaSetting != nil {
+		if p, ok := getQuotaPlan(global.QuotaSetting.DefaultPlan); ok {
+			return p
+		}
+	}
+	return quotaPlans()[0]
+}
+
+// 软限制为 0 或大于硬限制时取硬限制的 80%
+func softLimit(limit, soft int64) int64 {
+	if soft <= 0 || soft > limit {
+		return limit / 5 * 4
+	}
+	return soft
+}
+
+// 查询用户的存储用量和额度
+func (srv *Service) GetStorageUsage(uid int) (*StorageUsage, error) {
+	f := &model.Filelog{}
+	used, files, err := f.GetUsage(uid)
+	if err != nil {
+		return nil, errors.New("查询存储用量异常")
+	}
+	usage := &StorageUsage{Uid: uid, Used: used, Files: files}
+	mq := &model.Userquota{}
+	err = mq.GetByUid(uid)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("查询存储额度异常")
+	}
+	// 未设置套餐或套餐已从配置中移除时使用默认套餐
+	plan, ok := getQuotaPlan(mq.Plan)
+	if mq.Plan == "" || !ok {
+		plan = defaultQuotaPlan()
+	}
+	usage.Plan = plan.Name
+	usage.Limit = plan.Limit
+	soft := plan.SoftLimit
+	if mq.HardLimit > 0 {
+		usage.Limit = mq.HardLimit
+		usage.Custom = true
+	}
+	if mq.SoftLimit > 0 {
+		soft = mq.SoftLimit
+		usage.Custom = true
+	}
+	usage.SoftLimit = softLimit(usage.Limit, soft)
+	usage.OverSoftLimit = used >= usage.SoftLimit
+	usage.OverLimit = used >= usage.Limit
+	return usage, nil
+}
+
+// 校验文件大小和用户上传额度，size 为待上传的文件大小
+func (srv *Service) checkUploadQuota(uid int, size int64) error {
+	if size <= 0 {
+		return errors.New("缺少文件大小")
+	}
+	if size > storage.Default().MaxSize() {
+		return storage.ErrTooLarge
+	}
+	usage, err := srv.GetStorageUsage(uid)
+	if err != nil {
+		return err
+	}
+	if usage.Used+size > usage.Limit {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// 查询用户的额度设置和可用套餐
+func (srv *Service) GetUserQuota(uid int) (*StorageUsage, []setting.QuotaPlanSetting, error) {
+	u := &model.User{}
+	if err := u.SelectById(uid); err != nil {
+		return nil, nil, errors.New("查询用户信息异常")
+	}
+	usage, err := srv.GetStorageUsage(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return usage, quotaPlans(), nil
+}
+
+// 设置用户的套餐或单独的额度，hardLimit/softLimit 为 0 时使用套餐的额度
+func (srv *Service) UpdateUserQuota(uid int, plan string, hard, soft int64) error {
+	if plan != "" {
+		if _, ok := getQuotaPlan(plan); !ok {
+			return errors.New("套餐不存在")
+		}
+	}
+	if hard > 0 && soft > hard {
+		return errors.New("软限制不能大于硬限制")
+	}
+	u := &model.User{}
+	if err := u.SelectById(uid); err != nil {
+		return errors.New("查询用户信息异常")
+	}
+	mq := &model.Userquota{}
+	err := mq.GetByUid(uid)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("查询存储额度异常")
+	}
+	mq.Uid = uid
+	mq.Plan = plan
+	mq.HardLimit = hard
+	mq.SoftLimit = soft
+	mq.UpdateUid = global.Uid
+	if err := mq.SaveQuota(); err != nil {
+		return errors.New("保存存储额度失败")
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/model"
+)
+
+// 被邀请用户活跃状态：1-活跃(有效期内有数据更新)，0-不活跃，-1-已注销
+const INVITEE_ACTIVE = 1
+const INVITEE_INACTIVE = 0
+const INVITEE_DELETED = -1
+
+// 邀请关系树最大层级和节点数量
+const REFERRAL_TREE_MAX_DEPTH = 5
+const REFERRAL_TREE_MAX_NODES = 1000
+
+// 邀请统计按天或按月汇总
+const REFERRAL_UNIT_DAY = "day"
+const REFERRAL_UNIT_MONTH = "month"
+
+var referralUnitFormats = map[string]string{
+	REFERRAL_UNIT_DAY:   "%Y-%m-%d",
+	REFERRAL_UNIT_MONTH: "%Y-%m",
+}
+
+// 被邀请用户及活跃状态
+type InviteeInfo struct {
+	model.Invitee
+	Active     int8  `json:"active"`
+	ActiveTime int64 `json:"active_time"`
+}
+
+// 邀请关系树节点
+type ReferralNode struct {
+	InviteeInfo
+	InviteCount int64           `json:"invite_count"`
+	Children    []*ReferralNode `json:"children"`
+}
+
+// 单个时间段的邀请转化数据
+type ReferralPeriod struct {
+	Period       string  `json:"period"`
+	Users        int64   `json:"users"`
+	Referred     int64   `json:"referred"`
+	Invites      int64   `json:"invites"`
+	Redemptions  int64   `json:"redemptions"`
+	ReferralRate float64 `json:"referral_rate"`
+}
+
+// 邀请统计数据
+type ReferralStats struct {
+	Inviters *[]model.InviterCount `json:"inviters"`
+	Timeline []*ReferralPeriod     `json:"timeline"`
+}
+
+// 补充被邀请用户的活跃状态
+func withActivity(list []model.Invitee) []*InviteeInfo {
+	uids := make([]int, len(list))
+	for i, item := range list {
+		uids[i] = item.Id
+	}
+	times := cache.GetUsersUpdateTime(uids)
+	res := make([]*InviteeInfo, len(list))
+	for i, item := range list {
+		info := &InviteeInfo{Invitee: item, Active: INVITEE_INACTIVE}
+		if item.Status == -1 {
+			info.Active = INVITEE_DELETED
+		} else if t, ok := times[item.Id]; ok {
+			info.Active = INVITEE_ACTIVE
+			info.ActiveTime = t
+		}
+		res[i] = info
+	}
+	return res
+}
+
+// 分页查询当前用户邀请的账号
+func (srv *Service) GetInvitees(page, pageSize int) ([]*InviteeInfo, int64, error) {
+	u := &model.User{}
+	list, total, err := u.GetInvitees(global.Uid, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.New("查询邀请记录异常")
+	}
+	return withActivity(*list), total, nil
+}
+
+// 查询邀请关系树，uid 为 0 时分页返回所有顶层邀请人，truncated 表示节点数量超出上限被截断
+func (srv *Service) GetReferralTree(uid, depth, page, pageSize int) ([]*ReferralNode, int64, bool, error) {
+	if depth <= 0 || depth > REFERRAL_TREE_MAX_DEPTH {
+		depth = REFERRAL_TREE_MAX_DEPTH
+	}
+	u := &model.User{}
+	var roots []model.Invitee
+	var total int64
+	if uid > 0 {
+		root, err := u.GetInviteeById(uid)
+		if err != nil {
+			return nil, 0, false, errors.New("用户不存在")
+		}
+		roots = []model.Invitee{*root}
+		total = 1
+	} else {
+		list, cnt, err := u.GetRootInviters(page, pageSize)
+		if err != nil {
+			return nil, 0, false, errors.New("查询邀请关系异常")
+		}
+		roots = *list
+		total = cnt
+	}
+	nodes := toReferralNodes(roots)
+	res := nodes
+	remain := REFERRAL_TREE_MAX_NODES - len(nodes)
+	truncated := false
+	// 逐层查询下级邀请关系
+	for level := 0; level < depth && len(nodes) > 0; level++ {
+		parents := make(map[int]*ReferralNode, len(nodes))
+		pids := make([]int, 0, len(nodes))
+		for _, n := range nodes {
+			parents[n.Id] = n
+			pids = append(pids, n.Id)
+		}
+		counts, err := u.CountInvitees(pids)
+		if err != nil {
+			return nil, 0, false, errors.New("查询邀请关系异常")
+		}
+		for pid, cnt := range counts {
+			parents[pid].InviteCount = cnt
+		}
+		if level == depth-1 || len(counts) == 0 {
+			break
+		}
+		if remain <= 0 {
+			truncated = true
+			break
+		}
+		list, err := u.GetInviteesByPids(pids, remain+1)
+		if err != nil {
+			return nil, 0, false, errors.New("查询邀请关系异常")
+		}
+		if len(*list) > remain {
+			*list = (*list)[:remain]
+			truncated = true
+		}
+		nodes = toReferralNodes(*list)
+		remain -= len(nodes)
+		for _, n := range nodes {
+			p := parents[n.Pid]
+			p.Children = append(p.Children, n)
+		}
+	}
+	return res, total, truncated, nil
+}
+
+func toReferralNodes(list []model.Invitee) []*ReferralNode {
+	infos := withActivity(list)
+	nodes := make([]*ReferralNode, len(infos))
+	for i, info := range infos {
+		nodes[i] = &ReferralNode{InviteeInfo: *info, Children: []*ReferralNode{}}
+	}
+	return nodes
+}
+
+// 统计时间范围内的邀请人排行和按时间段的邀请转化情况
+func (srv *Service) GetReferralStats(startTime, endTime int, unit string, limit int) (*ReferralStats, error) {
+	format, ok := referralUnitFormats[unit]
+	if !ok {
+		return nil, errors.New("不支持的统计周期")
+	}
+	if endTime <= 0 {
+		endTime = int(time.Now().Unix())
+	}
+	if startTime < 0 || startTime >= endTime {
+		return nil, errors.New("统计时间范围异常")
+	}
+	u := &model.User{}
+	inviters, err := u.GetTopInviters(startTime, endTime, limit)
+	if err != nil {
+		return nil, errors.New("查询邀请人统计异常")
+	}
+	registers, err := u.GetRegisterStats(startTime, endTime, format)
+	if err != nil {
+		return nil, errors.New("查询注册统计异常")
+	}
+	im := &model.Invite{}
+	invites, err := im.GetCreateStats(startTime, endTime, format)
+	if err != nil {
+		return nil, errors.New("查询邀请码统计异常")
+	}
+	r := &model.Redemption{}
+	redemptions, err := r.GetRedeemStats(startTime, endTime, format)
+	if err != nil {
+		return nil, errors.New("查询邀请码使用统计异常")
+	}
+	// 按时间段合并各项统计
+	periods := map[string]*ReferralPeriod{}
+	timeline := []*ReferralPeriod{}
+	getPeriod := func(period string) *ReferralPeriod {
+		p, ok := periods[period]
+		if !ok {
+			p = &ReferralPeriod{Period: period}
+			periods[period] = p
+			timeline = append(timeline, p)
+		}
+		return p
+	}
+	for _, item := range *registers {
+		p := getPeriod(item.Period)
+		p.Users = item.Cnt
+		p.Referred = item.Referred
+		if item.Cnt > 0 {
+			p.ReferralRate = float64(item.Referred) / float64(item.Cnt)
+		}
+	}
+	for _, item := range *invites {
+		getPeriod(item.Period).Invites = item.Cnt
+	}
+	for _, item := range *redemptions {
+		getPeriod(item.Period).Redemptions = item.Cnt
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i].Period < timeline[j].Period
+	})
+	return &ReferralStats{
+		Inviters: inviters,
+		Timeline: timeline,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/model"
+)
+
+// 获取用户的角色列表，所有用户默认拥有 user 角色
+func (srv *Service) GetUserRoles(uid int) []string {
+	roles, ok := cache.GetUserRoles(uid)
+	if !ok {
+		mr := &model.Userrole{}
+		list, err := mr.GetRoles(uid)
+		if err != nil {
+			log.Printf("查询用户角色异常: %s", err)
+			return []string{model.ROLE_USER}
+		}
+		roles = list
+		cache.SetUserRoles(uid, roles)
+	}
+	return append([]string{model.ROLE_USER}, roles...)
+}
+
+// 判断用户是否拥有指定权限
+func (srv *Service) HasPermission(uid int, perm string) bool {
+	if uid == 0 {
+		return false
+	}
+	return model.RolesHavePermission(srv.GetUserRoles(uid), perm)
+}
+
+// 授予用户角色
+func (srv *Service) GrantRole(uid int, role string) error {
+	if !model.IsValidRole(role) || role == model.ROLE_USER {
+		return errors.New("角色不存在")
+	}
+	u := &model.User{}
+	if err := u.SelectById(uid); err != nil {
+		return errors.New("查询用户信息异常")
+	}
+	mr := &model.Userrole{}
+	if mr.ExistRole(uid, role) {
+		return nil
+	}
+	mr = &model.Userrole{}
+	err := mr.CreateUserrole(uid, role, global.Uid)
+	if err != nil {
+		return errors.New("授予角色失败")
+	}
+	cache.ClearUserRoles(uid)
+	return nil
+}
+
+// 撤销用户角色
+func (srv *Service) RevokeRole(uid int, role string) error {
+	if !model.IsValidRole(role) || role == model.ROLE_USER {
+		return errors.New("角色不存在")
+	}
+	// 避免管理员撤销自己的管理员角色后无人可管理
+	if uid == global.Uid && role == model.ROLE_ADMIN {
+		return errors.New("不能撤销自己的管理员角色")
+	}
+	mr := &model.Userrole{}
+	err := mr.DeleteUserrole(uid, role)
+	if err != nil {
+		return errors.New("撤销角色失败")
+	}
+	cache.ClearUserRoles(uid)
+	return nil
+}
+
+// 获取用户的角色记录
+func (srv *Service) GetUserroles(uid int) (*[]model.Userrole, error) {
+	mr := &model.Userrole{}
+	list, err := mr.GetUserroles(uid)
+	if err != nil {
+		return nil, errors.New("查询用户角色异常")
+	}
+	return list, nil
+}
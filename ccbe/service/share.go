@@ -2,21 +2,223 @@ package service
 
 import (
 	"errors"
+	"io"
+	"log"
+	"time"
+	"unicode/utf8"
+	"cc/be/cache"
 	"cc/be/global"
 	"cc/be/model"
+	"cc/be/render"
+	"cc/be/utils"
 	"cc/be/validreq"
 
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// 获取指定视图的分享信息
-func (srv *Service) GetShareData(shareId string) (*model.Share, error) {
+// 分享访问密码长度
+const SHARE_PASSWORD_MIN_LEN = 4
+
+var ErrShareExpired = errors.New("当前分享已过期")
+var ErrShareViewLimit = errors.New("当前分享已达到访问次数上限")
+var ErrSharePasswordRequired = errors.New("当前分享需要输入访问密码")
+var ErrShareRevision = errors.New("分享的历史版本不存在或不允许访问")
+
+// 获取指定视图的分享信息，设置了访问密码时需携带有效的访问令牌，每次获取计入访问次数
+// rev 大于 0 时获取快照分享的指定历史版本，返回的 Rev 为本次访问的版本号
+func (srv *Service) GetShareData(shareId, accessToken string, rev int) (*model.Share, error) {
+	return srv.getShareData(shareId, accessToken, rev, false)
+}
+
+// 获取嵌入页面的分享信息，未开启嵌入的分享不计入访问次数
+func (srv *Service) GetShareEmbedData(shareId, accessToken string, rev int) (*model.Share, error) {
+	return srv.getShareData(shareId, accessToken, rev, true)
+}
+
+func (srv *Service) getShareData(shareId, accessToken string, rev int, embed bool) (*model.Share, error) {
 	ms := &model.Share{}
 	err := ms.GetShareData(shareId)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
-	return ms, err
+	if ms.Status != 1 {
+		return ms, nil
+	}
+	if embed && !ms.CanEmbed() {
+		return nil, ErrShareEmbedDisabled
+	}
+	if err := checkShareValid(ms); err != nil {
+		return nil, err
+	}
+	if ms.HasPassword() && !checkShareAccess(ms, accessToken) {
+		return nil, ErrSharePasswordRequired
+	}
+	if rev > 0 {
+		if err := loadShareRevision(ms, rev); err != nil {
+			return nil, err
+		}
+	} else if ms.Mode == model.SHARE_MODE_LIVE {
+		if err := srv.loadLiveShare(ms); err != nil {
+			return nil, err
+		}
+	} else if ms.PinRev > 0 {
+		ms.Rev = ms.PinRev
+	}
+	ok, err := ms.IncrViewCount()
+	if err != nil {
+		return nil, errors.New("更新分享访问次数异常")
+	}
+	if !ok {
+		return nil, ErrShareViewLimit
+	}
+	return ms, nil
+}
+
+// 实时分享：根据视图或卡片的当前数据生成分享内容，分享者的数据更新时间变化后重新生成
+func (srv *Service) loadLiveShare(ms *model.Share) error {
+	updateTime := cache.GetUserUpdateTime(ms.Uid)
+	live := cache.GetShareLive(ms.Uuid)
+	if live == nil || updateTime == 0 || live.UpdateTime != updateTime {
+		var err error
+		live, err = srv.buildLiveShare(ms)
+		if errors.Is(err, ErrViewNotFound) {
+			return errors.New("分享的视图已删除")
+		} else if errors.Is(err, ErrCardNotFound) {
+			return errors.New("分享的卡片已删除")
+		} else if err != nil {
+			log.Printf("生成实时分享内容异常: %s", err)
+			return errors.New("生成分享内容异常")
+		}
+		live.UpdateTime = updateTime
+		// 获取更新时间失败时不缓存
+		if updateTime > 0 {
+			cache.SetShareLive(ms.Uuid, live)
+		}
+	}
+	ms.Name = live.Name
+	ms.Icon = live.Icon
+	ms.Type = live.Type
+	ms.Content = live.Content
+	return nil
+}
+
+// 历史版本：分享者允许通过链接访问历史版本时，使用指定版本的内容
+func loadShareRevision(ms *model.Share, rev int) error {
+	if ms.Mode != model.SHARE_MODE_SNAPSHOT || ms.RevPublic != 1 {
+		return ErrShareRevision
+	}
+	rv := &model.Sharerevision{}
+	err := rv.GetRevision(ms.Id, rev)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrShareRevision
+	} else if err != nil {
+		return errors.New("查询分享历史版本异常")
+	}
+	ms.Name = rv.Name
+	ms.Icon = rv.Icon
+	ms.Type = rv.Type
+	ms.Content = rv.Content
+	ms.Rev = rv.Rev
+	return nil
+}
+
+func (srv *Service) buildLiveShare(ms *model.Share) (*cache.ShareLive, error) {
+	if ms.IsCardShare() {
+		card, content, err := srv.BuildCardContent(ms.Uid, ms.CardId)
+		if err != nil {
+			return nil, err
+		}
+		return &cache.ShareLive{Name: card.Name, Icon: card.Icon, Content: content}, nil
+	}
+	view, content, err := srv.BuildViewContent(ms.Uid, ms.ViewId)
+	if err != nil {
+		return nil, err
+	}
+	return &cache.ShareLive{Name: view.Name, Icon: view.Icon, Type: int8(view.Type), Content: content}, nil
+}
+
+// 校验分享是否过期或达到访问次数上限
+func checkShareValid(ms *model.Share) error {
+	if ms.ExpireTime > 0 && int64(ms.ExpireTime) < time.Now().Unix() {
+		return ErrShareExpired
+	}
+	if ms.MaxViews > 0 && ms.ViewCount >= ms.MaxViews {
+		return ErrShareViewLimit
+	}
+	return nil
+}
+
+// 访问令牌中的密码摘要片段，修改密码后已签发的令牌失效
+func shareStamp(ms *model.Share) string {
+	if len(ms.Password) < 16 {
+		return ms.Password
+	}
+	return ms.Password[len(ms.Password)-16:]
+}
+
+func checkShareAccess(ms *model.Share, accessToken string) bool {
+	if accessToken == "" {
+		return false
+	}
+	access := cache.GetShareAccess(accessToken)
+	return access != nil && access.ShareId == ms.Uuid && access.Stamp == shareStamp(ms)
+}
+
+// 校验分享访问密码，通过后下发短期访问令牌
+func (srv *Service) CreateShareAccess(shareId, password, ip string) (string, error) {
+	ms := &model.Share{}
+	err := ms.GetShareData(shareId)
+	if err != nil || ms.Status != 1 {
+		return "", errors.New("分享不存在或已取消")
+	}
+	if err := checkShareValid(ms); err != nil {
+		return "", err
+	}
+	if !ms.HasPassword() {
+		return "", errors.New("当前分享无需访问密码")
+	}
+	keys := ShareThrottleKeys(shareId, ip)
+	if err := srv.CheckThrottle(keys); err != nil {
+		return "", err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(ms.Password), []byte(password)) != nil {
+		srv.ThrottleFailure(keys)
+		return "", errors.New("访问密码不正确")
+	}
+	token := utils.SecureRandStr(32)
+	err = cache.SetShareAccess(token, &cache.ShareAccess{ShareId: ms.Uuid, Stamp: shareStamp(ms)})
+	if err != nil {
+		return "", errors.New("生成访问令牌失败")
+	}
+	return token, nil
+}
+
+// 设置分享的访问限制，password 为 nil 时不修改访问密码
+func setShareLimit(ms *model.Share, password *string, expireTime, maxViews int) error {
+	if expireTime > 0 && int64(expireTime) <= time.Now().Unix() {
+		return errors.New("过期时间不能早于当前时间")
+	}
+	ms.ExpireTime = expireTime
+	ms.MaxViews = maxViews
+	if password == nil {
+		return nil
+	}
+	if *password == "" {
+		ms.Password = ""
+		return nil
+	}
+	if len(*password) < SHARE_PASSWORD_MIN_LEN {
+		return errors.New("访问密码长度不能少于4位")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("设置访问密码失败")
+	}
+	ms.Password = string(hash)
+	return nil
 }
 
 // 获取指定视图的分享信息
@@ -35,30 +237,343 @@ func (srv *Service) CreateShare(uid int, params *validreq.CreateShareReq) (*mode
 	if !srv.CheckViewExist(uid, params.ViewId) {
 		return nil, errors.New("查询视图信息异常")
 	}
+	// 实时分享不保存视图内容快照
+	content := params.Content
+	if params.Mode == model.SHARE_MODE_LIVE {
+		content = ""
+	}
 	// 查询该视图当前是否存在分享数据
 	ms := &model.Share{}
 	err := ms.GetShareInfo(uid, params.ViewId)
 	// 没有查询到分享数据，因此创建新的分享
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		err = ms.CreateShare(global.Uid, params.Type, params.ViewId, params.Name, params.Icon, params.Content)
+		if err := setShareLimit(ms, params.Password, params.ExpireTime, params.MaxViews); err != nil {
+			return nil, err
+		}
+		if err := setShareEmbed(ms, params.EmbedOrigins); err != nil {
+			return nil, err
+		}
+		ms.Mode = params.Mode
+		ms.RevPublic = params.RevPublic
+		if ms.Mode == model.SHARE_MODE_SNAPSHOT {
+			ms.Rev = 1
+		}
+		err = ms.CreateShare(global.Uid, params.Type, params.ViewId, params.Name, params.Icon, content)
 		if err != nil {
 			return nil, err
 		}
+		if ms.Mode == model.SHARE_MODE_SNAPSHOT {
+			if _, err := saveShareRevision(ms, ms.Rev, params.Name, params.Icon, params.Type, content); err != nil {
+				return nil, err
+			}
+		}
 		return ms, nil
 	} else if err != nil || ms.Id == 0 {
 		return nil, errors.New("查询分享数据异常")
 	} else {
 		// 已经存在分享数据，则进行更新
-		err = ms.UpdateShare(ms.Id, params.Name, params.Icon, params.Content)
+		if err := setShareLimit(ms, params.Password, params.ExpireTime, params.MaxViews); err != nil {
+			return nil, err
+		}
+		if err := setShareEmbed(ms, params.EmbedOrigins); err != nil {
+			return nil, err
+		}
+		ms.Mode = params.Mode
+		ms.RevPublic = params.RevPublic
+		name, icon := params.Name, params.Icon
+		if ms.Mode == model.SHARE_MODE_SNAPSHOT {
+			// 每次刷新保存为新的版本
+			rv, err := saveShareRevision(ms, ms.Rev+1, params.Name, params.Icon, params.Type, content)
+			if err != nil {
+				return nil, err
+			}
+			ms.Rev = rv.Rev
+			// 固定了公开版本时，刷新只保存新版本，公开内容不变
+			if ms.PinRev > 0 {
+				pinned := &model.Sharerevision{}
+				if pinned.GetRevision(ms.Id, ms.PinRev) == nil {
+					name, icon, content = pinned.Name, pinned.Icon, pinned.Content
+				} else {
+					ms.PinRev = 0
+				}
+			}
+		} else {
+			ms.PinRev = 0
+		}
+		err = ms.UpdateShare(ms.Id, name, icon, content)
 		if err != nil {
 			return nil, err
 		}
+		cache.DelShareLive(ms.Uuid)
 		return ms, nil
 	}
 }
 
+// 每个快照分享默认保留的历史版本数
+const SHARE_REVISION_DEFAULT_MAX = 10
+
+func shareMaxRevisions() int {
+	if global.ShareSetting != nil && global.ShareSetting.MaxRevisions > 0 {
+		return global.ShareSetting.MaxRevisions
+	}
+	return SHARE_REVISION_DEFAULT_MAX
+}
+
+// 保存快照分享的历史版本，并清理超出保留数量的旧版本
+func saveShareRevision(ms *model.Share, rev int, name, icon string, t int8, content string) (*model.Sharerevision, error) {
+	rv := &model.Sharerevision{
+		ShareId: ms.Id,
+		Uid:     ms.Uid,
+		Rev:     rev,
+		Name:    name,
+		Type:    t,
+		Icon:    icon,
+		Content: content,
+	}
+	if err := rv.CreateRevision(); err != nil {
+		return nil, errors.New("保存分享历史版本失败")
+	}
+	if minRev := rev - shareMaxRevisions() + 1; minRev > 1 {
+		if err := rv.PruneRevisions(ms.Id, minRev, ms.PinRev); err != nil {
+			log.Printf("清理分享历史版本异常: %s", err)
+		}
+	}
+	return rv, nil
+}
+
+// 获取视图的快照分享，实时分享没有历史版本
+func getSnapshotShare(uid int, viewId string) (*model.Share, error) {
+	ms := &model.Share{}
+	err := ms.GetShareInfo(uid, viewId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("该视图未创建分享")
+	} else if err != nil {
+		return nil, errors.New("查询分享数据异常")
+	}
+	if ms.Mode != model.SHARE_MODE_SNAPSHOT {
+		return nil, errors.New("实时分享没有历史版本")
+	}
+	return ms, nil
+}
+
+// 获取视图分享的历史版本列表
+func (srv *Service) GetShareRevisions(uid int, viewId string) (*model.Share, *[]model.Sharerevision, error) {
+	ms, err := getSnapshotShare(uid, viewId)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv := &model.Sharerevision{}
+	list, err := rv.GetRevisions(ms.Id)
+	if err != nil {
+		return nil, nil, errors.New("查询分享历史版本异常")
+	}
+	return ms, list, nil
+}
+
+// 固定公开的分享版本，之后刷新分享只保存新版本不修改公开内容；rev 为 0 时取消固定，公开最新版本
+func (srv *Service) PinShareRevision(uid int, viewId string, rev int) (*model.Share, error) {
+	ms, err := getSnapshotShare(uid, viewId)
+	if err != nil {
+		return nil, err
+	}
+	target := rev
+	if rev == 0 {
+		target = ms.Rev
+	}
+	rv := &model.Sharerevision{}
+	err = rv.GetRevision(ms.Id, target)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("分享的历史版本不存在")
+	} else if err != nil {
+		return nil, errors.New("查询分享历史版本异常")
+	}
+	if err := ms.UpdateShareRevision(rv, rev); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// 回滚到指定的历史版本，回滚内容保存为新的版本并取消固定
+func (srv *Service) RollbackShareRevision(uid int, viewId string, rev int) (*model.Share, error) {
+	ms, err := getSnapshotShare(uid, viewId)
+	if err != nil {
+		return nil, err
+	}
+	rv := &model.Sharerevision{}
+	err = rv.GetRevision(ms.Id, rev)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("分享的历史版本不存在")
+	} else if err != nil {
+		return nil, errors.New("查询分享历史版本异常")
+	}
+	nrv, err := saveShareRevision(ms, ms.Rev+1, rv.Name, rv.Icon, rv.Type, rv.Content)
+	if err != nil {
+		return nil, err
+	}
+	ms.Rev = nrv.Rev
+	if err := ms.UpdateShareRevision(nrv, 0); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// 分享名称最大字符数
+const SHARE_NAME_MAX_LEN = 32
+
+// 获取指定卡片的分享信息
+func (srv *Service) GetCardShareInfo(uid int, cardId string) (*model.Share, error) {
+	ms := &model.Share{}
+	err := ms.GetCardShareInfo(uid, cardId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ms, nil
+	}
+	return ms, err
+}
+
+// 创建或刷新卡片分享，分享内容在访问时根据卡片当前数据生成
+func (srv *Service) CreateCardShare(uid int, params *validreq.CreateCardShareReq) (*model.Share, error) {
+	card := &model.Card{}
+	if err := card.GetCardById(uid, params.CardId); err != nil {
+		return nil, errors.New("查询卡片信息异常")
+	}
+	name := card.Name
+	if utf8.RuneCountInString(name) > SHARE_NAME_MAX_LEN {
+		name = string([]rune(name)[:SHARE_NAME_MAX_LEN])
+	}
+	ms := &model.Share{}
+	err := ms.GetCardShareInfo(uid, params.CardId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := setShareLimit(ms, params.Password, params.ExpireTime, params.MaxViews); err != nil {
+			return nil, err
+		}
+		if err := setShareEmbed(ms, params.EmbedOrigins); err != nil {
+			return nil, err
+		}
+		if err := ms.CreateCardShare(uid, params.CardId, name, ""); err != nil {
+			return nil, err
+		}
+		return ms, nil
+	} else if err != nil || ms.Id == 0 {
+		return nil, errors.New("查询分享数据异常")
+	}
+	if err := setShareLimit(ms, params.Password, params.ExpireTime, params.MaxViews); err != nil {
+		return nil, err
+	}
+	if err := setShareEmbed(ms, params.EmbedOrigins); err != nil {
+		return nil, err
+	}
+	ms.Mode = model.SHARE_MODE_LIVE
+	if err := ms.UpdateShare(ms.Id, name, "", ""); err != nil {
+		return nil, err
+	}
+	cache.DelShareLive(ms.Uuid)
+	return ms, nil
+}
+
+// 更新卡片分享状态
+func (srv *Service) UpdateCardShareStatus(uid int, params *validreq.UpdateCardShareStatusReq) error {
+	ms := &model.Share{}
+	return ms.UpdateCardShareStatus(uid, params.CardId, params.Status)
+}
+
 // 更新视图分享状态
 func (srv *Service) UpdateShareStatus(uid int, params *validreq.UpdateShareStatusReq) error {
 	ms := &model.Share{}
 	return ms.UpdateShareStatus(uid, params.ViewId, params.Status)
 }
+
+// 分享访问统计默认天数和最大天数
+const SHARE_STATS_DEFAULT_DAYS = 30
+const SHARE_STATS_MAX_DAYS = 365
+
+// 访问来源统计数量和来源地址最大长度
+const SHARE_STATS_TOP_REFERERS = 10
+const SHARE_REFERER_MAX_LEN = 256
+
+// 分享访问统计
+type ShareStats struct {
+	TotalViews int                       `json:"total_views"`
+	Views      int64                     `json:"views"`
+	Visitors   int64                     `json:"visitors"`
+	Daily      *[]model.ShareviewDaily   `json:"daily"`
+	Referers   *[]model.ShareviewReferer `json:"referers"`
+}
+
+// 记录分享访问，IP 匿名化后保存，失败不影响分享访问
+func (srv *Service) RecordShareView(ms *model.Share, ip, referer, userAgent string) {
+	anonIP := utils.AnonymizeIP(ip)
+	if len(referer) > SHARE_REFERER_MAX_LEN {
+		referer = referer[:SHARE_REFERER_MAX_LEN]
+	}
+	sv := &model.Shareview{
+		ShareId: ms.Id,
+		Uid:     ms.Uid,
+		Ip:      anonIP,
+		Visitor: utils.Sha256Hex(ms.Uuid + anonIP + userAgent)[:16],
+		Referer: referer,
+	}
+	if err := sv.CreateShareview(); err != nil {
+		log.Printf("记录分享访问异常: %s", err)
+	}
+}
+
+// 获取视图分享近 days 天的访问统计
+func (srv *Service) GetShareStats(uid int, viewId string, days int) (*ShareStats, error) {
+	if days <= 0 {
+		days = SHARE_STATS_DEFAULT_DAYS
+	} else if days > SHARE_STATS_MAX_DAYS {
+		days = SHARE_STATS_MAX_DAYS
+	}
+	ms := &model.Share{}
+	err := ms.GetShareInfo(uid, viewId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("该视图未创建分享")
+	} else if err != nil {
+		return nil, errors.New("查询分享数据异常")
+	}
+	// 从 days-1 天前的零点开始统计
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	startTime := int(start.Unix())
+	sv := &model.Shareview{}
+	views, visitors, err := sv.CountShareviews(ms.Id, startTime)
+	if err != nil {
+		return nil, errors.New("查询分享访问统计异常")
+	}
+	daily, err := sv.GetDailyStats(ms.Id, startTime)
+	if err != nil {
+		return nil, errors.New("查询分享访问统计异常")
+	}
+	referers, err := sv.GetTopReferers(ms.Id, startTime, SHARE_STATS_TOP_REFERERS)
+	if err != nil {
+		return nil, errors.New("查询分享访问来源异常")
+	}
+	return &ShareStats{
+		TotalViews: ms.ViewCount,
+		Views:      views,
+		Visitors:   visitors,
+		Daily:      daily,
+		Referers:   referers,
+	}, nil
+}
+
+func shareRenderOptions() *render.Options {
+	opts := &render.Options{}
+	if global.ShareSetting != nil {
+		opts.ShareURL = global.ShareSetting.ShareURL
+		opts.StaticURL = global.ShareSetting.StaticURL
+		opts.DefaultImage = global.ShareSetting.DefaultImage
+	}
+	return opts
+}
+
+// 将分享内容渲染为 HTML 页面
+func (srv *Service) RenderSharePage(w io.Writer, ms *model.Share) error {
+	return render.Render(w, &render.Share{
+		Uuid:    ms.Uuid,
+		Name:    ms.Name,
+		Type:    ms.Type,
+		IsCard:  ms.IsCardShare(),
+		Content: ms.Content,
+	}, shareRenderOptions())
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/render"
+	"cc/be/utils"
+
+	"gorm.io/gorm"
+)
+
+// 嵌入页面默认尺寸
+const SHARE_EMBED_WIDTH = 640
+const SHARE_EMBED_HEIGHT = 480
+
+// 每个分享允许嵌入的最大来源数
+const SHARE_EMBED_MAX_ORIGINS = 10
+
+// oEmbed 响应建议的缓存时间(s)
+const OEMBED_CACHE_AGE = 3600
+
+var ErrShareEmbedDisabled = errors.New("当前分享未开启嵌入")
+var ErrShareNotFound = errors.New("分享不存在")
+
+// 允许嵌入的来源，支持 https://*.example.com 形式的子域名通配
+var embedOriginRegexp = regexp.MustCompile(`^https?://(\*\.)?[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*(:[0-9]{1,5})?$`)
+
+// oEmbed 响应，字段含义见 https://oembed.com
+type OEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url,omitempty"`
+	Title        string `json:"title"`
+	Html         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+// 设置允许嵌入的来源，origins 为 nil 时不修改
+func setShareEmbed(ms *model.Share, origins *string) error {
+	if origins == nil {
+		return nil
+	}
+	var list []string
+	seen := map[string]bool{}
+	for _, o := range strings.Fields(strings.ReplaceAll(*origins, ",", " ")) {
+		o = strings.TrimSuffix(strings.ToLower(o), "/")
+		if o == "*" {
+			ms.EmbedOrigins = "*"
+			return nil
+		}
+		if !embedOriginRegexp.MatchString(o) {
+			return errors.New("嵌入来源格式不正确: " + o)
+		}
+		if !seen[o] {
+			seen[o] = true
+			list = append(list, o)
+		}
+	}
+	if len(list) > SHARE_EMBED_MAX_ORIGINS {
+		return errors.New("嵌入来源不能超过" + strconv.Itoa(SHARE_EMBED_MAX_ORIGINS) + "个")
+	}
+	ms.EmbedOrigins = strings.Join(list, " ")
+	return nil
+}
+
+// 嵌入页面的 frame-ancestors 策略
+func ShareFrameAncestors(ms *model.Share) string {
+	if !ms.CanEmbed() {
+		return "'none'"
+	}
+	return ms.EmbedOrigins
+}
+
+// 将分享内容渲染为嵌入页面
+func (srv *Service) RenderShareEmbed(w io.Writer, ms *model.Share, theme string) error {
+	return render.RenderEmbed(w, &render.Share{
+		Uuid:    ms.Uuid,
+		Name:    ms.Name,
+		Type:    ms.Type,
+		IsCard:  ms.IsCardShare(),
+		Content: ms.Content,
+	}, shareRenderOptions(), theme)
+}
+
+// 嵌入尺寸使用默认尺寸，不超过消费方指定的最大尺寸
+func embedSize(size, maxSize int) int {
+	if maxSize > 0 && maxSize < size {
+		return maxSize
+	}
+	return size
+}
+
+// 根据分享链接生成 oEmbed 响应，只支持开启嵌入且无需访问密码的分享
+// theme 为空时使用分享链接中的 theme 参数
+func (srv *Service) GetShareOEmbed(shareURL, theme string, maxWidth, maxHeight int) (*OEmbed, error) {
+	if global.ShareSetting == nil || global.ShareSetting.ShareURL == "" || global.ShareSetting.EmbedURL == "" {
+		return nil, errors.New("未配置分享嵌入地址")
+	}
+	u, err := url.Parse(shareURL)
+	if err != nil {
+		return nil, ErrShareNotFound
+	}
+	query := u.Query()
+	u.RawQuery = ""
+	u.Fragment = ""
+	shareId := strings.TrimPrefix(u.String(), global.ShareSetting.ShareURL)
+	if shareId == u.String() || shareId == "" || strings.Contains(shareId, "/") {
+		return nil, ErrShareNotFound
+	}
+	ms := &model.Share{}
+	err = ms.GetShareData(shareId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrShareNotFound
+	} else if err != nil {
+		return nil, errors.New("查询分享数据异常")
+	}
+	if ms.Status != 1 || !ms.CanEmbed() || ms.HasPassword() || checkShareValid(ms) != nil {
+		return nil, ErrShareEmbedDisabled
+	}
+	// 嵌入地址保留主题和历史版本参数
+	src := global.ShareSetting.EmbedURL + ms.Uuid
+	params := url.Values{}
+	if theme == "" {
+		theme = query.Get("theme")
+	}
+	if theme != "" {
+		params.Set("theme", render.EmbedTheme(theme))
+	}
+	if rev := utils.StrTo(query.Get("rev")).MustInt(); rev > 0 {
+		params.Set("rev", strconv.Itoa(rev))
+	}
+	if len(params) > 0 {
+		src += "?" + params.Encode()
+	}
+	width := embedSize(SHARE_EMBED_WIDTH, maxWidth)
+	height := embedSize(SHARE_EMBED_HEIGHT, maxHeight)
+	providerURL := ""
+	if p, err := url.Parse(global.ShareSetting.ShareURL); err == nil {
+		providerURL = p.Scheme + "://" + p.Host
+	}
+	return &OEmbed{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "CardCool",
+		ProviderURL:  providerURL,
+		Title:        ms.Name,
+		Html: `<iframe src="` + html.EscapeString(src) + `" width="` + strconv.Itoa(width) + `" height="` + strconv.Itoa(height) +
+			`" title="` + html.EscapeString(ms.Name) + `" frameborder="0" loading="lazy" sandbox="allow-popups allow-popups-to-escape-sandbox"></iframe>`,
+		Width:    width,
+		Height:   height,
+		CacheAge: OEMBED_CACHE_AGE,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+
+	"cc/be/cache"
+	"cc/be/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrSpaceNotFound = errors.New("空间不存在")
+var ErrSpaceForbidden = errors.New("无权管理该空间的成员")
+var ErrSpaceRole = errors.New("成员角色不正确")
+
+// 获取用户的数据同步范围，包含已加入的共享空间
+func (srv *Service) GetSyncScope(uid int) (*model.SyncScope, error) {
+	scope := model.NewSyncScope(uid)
+	sm := &model.Spacemember{}
+	list, err := sm.GetJoinedMembers(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range *list {
+		scope.AddSpace(m.OwnerUid, m.SpaceId)
+	}
+	return scope, nil
+}
+
+// 获取用户已加入的共享空间: 空间 id -> 成员记录
+func (srv *Service) GetJoinedSpaces(uid int) (map[string]*model.Spacemember, error) {
+	sm := &model.Spacemember{}
+	list, err := sm.GetJoinedMembers(uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*model.Spacemember, len(*list))
+	for i, m := range *list {
+		res[m.SpaceId] = &(*list)[i]
+	}
+	return res, nil
+}
+
+// 刷新空间所有者和所有成员的更新时间缓存，推送更新消息到各自的客户端
+func (srv *Service) NotifySpaceUpdate(ownerUid int, spaceIds []string, t int64) {
+	cache.SetUserUpdateTime(ownerUid, t)
+	if len(spaceIds) <= 0 {
+		return
+	}
+	sm := &model.Spacemember{}
+	uids, err := sm.GetMemberUids(ownerUid, spaceIds)
+	if err != nil {
+		return
+	}
+	for _, uid := range uids {
+		cache.SetUserUpdateTime(uid, t)
+	}
+}
+
+// 获取空间所有者的 uid，用户需为所有者或已加入的成员
+func (srv *Service) getSpaceOwner(uid int, spaceId string) (int, *model.Spacemember, error) {
+	s := &model.Space{}
+	err := s.GetSpaceById(uid, spaceId)
+	if err == nil {
+		return uid, nil, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, err
+	}
+	sm := &model.Spacemember{}
+	err = sm.GetMember(spaceId, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && sm.Status != model.SPACE_MEMBER_JOINED) {
+		return 0, nil, ErrSpaceNotFound
+	} else if err != nil {
+		return 0, nil, err
+	}
+	return sm.OwnerUid, sm, nil
+}
+
+// 获取空间成员列表，所有者和已加入的成员可查看
+func (srv *Service) GetSpaceMembers(uid int, spaceId string) (*[]model.SpacememberUser, int, error) {
+	ownerUid, _, err := srv.getSpaceOwner(uid, spaceId)
+	if err != nil {
+		return nil, 0, err
+	}
+	sm := &model.Spacemember{}
+	list, err := sm.GetSpaceMembers(ownerUid, spaceId)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, ownerUid, nil
+}
+
+// 根据用户编码邀请成员加入空间，仅所有者可邀请
+func (srv *Service) InviteSpaceMember(uid int, spaceId, code string, role int8) (*model.Spacemember, error) {
+	if !model.IsValidSpaceRole(role) {
+		return nil, ErrSpaceRole
+	}
+	s := &model.Space{}
+	err := s.GetSpaceById(uid, spaceId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSpaceForbidden
+	} else if err != nil {
+		return nil, err
+	}
+	u := &model.User{}
+	memberUid := u.GetIdByCode(code)
+	if memberUid <= 0 {
+		return nil, errors.New("邀请的用户不存在")
+	}
+	if memberUid == uid {
+		return nil, errors.New("不能邀请自己")
+	}
+	sm := &model.Spacemember{}
+	err = sm.GetMember(spaceId, memberUid)
+	if err == nil {
+		return nil, errors.New("该用户已是空间成员或已被邀请")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	sm = &model.Spacemember{
+		SpaceId:   spaceId,
+		OwnerUid:  uid,
+		Uid:       memberUid,
+		Role:      role,
+		Status:    model.SPACE_MEMBER_INVITED,
+		InviteUid: uid,
+	}
+	if err := sm.CreateMember(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// 修改成员角色，仅所有者可修改
+func (srv *Service) UpdateSpaceMember(uid int, spaceId string, memberUid int, role int8) error {
+	if !model.IsValidSpaceRole(role) {
+		return ErrSpaceRole
+	}
+	sm := &model.Spacemember{}
+	err := sm.GetMember(spaceId, memberUid)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && sm.OwnerUid != uid) {
+		return ErrSpaceForbidden
+	} else if err != nil {
+		return err
+	}
+	if sm.Role == role {
+		return nil
+	}
+	return sm.UpdateRole(role)
+}
+
+// 移除成员，所有者可移除任一成员，成员可退出空间
+// 退出后客户端需自行清理该空间的本地数据
+func (srv *Service) RemoveSpaceMember(uid int, spaceId string, memberUid int) error {
+	sm := &model.Spacemember{}
+	err := sm.GetMember(spaceId, memberUid)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && sm.OwnerUid != uid && sm.Uid != uid) {
+		return ErrSpaceForbidden
+	} else if err != nil {
+		return err
+	}
+	return sm.DeleteMember()
+}
+
+// 获取待接受的空间邀请
+func (srv *Service) GetSpaceInvites(uid int) (*[]model.SpaceInvite, error) {
+	sm := &model.Spacemember{}
+	return sm.GetInvites(uid)
+}
+
+// 接受空间邀请，加入后客户端需从头拉取数据才能获取空间的历史数据
+func (srv *Service) AcceptSpaceInvite(uid int, spaceId string) error {
+	sm, err := srv.getSpaceInvite(uid, spaceId)
+	if err != nil {
+		return err
+	}
+	if err := sm.Join(); err != nil {
+		return err
+	}
+	// 通知成员的其他客户端同步共享空间
+	cache.SetUserUpdateTime(uid, cache.GetUserUpdateTime(uid)+1)
+	return nil
+}
+
+// 拒绝空间邀请
+func (srv *Service) DeclineSpaceInvite(uid int, spaceId string) error {
+	sm, err := srv.getSpaceInvite(uid, spaceId)
+	if err != nil {
+		return err
+	}
+	return sm.DeleteMember()
+}
+
+func (srv *Service) getSpaceInvite(uid int, spaceId string) (*model.Spacemember, error) {
+	sm := &model.Spacemember{}
+	err := sm.GetMember(spaceId, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && sm.Status != model.SPACE_MEMBER_INVITED) {
+		return nil, errors.New("邀请不存在或已处理")
+	} else if err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"cc/be/cache"
+)
+
+// 限流维度
+const THROTTLE_LOGIN_MOBILE = "login_mobile"
+const THROTTLE_LOGIN_IP = "login_ip"
+const THROTTLE_REGISTER_IP = "register_ip"
+const THROTTLE_SHARE_PASSWORD = "share_password"
+const THROTTLE_LOGIN_TOTP = "login_totp"
+
+// 限流规则：失败次数达到 Threshold 后锁定 Base，之后每次失败锁定时长翻倍，最长 Max
+type throttleRule struct {
+	Threshold int64
+	Base      time.Duration
+	Max       time.Duration
+}
+
+var throttleRules = map[string]throttleRule{
+	THROTTLE_LOGIN_MOBILE: {Threshold: 5, Base: time.Minute, Max: time.Hour},
+	THROTTLE_LOGIN_IP:     {Threshold: 20, Base: time.Minute, Max: time.Hour},
+	THROTTLE_REGISTER_IP:  {Threshold: 10, Base: 5 * time.Minute, Max: 24 * time.Hour},
+	// 分享访问密码按分享和 IP 限流
+	THROTTLE_SHARE_PASSWORD: {Threshold: 10, Base: time.Minute, Max: time.Hour},
+	// 两步验证码按用户限流，跨登录挑战累计，密码登录成功不会清除
+	THROTTLE_LOGIN_TOTP: {Threshold: 10, Base: 5 * time.Minute, Max: 24 * time.Hour},
+}
+
+// 限流对象
+type ThrottleKey struct {
+	Scope string
+	Value string
+}
+
+// 锁定异常，Wait 为剩余锁定时长
+type LockedError struct {
+	Wait time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("尝试次数过多，请 %d 秒后重试", int(math.Ceil(e.Wait.Seconds())))
+}
+
+// 登录和绑定账号按手机号和 IP 限流
+func LoginThrottleKeys(mobile, ip string) []ThrottleKey {
+	return []ThrottleKey{
+		{Scope: THROTTLE_LOGIN_MOBILE, Value: mobile},
+		{Scope: THROTTLE_LOGIN_IP, Value: ip},
+	}
+}
+
+// 注册按 IP 限流，防止枚举邀请码
+func RegisterThrottleKeys(ip string) []ThrottleKey {
+	return []ThrottleKey{
+		{Scope: THROTTLE_REGISTER_IP, Value: ip},
+	}
+}
+
+// 分享访问密码按分享和 IP 组合限流，防止暴力破解
+func ShareThrottleKeys(shareId, ip string) []ThrottleKey {
+	return []ThrottleKey{
+		{Scope: THROTTLE_SHARE_PASSWORD, Value: shareId + ":" + ip},
+	}
+}
+
+// 两步验证按用户限流，防止重复密码登录获取新的挑战后继续猜测验证码
+func TotpThrottleKeys(uid int) []ThrottleKey {
+	return []ThrottleKey{
+		{Scope: THROTTLE_LOGIN_TOTP, Value: strconv.Itoa(uid)},
+	}
+}
+
+// 计算锁定时长
+func lockDuration(rule throttleRule, fails int64) time.Duration {
+	if fails < rule.Threshold {
+		return 0
+	}
+	n := fails - rule.Threshold
+	if n > 16 {
+		return rule.Max
+	}
+	d := rule.Base << uint(n)
+	if d > rule.Max {
+		return rule.Max
+	}
+	return d
+}
+
+// 校验是否处于锁定状态
+func (srv *Service) CheckThrottle(keys []ThrottleKey) error {
+	for _, k := range keys {
+		if wait := cache.GetThrottleLock(k.Scope, k.Value); wait > 0 {
+			return &LockedError{Wait: wait}
+		}
+	}
+	return nil
+}
+
+// 记录失败，达到阈值时锁定
+func (srv *Service) ThrottleFailure(keys []ThrottleKey) {
+	for _, k := range keys {
+		rule, ok := throttleRules[k.Scope]
+		if !ok || k.Value == "" {
+			continue
+		}
+		fails := cache.IncrThrottleFail(k.Scope, k.Value)
+		if d := lockDuration(rule, fails); d > 0 {
+			cache.SetThrottleLock(k.Scope, k.Value, d)
+		}
+	}
+}
+
+// 操作成功后清除手机号维度的失败记录，IP 维度保留以防止跨账号尝试
+func (srv *Service) ThrottleSuccess(keys []ThrottleKey) {
+	for _, k := range keys {
+		if k.Scope == THROTTLE_LOGIN_MOBILE {
+			cache.ClearThrottle(k.Scope, k.Value)
+		}
+	}
+}
+
+// 获取当前锁定列表
+func (srv *Service) GetLockouts() ([]cache.Lockout, error) {
+	return cache.GetLockouts()
+}
+
+// 管理员解除锁定
+func (srv *Service) Unlock(scope, value string) error {
+	if _, ok := throttleRules[scope]; !ok {
+		return fmt.Errorf("限流维度不存在: %s", scope)
+	}
+	cache.ClearThrottle(scope, value)
+	return nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/model"
+	"cc/be/utils"
+)
+
+// 认证器中显示的发行方名称
+const TOTP_ISSUER = "CardCool"
+
+// 每次生成的恢复码数量
+const RECOVERY_CODE_NUM = 10
+
+// 生成恢复码，返回明文和摘要
+func genRecoveryCodes() ([]string, []string) {
+	codes := make([]string, RECOVERY_CODE_NUM)
+	hashes := make([]string, RECOVERY_CODE_NUM)
+	for i := range codes {
+		code := strings.ToLower(utils.SecureRandStr(10))
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.Sha256Hex(code)
+}
+
+// 校验已启用的两步验证码，成功后记录步数防止重放
+func verifyTotpCode(mt *model.Totp, code string) bool {
+	counter, ok := utils.VerifyTotp(mt.Secret, code, time.Now(), mt.LastCounter)
+	if !ok {
+		return false
+	}
+	rows, err := mt.UpdateLastCounter(mt.Id, counter)
+	return err == nil && rows > 0
+}
+
+// 校验两步验证码或恢复码
+func verifySecondFactor(uid int, code string) bool {
+	mt := &model.Totp{}
+	err := mt.SelectByUid(uid)
+	if err != nil || mt.Status != model.TOTP_STATUS_ENABLED {
+		return false
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTP_DIGITS {
+		return verifyTotpCode(mt, code)
+	}
+	mr := &model.Recoverycode{}
+	return mr.UseRecoverycode(uid, hashRecoveryCode(code), int(time.Now().Unix()))
+}
+
+// 判断用户是否需要两步验证
+func (srv *Service) IsTotpEnabled(uid int) bool {
+	mt := &model.Totp{}
+	return mt.IsEnabled(uid)
+}
+
+// 获取当前用户的两步验证状态
+func (srv *Service) GetTotpStatus() (bool, int64) {
+	if !srv.IsTotpEnabled(global.Uid) {
+		return false, 0
+	}
+	mr := &model.Recoverycode{}
+	return true, mr.CountUnused(global.Uid)
+}
+
+// 开始绑定两步验证，返回密钥和扫码 URI
+func (srv *Service) EnrollTotp() (string, string, error) {
+	if srv.IsTotpEnabled(global.Uid) {
+		return "", "", errors.New("已启用两步验证")
+	}
+	u, err := srv.GetUser()
+	if err != nil {
+		return "", "", err
+	}
+	account := u.Mobile
+	if account == "" {
+		account = u.Code
+	}
+	secret := utils.GenerateTotpSecret()
+	mt := &model.Totp{}
+	err = mt.SavePending(global.Uid, secret)
+	if err != nil {
+		log.Printf("保存两步验证密钥异常: %s", err)
+		return "", "", errors.New("保存两步验证密钥失败")
+	}
+	return secret, utils.TotpURI(TOTP_ISSUER, account, secret), nil
+}
+
+// 使用认证器验证码激活两步验证，返回恢复码明文(仅返回一次)
+func (srv *Service) ActivateTotp(code string) ([]string, error) {
+	mt := &model.Totp{}
+	err := mt.SelectByUid(global.Uid)
+	if err != nil {
+		return nil, errors.New("请先绑定认证器")
+	}
+	if mt.Status == model.TOTP_STATUS_ENABLED {
+		return nil, errors.New("已启用两步验证")
+	}
+	counter, ok := utils.VerifyTotp(mt.Secret, code, time.Now(), mt.LastCounter)
+	if !ok {
+		return nil, errors.New("验证码不正确")
+	}
+	codes, hashes := genRecoveryCodes()
+	err = mt.Enable(mt.Id, counter, global.Uid, hashes)
+	if err != nil {
+		log.Printf("启用两步验证异常: %s", err)
+		return nil, errors.New("启用两步验证失败")
+	}
+	return codes, nil
+}
+
+// 关闭两步验证，需要验证码或恢复码
+func (srv *Service) DisableTotp(code string) error {
+	if !verifySecondFactor(global.Uid, code) {
+		return errors.New("验证码不正确")
+	}
+	mt := &model.Totp{}
+	err := mt.DeleteByUid(global.Uid)
+	if err != nil {
+		return errors.New("关闭两步验证失败")
+	}
+	return nil
+}
+
+// 重新生成恢复码，需要认证器验证码
+func (srv *Service) RegenerateRecoveryCodes(code string) ([]string, error) {
+	mt := &model.Totp{}
+	err := mt.SelectByUid(global.Uid)
+	if err != nil || mt.Status != model.TOTP_STATUS_ENABLED {
+		return nil, errors.New("未启用两步验证")
+	}
+	if !verifyTotpCode(mt, strings.TrimSpace(code)) {
+		return nil, errors.New("验证码不正确")
+	}
+	codes, hashes := genRecoveryCodes()
+	mr := &model.Recoverycode{}
+	err = mr.ReplaceRecoverycodes(global.Uid, hashes)
+	if err != nil {
+		return nil, errors.New("生成恢复码失败")
+	}
+	return codes, nil
+}
+
+// 密码校验通过后生成两步验证登录挑战
+func (srv *Service) CreateLoginChallenge(uid int) (string, error) {
+	challenge := utils.SecureRandStr(32)
+	err := cache.SetLoginChallenge(challenge, uid)
+	if err != nil {
+		log.Printf("保存登录挑战异常: %s", err)
+		return "", errors.New("生成登录挑战失败")
+	}
+	return challenge, nil
+}
+
+// 校验登录挑战和两步验证码，返回登录用户
+func (srv *Service) VerifyLoginChallenge(challenge, code string) (*model.User, error) {
+	uid := cache.GetLoginChallenge(challenge)
+	if uid == 0 {
+		return nil, errors.New("登录已过期，请重新登录")
+	}
+	keys := TotpThrottleKeys(uid)
+	if err := srv.CheckThrottle(keys); err != nil {
+		return nil, err
+	}
+	if !verifySecondFactor(uid, code) {
+		srv.ThrottleFailure(keys)
+		if left := cache.IncrLoginChallengeAttempt(challenge); left == 0 {
+			return nil, errors.New("验证失败次数过多，请重新登录")
+		}
+		return nil, errors.New("验证码不正确")
+	}
+	cache.ClearLoginChallenge(challenge)
+	cache.ClearThrottle(THROTTLE_LOGIN_TOTP, strconv.Itoa(uid))
+	u := &model.User{}
+	err := u.SelectById(uid)
+	if err != nil {
+		return nil, errors.New("查询用户账号异常")
+	}
+	return u, nil
+}
+
+// 管理员重置用户的两步验证
+func (srv *Service) ResetTotp(uid int) error {
+	mt := &model.Totp{}
+	err := mt.DeleteByUid(uid)
+	if err != nil {
+		return errors.New("重置两步验证失败")
+	}
+	return nil
+}
@@ -125,9 +125,9 @@ func (srv *Service) UpdateTypes(uid int, types *[]*model.Type, typeIds *[]string
 }
 
 // 获取节点分组列表
-func (srv *Service) GetTypes(uid int, updateTime int64, limit int) (*[]model.Type, error) {
+func (srv *Service) GetTypes(scope *model.SyncScope, updateTime int64, limit int) (*[]model.Type, error) {
 	mt := &model.Type{}
-	list, err := mt.GetTypes(uid, updateTime, limit)
+	list, err := mt.GetTypes(scope, updateTime, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -135,16 +135,19 @@ func (srv *Service) GetTypes(uid int, updateTime int64, limit int) (*[]model.Typ
 		return list, nil
 	}
 	var typeIds []string
+	var uids []int
 	for _, t := range *list {
 		if t.Props == "" || t.Styles == "" {
 			typeIds = append(typeIds, t.Id)
+			uids = append(uids, t.Uid)
 		}
 	}
 	if len(typeIds) <= 0 {
 		return list, nil
 	}
+	// 共享空间的类型可能属于空间的任一成员
 	p := &model.Propext{}
-	propMap, err := p.GetPropexts(uid, &typeIds)
+	propMap, err := p.GetPropextsByUids(uids, &typeIds)
 	if err != nil {
 		return nil, err
 	}
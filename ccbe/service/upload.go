@@ -2,61 +2,330 @@ package service
 
 import (
 	"errors"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"path"
+	"strings"
 	"cc/be/cache"
 	"cc/be/global"
+	"cc/be/imaging"
+	"cc/be/model"
 	"cc/be/resp"
+	"cc/be/storage"
+	"cc/be/validreq"
 	"strconv"
-	"time"
 
-	"github.com/qiniu/go-sdk/v7/auth/qbox"
-	"github.com/qiniu/go-sdk/v7/storage"
+	"gorm.io/gorm"
 )
 
-// 上传资源总额度: 1G = 1024*1024*1024
+// 未配置存储套餐时的默认额度: 1G = 1024*1024*1024
 const SOURCE_LIMIT = 1073741824
 
-// 登录账号
+// 获取文件上传凭证
 func (srv *Service) GetUploadToken() (*resp.UploadToken, error) {
-	// 查询用户信息
-	userinfo, err := srv.GetUserInfo()
+	usage, err := srv.GetStorageUsage(global.Uid)
 	if err != nil {
-		return nil, errors.New("查询用户信息异常")
+		return nil, err
 	}
-	fsize, isOk := (*userinfo)["fsize"]
-	if !isOk {
-		return nil, errors.New("查询用户资源总量异常")
-	}
-	size, _ := strconv.ParseInt(fsize, 10, 64)
-	if size > SOURCE_LIMIT {
-		return nil, errors.New("资源已超出额度")
+	if usage.OverLimit {
+		return nil, ErrQuotaExceeded
 	}
+	p := storage.Default()
 	uidStr := strconv.Itoa(global.Uid)
 	data := cache.GetUploadToken(uidStr)
-	if data.Token == "" {
-		putPolicy := storage.PutPolicy{
-			Scope:            global.QiniuSetting.Bucket,
-			Expires:          uint64(global.QiniuSetting.ExpireTime),
-			SaveKey:          "img/" + uidStr + "/${etag}${ext}",
-			CallbackURL:      "https://i.cardcool.top/api/qiniucallback",
-			CallbackBody:     `{"key":"$(key)","hash":"$(etag)","fsize":$(fsize),"uid":` + uidStr + `}`,
-			CallbackBodyType: "application/json",
-		}
-		mac := qbox.NewMac(global.QiniuSetting.AccessKey, global.QiniuSetting.SecretKey)
-		upToken := putPolicy.UploadToken(mac)
-		if upToken == "" {
-			return nil, errors.New("生成上传token异常")
-		}
-		data.Token = upToken
-		data.ExpireTime = time.Now().Unix() + global.QiniuSetting.ExpireTime - 200
+	// 切换存储方式后缓存的凭证不再可用
+	if data.Token == "" || data.Provider != p.Name() {
+		t, err := p.UploadToken(global.Uid, storage.ExpireTime(global.StorageSetting))
+		if err != nil {
+			return nil, err
+		}
+		data = &resp.UploadToken{
+			Token:      t.Token,
+			ExpireTime: t.ExpireTime - 200,
+			Provider:   p.Name(),
+			UploadURL:  t.UploadURL,
+		}
 		cache.SetUploadToken(uidStr, data)
 	}
 	return data, nil
 }
 
-// 七牛云文件上传回调校验
-// QBox ljccqOeFycTZDqb5N4cLADkvI1E4YJYortghRmS1:Nmm3ICp_Jt_lJt33ttWt6jjdLrU=
+// 七牛云文件上传回调校验，其他存储方式不支持回调
 func (srv *Service) VerifyQiniuCallback(req *http.Request) (bool, error) {
-	mac := qbox.NewMac(global.QiniuSetting.AccessKey, global.QiniuSetting.SecretKey)
-	return qbox.VerifyCallback(mac, req)
+	return storage.Default().VerifyCallback(req)
+}
+
+// 使用上传凭证上传到本地存储，与七牛云直传的流程一致
+func (srv *Service) ReceiveUpload(token, filename string, r io.Reader, size int64) (*resp.UploadFile, error) {
+	p, ok := storage.Default().(*storage.LocalProvider)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	uid, err := p.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return srv.putUpload(uid, filename, r, size)
+}
+
+// 直接上传文件到服务端，写入当前配置的存储并登记
+// size 为请求声明的大小，在读取文件内容前校验额度
+func (srv *Service) UploadFile(filename string, r io.Reader, size int64) (*resp.UploadFile, error) {
+	return srv.putUpload(global.Uid, filename, r, size)
+}
+
+// 写入临时文件计算 etag 后保存到存储，文件路径与七牛云的 SaveKey 一致
+func (srv *Service) putUpload(uid int, filename string, r io.Reader, size int64) (*resp.UploadFile, error) {
+	return srv.storeUpload(uid, filename, r, size, "")
+}
+
+// 保存上传文件，图片去除位置信息并生成缩略图
+// uploaded 为客户端直传的文件路径，内容未修改时直接使用，已修改或与已保存的文件重复时删除
+func (srv *Service) storeUpload(uid int, filename string, r io.Reader, size int64, uploaded string) (*resp.UploadFile, error) {
+	if err := srv.checkUploadQuota(uid, size); err != nil {
+		return nil, err
+	}
+	p := storage.Default()
+	sp, err := storage.Spool(r, p.MaxSize())
+	if errors.Is(err, storage.ErrTooLarge) {
+		return nil, err
+	} else if err != nil {
+		log.Printf("接收上传文件异常 [%d]: %s", uid, err)
+		return nil, errors.New("接收上传文件异常")
+	}
+	defer func() {
+		sp.Close()
+	}()
+	meta, err := storage.Inspect(sp.File, filename)
+	if err != nil {
+		log.Printf("读取上传文件异常 [%d]: %s", uid, err)
+		return nil, errors.New("接收上传文件异常")
+	}
+	img, sp, err := prepareImage(sp, meta, p.MaxSize())
+	if err != nil {
+		log.Printf("处理上传图片异常 [%d]: %s", uid, err)
+		return nil, errors.New("接收上传文件异常")
+	}
+	f := &model.Filelog{
+		Uid:    uid,
+		Hash:   sp.Hash,
+		Key:    existingKey(uid, sp.Hash),
+		Name:   fileName(filename),
+		Mime:   meta.Mime,
+		Width:  meta.Width,
+		Height: meta.Height,
+		Size:   sp.Size,
+	}
+	// 相同内容的文件已保存时不再重复写入，也不再生成缩略图
+	fresh := f.Key == ""
+	if fresh && uploaded != "" && storage.KeyHash(uploaded) == sp.Hash {
+		f.Key = uploaded
+	} else if fresh {
+		f.Key = storage.UserPrefix(uid) + sp.Hash + storage.Ext(filename)
+		if err := p.Put(f.Key, sp.File, sp.Size); err != nil {
+			log.Printf("保存上传文件异常 [%d]: %s", uid, err)
+			return nil, errors.New("保存上传文件异常")
+		}
+	}
+	res, err := saveUploadLog(f)
+	if err != nil {
+		return nil, err
+	}
+	if uploaded != "" {
+		dropDuplicate(uid, uploaded, res.Key)
+	}
+	if img != nil && fresh {
+		if names := putVariants(f.Key, img); len(names) > 0 {
+			b := &model.Fileblob{}
+			if err := b.UpdateVariants(f.Hash, f.Key, strings.Join(names, ",")); err != nil {
+				log.Printf("保存缩略图记录异常 [%d]: %s", uid, err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// 登记客户端直传完成的文件，用于不支持上传回调的存储方式
+// 存储不支持图片处理时由服务端读取图片处理，处理后的文件路径以响应中的 key 为准
+func (srv *Service) CompleteUpload(param *validreq.UploadCompleteReq) (*resp.UploadFile, error) {
+	if !storage.IsUserKey(global.Uid, param.Key) {
+		return nil, errors.New("文件路径不正确")
+	}
+	// 文件名须为内容的 etag，与七牛云的 SaveKey 一致
+	hash := storage.KeyHash(param.Key)
+	if !storage.IsEtag(hash) {
+		return nil, errors.New("文件名须为文件内容的 etag")
+	}
+	p := storage.Default()
+	obj, err := p.Stat(param.Key)
+	if err != nil {
+		return nil, errors.New("查询上传文件异常")
+	}
+	f := &model.Filelog{
+		Uid:    global.Uid,
+		Hash:   hash,
+		Key:    obj.Key,
+		Name:   fileName(param.Name),
+		Mime:   param.Mime,
+		Width:  param.Width,
+		Height: param.Height,
+		Size:   obj.Size,
+	}
+	if f.Mime == "" {
+		f.Mime = obj.Mime
+	}
+	if f.Mime == "" || f.Mime == "application/octet-stream" {
+		f.Mime = mime.TypeByExtension(path.Ext(param.Key))
+	}
+	if _, ok := p.(storage.ImageProcessor); !ok && imaging.Format(f.Mime) != "" && obj.Size <= IMAGE_MAX_SIZE {
+		rc, err := p.Get(param.Key)
+		if err != nil {
+			log.Printf("读取上传文件异常 [%d]: %s", global.Uid, err)
+			return nil, errors.New("读取上传文件异常")
+		}
+		defer rc.Close()
+		filename := param.Name
+		if filename == "" {
+			filename = path.Base(param.Key)
+		}
+		return srv.storeUpload(global.Uid, filename, rc, obj.Size, param.Key)
+	}
+	return saveUploadLog(f)
+}
+
+func newUploadFile(f *model.Filelog) *resp.UploadFile {
+	return &resp.UploadFile{
+		Id:     f.Id,
+		Key:    f.Key,
+		Hash:   f.Hash,
+		Fsize:  f.Size,
+		Uid:    f.Uid,
+		Name:   f.Name,
+		Mime:   f.Mime,
+		Width:  f.Width,
+		Height: f.Height,
+		Url:    storage.Default().DownloadURL(f.Key),
+	}
+}
+
+// 原始文件名，去掉客户端路径并限制长度
+func fileName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if r := []rune(name); len(r) > 100 {
+		name = string(r[len(r)-100:])
+	}
+	return name
+}
+
+// 保存文件上传记录，相同内容的文件只保存一份，每个持有的用户各有一条记录并计入各自的存储用量
+// 同一用户重复上传时增加引用计数，f.Key 为本次上传的路径，与已保存的文件重复时删除
+func saveUploadLog(f *model.Filelog) (*resp.UploadFile, error) {
+	old := &model.Filelog{}
+	err := old.GetByHash(f.Uid, f.Hash)
+	if err == nil {
+		if err := old.AddRef(f); err != nil {
+			return nil, errors.New("保存文件上传记录失败")
+		}
+		dropDuplicate(f.Uid, f.Key, old.Key)
+		return newUploadFile(old), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("查询文件上传记录失败")
+	}
+	blob, err := acquireBlob(f.Hash, f.Key, f.Size)
+	if err != nil {
+		log.Printf("保存文件内容记录异常 [%d]: %s", f.Uid, err)
+		return nil, errors.New("保存文件上传记录失败")
+	}
+	dropDuplicate(f.Uid, f.Key, blob.Key)
+	f.Key = blob.Key
+	f.RefCount = 1
+	if err := f.CreateWithBlob(blob.Id); errors.Is(err, model.ErrBlobReleased) {
+		return nil, errors.New("文件已被删除，请重新上传")
+	} else if err != nil {
+		return nil, errors.New("保存文件上传记录失败")
+	}
+	// 更新用户信息
+	cache.UpdateUserFsize(f.Uid, f.Size)
+	return newUploadFile(f), nil
+}
+
+// 释放一次文件引用，引用计数为 0 时删除用户的文件记录，释放存储空间
+// 文件名为内容的 etag，根据文件名查询对应的记录，相同内容的文件可能保存在其他用户的目录下
+func (srv *Service) DeleteFile(key string) error {
+	f := &model.Filelog{}
+	err := f.GetByHash(global.Uid, storage.KeyHash(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 没有登记的文件只能删除自己目录下的
+		if !storage.IsUserKey(global.Uid, key) {
+			return errors.New("文件路径不正确")
+		}
+		dropDuplicate(global.Uid, key, "")
+		return nil
+	} else if err != nil {
+		return errors.New("查询文件上传记录失败")
+	}
+	if f.RefCount > 1 {
+		if err := f.ReleaseRef(); err != nil {
+			return errors.New("删除文件上传记录失败")
+		}
+		return nil
+	}
+	if _, _, err := releaseFile(f, 0); err != nil {
+		log.Printf("删除文件异常 [%d]: %s", global.Uid, err)
+		return errors.New("删除文件异常")
+	}
+	cache.ClearUserInfo(global.Uid)
+	return nil
+}
+
+func deleteObjects(uid int, keys ...string) error {
+	for _, key := range keys {
+		if err := storage.Default().Delete(key); err != nil {
+			log.Printf("删除文件异常 [%d]: %s", uid, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// 释放用户持有的全部文件并删除用户目录，返回删除的文件数
+// 用户目录下的文件仍被其他用户持有时保留目录
+func deleteUserObjects(uid int) (int, error) {
+	f := &model.Filelog{}
+	total := 0
+	lastId := 0
+	for {
+		list, err := f.GetFilelogs(uid, lastId, FILE_GC_BATCH)
+		if err != nil {
+			return total, err
+		}
+		for i := range *list {
+			_, n, err := releaseFile(&(*list)[i], 0)
+			if err != nil {
+				return total, err
+			}
+			total += n
+		}
+		if len(*list) < FILE_GC_BATCH {
+			break
+		}
+		lastId = (*list)[len(*list)-1].Id
+	}
+	b := &model.Fileblob{}
+	shared, err := b.CountByPrefix(storage.UserPrefix(uid))
+	if err != nil {
+		return total, err
+	}
+	if shared > 0 {
+		log.Printf("用户目录下有 %d 个文件被其他用户持有，保留目录 [%d]", shared, uid)
+		return total, nil
+	}
+	n, err := storage.Default().DeletePrefix(storage.UserPrefix(uid))
+	return total + n, err
 }
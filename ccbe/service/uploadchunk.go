@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"cc/be/cache"
+	"cc/be/global"
+	"cc/be/resp"
+	"cc/be/storage"
+	"cc/be/utils"
+)
+
+// 分片大小: 4M，与 etag 的分块大小一致
+const UPLOAD_CHUNK_SIZE = 4194304
+
+var ErrUploadNotFound = errors.New("上传任务不存在或已过期")
+
+// 分片保存目录
+func chunkDir(uid int, uploadId string) string {
+	return filepath.Join(storage.TempDir(), "chunks", strconv.Itoa(uid)+"-"+uploadId)
+}
+
+func chunkPath(uid int, uploadId string, index int) string {
+	return filepath.Join(chunkDir(uid, uploadId), strconv.Itoa(index))
+}
+
+// 创建分片上传任务，创建时校验文件大小和上传额度
+func (srv *Service) InitChunkUpload(filename string, size int64) (*resp.UploadSession, error) {
+	if err := srv.checkUploadQuota(global.Uid, size); err != nil {
+		return nil, err
+	}
+	cleanChunkDirs()
+	s := &resp.UploadSession{
+		UploadId:   utils.SecureRandStr(24),
+		Filename:   filename,
+		Size:       size,
+		ChunkSize:  UPLOAD_CHUNK_SIZE,
+		Chunks:     int((size + UPLOAD_CHUNK_SIZE - 1) / UPLOAD_CHUNK_SIZE),
+		ExpireTime: time.Now().Add(cache.UPLOAD_SESSION_EXPIRE).Unix(),
+		Uploaded:   []int{},
+	}
+	if err := os.MkdirAll(chunkDir(global.Uid, s.UploadId), 0755); err != nil {
+		log.Printf("创建分片目录异常: %s", err)
+		return nil, errors.New("创建上传任务失败")
+	}
+	if err := cache.SetUploadSession(global.Uid, s); err != nil {
+		return nil, errors.New("创建上传任务失败")
+	}
+	return s, nil
+}
+
+// 查询分片上传任务，用于断点续传
+func (srv *Service) GetChunkUpload(uploadId string) (*resp.UploadSession, error) {
+	s := cache.GetUploadSession(global.Uid, uploadId)
+	if s == nil {
+		return nil, ErrUploadNotFound
+	}
+	s.Uploaded = cache.GetUploadChunks(global.Uid, uploadId)
+	return s, nil
+}
+
+// 上传分片，除最后一片外大小须为 ChunkSize，重复上传同一分片时覆盖
+func (srv *Service) UploadChunk(uploadId string, index int, r io.Reader) error {
+	s := cache.GetUploadSession(global.Uid, uploadId)
+	if s == nil {
+		return ErrUploadNotFound
+	}
+	if index < 0 || index >= s.Chunks {
+		return errors.New("分片序号不正确")
+	}
+	expect := s.ChunkSize
+	if index == s.Chunks-1 {
+		expect = s.Size - s.ChunkSize*int64(index)
+	}
+	dir := chunkDir(global.Uid, uploadId)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".chunk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, io.LimitReader(r, expect+1))
+	tmp.Close()
+	if err != nil {
+		return errors.New("接收分片异常")
+	}
+	if n != expect {
+		return errors.New("分片大小不正确")
+	}
+	if err := os.Rename(tmp.Name(), chunkPath(global.Uid, uploadId, index)); err != nil {
+		return err
+	}
+	return cache.AddUploadChunk(global.Uid, uploadId, index)
+}
+
+// 合并全部分片并保存文件，完成后删除上传任务
+func (srv *Service) CompleteChunkUpload(uploadId string) (*resp.UploadFile, error) {
+	s := cache.GetUploadSession(global.Uid, uploadId)
+	if s == nil {
+		return nil, ErrUploadNotFound
+	}
+	if uploaded := cache.GetUploadChunks(global.Uid, uploadId); len(uploaded) != s.Chunks {
+		return nil, errors.New("还有 " + strconv.Itoa(s.Chunks-len(uploaded)) + " 个分片未上传")
+	}
+	readers := make([]io.Reader, 0, s.Chunks)
+	for i := 0; i < s.Chunks; i++ {
+		f, err := os.Open(chunkPath(global.Uid, uploadId, i))
+		if err != nil {
+			return nil, errors.New("分片文件不存在，请重新上传")
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	res, err := srv.putUpload(global.Uid, s.Filename, io.MultiReader(readers...), s.Size)
+	if err != nil {
+		return nil, err
+	}
+	cache.DelUploadSession(global.Uid, uploadId)
+	os.RemoveAll(chunkDir(global.Uid, uploadId))
+	return res, nil
+}
+
+// 取消分片上传任务
+func (srv *Service) AbortChunkUpload(uploadId string) error {
+	if cache.GetUploadSession(global.Uid, uploadId) == nil {
+		return ErrUploadNotFound
+	}
+	cache.DelUploadSession(global.Uid, uploadId)
+	return os.RemoveAll(chunkDir(global.Uid, uploadId))
+}
+
+// 清理已过期任务遗留的分片目录
+func cleanChunkDirs() {
+	root := filepath.Join(storage.TempDir(), "chunks")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	expire := time.Now().Add(-cache.UPLOAD_SESSION_EXPIRE)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && info.ModTime().Before(expire) {
+			os.RemoveAll(filepath.Join(root, e.Name()))
+		}
+	}
+}
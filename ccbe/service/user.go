@@ -11,13 +11,11 @@ import (
 
 	"cc/be/cache"
 	"cc/be/global"
+	"cc/be/idp"
 	"cc/be/model"
+	"cc/be/resp"
 	"cc/be/utils"
 	"cc/be/validreq"
-
-	mpoauth2 "github.com/chanxuehong/wechat/mp/oauth2"
-	// "github.com/chanxuehong/wechat/oauth2"
-	// "gorm.io/gorm"
 )
 
 const defaultPassword = "qMY0SIQoKOtZW6vA"
@@ -68,12 +66,15 @@ func (srv *Service) GetUserInfo() (*map[string]string, error) {
 	// 查询用户上传文件大小
 	f := &model.Filelog{}
 	fsize := f.SumSize(user.Id)
+	// 查询关联的微信身份
+	mi := &model.Identity{}
+	openid := mi.GetSubject(user.Id, idp.PROVIDER_WECHAT)
 	// 缓存用户信息
 	info := &map[string]string{
 		"username": user.Username,
 		"avatar":   user.Avatar,
 		"mobile":   user.Mobile,
-		"openid":   user.Openid,
+		"openid":   openid,
 		"config":   user.Config,
 		"code":     user.Code,
 		"fsize":    strconv.FormatInt(fsize, 10),
@@ -102,120 +103,6 @@ func (srv *Service) Login(param *validreq.LoginReq) (*model.User, error) {
 	return u, nil
 }
 
-// 根据微信扫码授权回调绑定原手机账号
-func (srv *Service) BindUser(param *validreq.BindUserReq) (*model.User, error) {
-	u := &model.User{}
-	err := u.SelectByMobile(param.Mobile)
-	if err != nil {
-		log.Printf("查询用户账号出现异常: %s", err)
-		return nil, errors.New("查询用户账号异常")
-	}
-	// 判断账号密码
-	if u.Password != genPassword(param.Password) {
-		return nil, errors.New("账号密码不正确")
-	}
-	// 判断账号状态
-	if u.Status != 0 {
-		return nil, errors.New("账号状态异常")
-	}
-	if u.ExistByOpenid(param.Openid) {
-		return nil, errors.New("该微信账号已绑定其他账号")
-	}
-	// 绑定微信 openid
-	if u.Username == "未命名" {
-		u.Username = param.Username
-	}
-	if u.Avatar == "/cc/avatar.png" {
-		u.Avatar = param.Avatar
-	}
-	err = u.UpdateUserBind(u.Id, param.Openid, param.Unionid, u.Username, u.Avatar)
-	if err != nil {
-		log.Printf("更新用户绑定出现异常: %s", err)
-		return nil, errors.New("更新用户绑定异常")
-	}
-	return u, nil
-}
-
-// 微信扫码登录回调
-func (srv *Service) Callback(param *validreq.CallbackReq) (*model.User, error) {
-	return nil, errors.New("暂不支持微信扫码登录回调")
-	// // 调用微信接口，根据 code 获取用户信息
-	// oauth2Endpoint := mpoauth2.NewEndpoint(global.WechatOpenSetting.AppId, global.WechatOpenSetting.AppSecret)
-	// oauth2Client := oauth2.Client{
-	// 	Endpoint: oauth2Endpoint,
-	// }
-	// token, err := oauth2Client.ExchangeToken(param.Code)
-	// log.Printf("换取 token [%s]: %+v\r\n", param.Code, token)
-	// if err != nil {
-	// 	log.Printf("换取token异常: %s", err)
-	// 	return nil, errors.New("换取token异常")
-	// }
-	// if token.OpenId == "" {
-	// 	return nil, errors.New("获取OpenID异常")
-	// }
-	// u := &model.User{}
-	// err = u.SelectByOpenId(token.OpenId)
-	// if err != nil {
-	// 	// 根据 openid 没有找到用户，则注册账号
-	// 	if errors.Is(err, gorm.ErrRecordNotFound) {
-	// 		// 调用接口获取用户信息
-	// 		userinfo, _ := mpoauth2.GetUserInfo(token.AccessToken, token.OpenId, "", nil)
-	// 		log.Printf("根据 openid 未查询到用户，注册新账号 %+v\r\n", userinfo)
-	// 		u, err = srv.registerByCode(userinfo, token.OpenId, token.UnionId, param.InviteCode)
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		// 初始化用户数据: 默认空间
-	// 		ti := time.Now().UnixMilli()
-	// 		sid, err := srv.InitSpace(u.Id, ti)
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		ts, err := srv.InitType(u.Id, ti+1)
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		cs, err := srv.InitCard(u.Id, ti+10, sid, ts)
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		_, err = srv.InitView(u.Id, ti+20, sid, (*cs)[1])
-	// 		if err != nil {
-	// 			return nil, err
-	// 		}
-	// 		// 刷新更新时间缓存
-	// 		cache.SetUserUpdateTime(u.Id, ti+100)
-	// 	} else {
-	// 		log.Printf("查询用户信息出现异常: %s", err)
-	// 		return nil, errors.New("查询用户账号异常")
-	// 	}
-	// } else {
-	// 	// 判断账号状态
-	// 	if u.Status != 0 {
-	// 		return nil, errors.New("账号状态异常")
-	// 	}
-	// }
-	// return u, nil
-}
-
-// 根据微信用户信息注册账号
-func (srv *Service) registerByCode(userinfo *mpoauth2.UserInfo, openId, unionId, inviteCode string) (*model.User, error) {
-	u := &model.User{}
-	// 校验邀请码
-	pid := 0
-	if inviteCode != "" {
-		u1 := &model.User{}
-		pid = u1.GetIdByCode(inviteCode)
-	}
-	dbpsw := genPassword("cardcool" + utils.RandStrBySeed(12))
-	code := genCode()
-	err := u.InsertWechatUser(openId, unionId, userinfo.Nickname, userinfo.HeadImageURL, dbpsw, code, pid)
-	if err != nil {
-		return nil, errors.New("创建账号失败")
-	}
-	return u, nil
-}
-
 // 注册账号
 func (srv *Service) Register(param *validreq.RegisterReq) (*model.User, error) {
 	u := &model.User{}
@@ -224,96 +111,53 @@ func (srv *Service) Register(param *validreq.RegisterReq) (*model.User, error) {
 		return nil, errors.New("该手机号已注册账号")
 	}
 	// 校验邀请码
-	pid := u.GetIdByCode(param.Code)
-	useInviteId := 0
-	im := &model.Invite{}
-	if pid == 0 {
-		err := im.GetByCode(param.Code)
-		if err == nil {
-			t := int(time.Now().Unix())
-			if im.Status == -1 {
-				return nil, errors.New("该邀请码已失效")
-			} else if im.Status == 1 {
-				return nil, errors.New("该邀请码已被使用")
-			} else if im.StartTime > t {
-				return nil, errors.New("该邀请码暂无法使用")
-			} else if im.EndTime != 0 && im.EndTime < t {
-				return nil, errors.New("该邀请码已过期")
-			} else {
-				pid = im.CreateUid
-				if im.LimitType == 1 {
-					useInviteId = im.Id
-				}
-			}
-		}
-	} else {
-		u = &model.User{}
+	pid, im, err := checkInviteCode(param.Code)
+	if err != nil {
+		return nil, err
 	}
-	if pid == 0 {
-		return nil, errors.New("该邀请码无效")
+	// 校验验证码
+	err = srv.CheckVerifyCode(VERIFY_REGISTER, param.Mobile, param.AuthCode)
+	if err != nil {
+		return nil, err
 	}
-	// 校验验证码 TODO
 	psw := genPassword(param.Password)
 	dbpsw := genPassword(psw + utils.RandStrBySeed(12))
 	code := genCode()
-	err := u.InsertUser(param.Mobile, psw, dbpsw, code, pid)
-	if err != nil {
+	err = u.InsertUser(param.Mobile, psw, dbpsw, code, pid, im)
+	if errors.Is(err, model.ErrInviteUsedUp) {
+		return nil, errors.New("该邀请码已被使用")
+	} else if err != nil {
 		return nil, errors.New("创建账号失败")
 	}
-	// 核销一次性邀请码
-	if useInviteId > 0 {
-		im.UpdateInviteStatus()
-	}
 	return u, nil
 }
 
-// 注册账号
-func (srv *Service) RegisterByCode(param *validreq.RegcodeReq) (*model.User, error) {
+// 校验邀请码，返回邀请者 uid，使用邀请码表中的邀请码时同时返回邀请码记录用于核销
+func checkInviteCode(inviteCode string) (int, *model.Invite, error) {
 	u := &model.User{}
-	// 校验 openid
-	if u.ExistByOpenid(param.Openid) {
-		return nil, errors.New("已存在账号，请稍后重试")
+	pid := u.GetIdByCode(inviteCode)
+	if pid > 0 {
+		return pid, nil, nil
 	}
-	// 校验邀请码
-	pid := u.GetIdByCode(param.Code)
-	useInviteId := 0
 	im := &model.Invite{}
-	if pid == 0 {
-		err := im.GetByCode(param.Code)
-		if err == nil {
-			t := int(time.Now().Unix())
-			if im.Status == -1 {
-				return nil, errors.New("该邀请码已失效")
-			} else if im.Status == 1 {
-				return nil, errors.New("该邀请码已被使用")
-			} else if im.StartTime > t {
-				return nil, errors.New("该邀请码暂无法使用")
-			} else if im.EndTime != 0 && im.EndTime < t {
-				return nil, errors.New("该邀请码已过期")
-			} else {
-				pid = im.CreateUid
-				if im.LimitType == 1 {
-					useInviteId = im.Id
-				}
-			}
-		}
-	} else {
-		u = &model.User{}
-	}
-	if pid == 0 {
-		return nil, errors.New("该邀请码无效")
-	}
-	dbpsw := genPassword("cardcool" + utils.RandStrBySeed(12))
-	code := genCode()
-	err := u.InsertWechatUser(param.Openid, param.Unionid, param.Username, param.Avatar, dbpsw, code, pid)
+	err := im.GetByCode(inviteCode)
 	if err != nil {
-		return nil, errors.New("创建账号失败")
-	}
-	// 核销一次性邀请码
-	if useInviteId > 0 {
-		im.UpdateInviteStatus()
-	}
-	return u, nil
+		return 0, nil, errors.New("该邀请码无效")
+	}
+	t := int(time.Now().Unix())
+	if im.Status == model.INVITE_STATUS_REVOKED {
+		return 0, nil, errors.New("该邀请码已失效")
+	} else if im.Status == model.INVITE_STATUS_USED || (im.UseLimit() > 0 && im.UsedCount >= im.UseLimit()) {
+		return 0, nil, errors.New("该邀请码已被使用")
+	} else if im.StartTime > t {
+		return 0, nil, errors.New("该邀请码暂无法使用")
+	} else if im.EndTime != 0 && im.EndTime < t {
+		return 0, nil, errors.New("该邀请码已过期")
+	}
+	if im.CreateUid == 0 {
+		return 0, nil, errors.New("该邀请码无效")
+	}
+	return im.CreateUid, im, nil
 }
 
 func (srv *Service) AddAccount(mobile string) (*model.User, error) {
@@ -330,52 +174,13 @@ func (srv *Service) addUser(mobile string, pid int) (*model.User, error) {
 	psw := genPassword(defaultPassword)
 	dbpsw := genPassword(psw + utils.RandStrBySeed(12))
 	code := genCode()
-	err := u.InsertUser(mobile, psw, dbpsw, code, pid)
+	err := u.InsertUser(mobile, psw, dbpsw, code, pid, nil)
 	if err != nil {
 		return nil, errors.New("创建账号失败")
 	}
 	return u, nil
 }
 
-// 绑定微信
-func (srv *Service) BindWechat(param *validreq.BindWechatReq) (string, error) {
-	return "", errors.New("暂不支持微信扫码")
-	// // 调用微信接口，根据 code 获取用户信息
-	// oauth2Endpoint := mpoauth2.NewEndpoint(global.WechatOpenSetting.AppId, global.WechatOpenSetting.AppSecret)
-	// oauth2Client := oauth2.Client{
-	// 	Endpoint: oauth2Endpoint,
-	// }
-	// token, err := oauth2Client.ExchangeToken(param.Code)
-	// log.Printf("换取 token [%s]: %+v\r\n", param.Code, token)
-	// if err != nil {
-	// 	log.Printf("换取token异常: %s", err)
-	// 	return "", errors.New("换取token异常")
-	// }
-	// if token.OpenId == "" {
-	// 	return "", errors.New("获取OpenID异常")
-	// }
-	// u := &model.User{}
-	// err = u.SelectByOpenId(token.OpenId)
-	// if err != nil {
-	// 	// 根据 openid 没有找到用户，则绑定账号
-	// 	if errors.Is(err, gorm.ErrRecordNotFound) {
-	// 		u2 := &model.User{}
-	// 		err := u2.BindWechat(global.Uid, token.OpenId, token.UnionId)
-	// 		if err != nil {
-	// 			return "", errors.New("更新 openid 失败")
-	// 		}
-	// 	} else {
-	// 		log.Printf("查询用户信息出现异常: %s", err)
-	// 		return "", errors.New("查询用户账号异常")
-	// 	}
-	// } else {
-	// 	return "", errors.New("该微信号已绑定其他账号")
-	// }
-	// // 清除缓存信息
-	// cache.ClearUserInfo(global.Uid)
-	// return token.OpenId, nil
-}
-
 // 修改账号
 func (srv *Service) UpdateMobileAccount(param *validreq.UpdateMobileAccountReq) error {
 	u := &model.User{}
@@ -421,6 +226,11 @@ func (srv *Service) UpdateMobileAccount(param *validreq.UpdateMobileAccountReq)
 		if err == nil {
 			return errors.New("手机号已被注册，请使用其他手机号")
 		}
+		// 校验新手机号的验证码
+		err = srv.CheckVerifyCode(VERIFY_CHANGE_MOBILE, param.Mobile, param.AuthCode)
+		if err != nil {
+			return err
+		}
 	}
 	err = u.UpdateMobileAccount(global.Uid, param.Mobile, password)
 	if err != nil {
@@ -498,6 +308,11 @@ func (srv *Service) Change(param *validreq.ChangeReq) (*model.User, error) {
 		if err == nil {
 			return nil, errors.New("新手机号已被注册，请使用其他手机号")
 		}
+		// 校验新手机号的验证码
+		err = srv.CheckVerifyCode(VERIFY_CHANGE_MOBILE, param.Mobile, param.AuthCode)
+		if err != nil {
+			return nil, err
+		}
 	}
 	password := ""
 	if newPassword := genPassword(param.Password); param.Password != "" && newPassword != u.Password {
@@ -510,27 +325,17 @@ func (srv *Service) Change(param *validreq.ChangeReq) (*model.User, error) {
 	return u, nil
 }
 
-// 七牛云文件上传回调
-func (srv *Service) QiniuCallback(param *validreq.QiniuCallbackReq) error {
+// 七牛云文件上传回调，相同内容的文件已保存时返回已保存的路径
+func (srv *Service) QiniuCallback(param *validreq.QiniuCallbackReq) (*resp.UploadFile, error) {
 	if param.Uid == 0 {
-		return errors.New("七牛回调uid异常")
+		return nil, errors.New("七牛回调uid异常")
 	}
-	// 保存文件上传记录
-	f := &model.Filelog{
+	return saveUploadLog(&model.Filelog{
 		Uid:  param.Uid,
 		Hash: param.Hash,
+		Key:  param.Key,
+		Name: fileName(param.Fname),
+		Mime: param.Mime,
 		Size: param.Fsize,
-	}
-	// 判断记录是否已经存在
-	isExist := f.ExistByHash(param.Hash)
-	if isExist {
-		return nil
-	}
-	err := f.CreateFilelog()
-	if err != nil {
-		return errors.New("保存文件上传记录失败")
-	}
-	// 更新用户信息
-	cache.UpdateUserFsize(param.Uid, param.Fsize)
-	return nil
+	})
 }
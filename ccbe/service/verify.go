@@ -0,0 +1,149 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"cc/be/cache"
+	"cc/be/model"
+	"cc/be/sender"
+	"cc/be/utils"
+	"cc/be/validreq"
+)
+
+// 验证码用途
+const VERIFY_REGISTER = "register"
+const VERIFY_RESET_PASSWORD = "reset_password"
+const VERIFY_CHANGE_MOBILE = "change_mobile"
+
+// 每日发送上限
+const VERIFY_DAILY_TARGET_LIMIT = 10
+const VERIFY_DAILY_IP_LIMIT = 50
+
+var verifyPurposeNames = map[string]string{
+	VERIFY_REGISTER:       "注册账号",
+	VERIFY_RESET_PASSWORD: "重置密码",
+	VERIFY_CHANGE_MOBILE:  "修改手机号",
+}
+
+var mobileRegexp = regexp.MustCompile(`^1\d{10}$`)
+
+// 判断是否为手机号
+func IsMobile(s string) bool {
+	return mobileRegexp.MatchString(s)
+}
+
+func genVerifyCode() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// 发送验证码，接收方为手机号时发送短信，否则发送邮件
+func (srv *Service) SendVerifyCode(purpose, target, ip string) error {
+	name, ok := verifyPurposeNames[purpose]
+	if !ok {
+		return errors.New("验证码用途异常")
+	}
+	isMobile := IsMobile(target)
+	if !isMobile && !strings.Contains(target, "@") {
+		return errors.New("请输入正确的手机号或邮箱")
+	}
+	if !cache.LockVerifySend(target) {
+		return errors.New("发送过于频繁，请稍后重试")
+	}
+	if cache.IncrVerifyDaily(target) > VERIFY_DAILY_TARGET_LIMIT || cache.IncrVerifyDaily(ip) > VERIFY_DAILY_IP_LIMIT {
+		return errors.New("今日发送次数已达上限")
+	}
+	code := genVerifyCode()
+	err := cache.SetVerifyCode(purpose, target, utils.Sha256Hex(code))
+	if err != nil {
+		log.Printf("保存验证码异常: %s", err)
+		return errors.New("发送验证码失败")
+	}
+	content := fmt.Sprintf("您正在%s，验证码 %s，%d 分钟内有效，请勿泄露给他人。", name, code, int(cache.VERIFY_CODE_EXPIRE.Minutes()))
+	if isMobile {
+		err = sender.Sms().SendSms(target, content)
+	} else {
+		err = sender.Email().SendEmail(target, "验证码", content)
+	}
+	if err != nil {
+		log.Printf("发送验证码异常 [%s]: %s", target, err)
+		cache.ClearVerifyCode(purpose, target)
+		return errors.New("发送验证码失败")
+	}
+	return nil
+}
+
+// 校验验证码，校验成功后验证码失效
+func (srv *Service) CheckVerifyCode(purpose, target, code string) error {
+	hash := cache.GetVerifyCode(purpose, target)
+	if hash == "" {
+		return errors.New("验证码已失效，请重新获取")
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(utils.Sha256Hex(strings.TrimSpace(code)))) != 1 {
+		if left := cache.IncrVerifyAttempt(purpose, target); left == 0 {
+			return errors.New("验证码错误次数过多，请重新获取")
+		}
+		return errors.New("验证码不正确")
+	}
+	cache.ClearVerifyCode(purpose, target)
+	return nil
+}
+
+// 未登录时获取注册或重置密码的验证码
+func (srv *Service) SendPublicCode(param *validreq.SendCodeReq, ip string) error {
+	u := &model.User{}
+	switch param.Purpose {
+	case VERIFY_REGISTER:
+		if u.ExistByMobile(param.Mobile) {
+			return errors.New("该手机号已注册账号")
+		}
+	case VERIFY_RESET_PASSWORD:
+		// 未注册的手机号不发送，但同样返回成功，避免通过该接口查询手机号是否注册
+		if !u.ExistByMobile(param.Mobile) {
+			return nil
+		}
+	default:
+		return errors.New("验证码用途异常")
+	}
+	return srv.SendVerifyCode(param.Purpose, param.Mobile, ip)
+}
+
+// 通过短信验证码重置密码
+func (srv *Service) ResetPassword(param *validreq.ResetPasswordReq) error {
+	err := srv.CheckVerifyCode(VERIFY_RESET_PASSWORD, param.Mobile, param.AuthCode)
+	if err != nil {
+		return err
+	}
+	u := &model.User{}
+	err = u.SelectByMobile(param.Mobile)
+	if err != nil {
+		return errors.New("该手机号未注册账号")
+	}
+	err = u.UpdatePassword(u.Id, genPassword(param.Password))
+	if err != nil {
+		return errors.New("重置密码失败")
+	}
+	// 重置密码后解除该手机号的登录锁定
+	cache.ClearThrottle(THROTTLE_LOGIN_MOBILE, param.Mobile)
+	cache.ClearUserInfo(u.Id)
+	return nil
+}
+
+// 已登录用户获取修改手机号的验证码
+func (srv *Service) SendChangeMobileCode(mobile, ip string) error {
+	u := &model.User{}
+	if u.ExistByMobile(mobile) {
+		return errors.New("手机号已被注册，请使用其他手机号")
+	}
+	return srv.SendVerifyCode(VERIFY_CHANGE_MOBILE, mobile, ip)
+}
@@ -117,11 +117,11 @@ func (srv *Service) UpdateViews(uid int, views *[]*model.View, viewIds *[]string
 }
 
 // 获取分组列表
-func (srv *Service) GetViews(uid int, updateTime int64, limit int) (*[]model.View, *map[string]string, error) {
+func (srv *Service) GetViews(scope *model.SyncScope, updateTime int64, limit int) (*[]model.View, *map[string]string, error) {
 	mv := &model.View{}
 	// var contentMap = make(map[string]string)
 	contentMap := &map[string]string{}
-	list, err := mv.GetViews(uid, updateTime, limit)
+	list, err := mv.GetViews(scope, updateTime, limit)
 	if err != nil {
 		return nil, contentMap, err
 	}
@@ -138,7 +138,7 @@ func (srv *Service) GetViews(uid int, updateTime int64, limit int) (*[]model.Vie
 		return list, contentMap, nil
 	}
 	p := &model.Propext{}
-	propMap, err := p.GetPropexts(uid, &viewIds)
+	propMap, err := p.GetPropextsByUids(scope.Uids(), &viewIds)
 	if err != nil {
 		return nil, contentMap, err
 	}
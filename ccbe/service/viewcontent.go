@@ -0,0 +1,756 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"cc/be/model"
+	"cc/be/render"
+
+	"gorm.io/gorm"
+)
+
+// 服务端组装视图内容，结构与前端 getViewContent 生成的分享内容一致
+// 列表视图: {cards}，白板视图: {nodes, edges, cards}
+
+// 列表视图最多查询的卡片数，与前端 QUERY_CARD_CNT 一致
+const VIEW_QUERY_CARD_CNT = 100
+
+// 白板节点关联对象类型 viewnode.node_type
+const (
+	VN_NODE_TEXT  = 0
+	VN_NODE_CARD  = 1
+	VN_NODE_VIEW  = 2
+	VN_NODE_GROUP = 3
+)
+
+// 白板节点类型 viewnode.vn_type_id，mindGroup 之后为兼容的老节点类型
+const (
+	VN_TYPE_CARD      = "card"
+	VN_TYPE_VIEW      = "view"
+	VN_TYPE_TEXT      = "text"
+	VN_TYPE_SHAPE     = "shape"
+	VN_TYPE_MGROUP    = "mgroup"
+	VN_TYPE_IGROUP    = "igroup"
+	VN_TYPE_MINDGROUP = "mindGroup"
+	VN_TYPE_MINDROOT  = "mindRoot"
+	VN_TYPE_MINDSUB   = "mindSub"
+	VN_TYPE_MINDNODE  = "mindNode"
+	VN_TYPE_MIND      = "mind"
+	VN_TYPE_SQUARE    = "square"
+	VN_TYPE_CIRCLE    = "circle"
+	VN_TYPE_TRIANGLE  = "triangle"
+)
+
+// 导图根节点 pid、导图节点默认宽度和节点样式
+const MIND_ROOT_PID = "root"
+const VN_NODE_WIDTH = 280
+const VN_STYLE_FULL = "full"
+const VN_STYLE_FOLD = "fold"
+
+var ErrViewNotFound = errors.New("视图不存在或已删除")
+var ErrCardNotFound = errors.New("卡片不存在或已删除")
+
+// 卡片属性及属性值，text 为卡片分享中属性值的展示文本
+type ViewCardProp struct {
+	Id      string          `json:"id"`
+	Name    string          `json:"name"`
+	Type    string          `json:"type"`
+	Handles json.RawMessage `json:"handles,omitempty"`
+	Show    json.RawMessage `json:"show,omitempty"`
+	Options json.RawMessage `json:"options,omitempty"`
+	Val     interface{}     `json:"val"`
+	Text    string          `json:"text,omitempty"`
+}
+
+// 视图内容中的卡片
+type ViewCard struct {
+	Id         string                 `json:"id"`
+	SpaceId    string                 `json:"space_id"`
+	TypeId     string                 `json:"type_id"`
+	Name       string                 `json:"name"`
+	Icon       string                 `json:"icon"`
+	Tags       []string               `json:"tags"`
+	PropsObj   map[string]interface{} `json:"propsObj,omitempty"`
+	Props      []*ViewCardProp        `json:"props"`
+	Content    json.RawMessage        `json:"content"`
+	CreateTime int                    `json:"create_time"`
+	UpdateTime int64                  `json:"update_time"`
+	CreateDate string                 `json:"create_date"`
+	UpdateDate string                 `json:"update_date"`
+}
+
+// 白板节点引用的视图
+type ViewInfo struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	SpaceId    string `json:"space_id"`
+	Pid        string `json:"pid"`
+	Type       int    `json:"type"`
+	InlineType int    `json:"inline_type"`
+	Icon       string `json:"icon"`
+	Desc       string `json:"desc"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// 白板节点
+type FlowNode struct {
+	Id         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Position   interface{}            `json:"position"`
+	ParentNode string                 `json:"parentNode,omitempty"`
+	Style      map[string]interface{} `json:"style"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// 白板关联
+type FlowEdge struct {
+	Id           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Source       string                 `json:"source"`
+	Target       string                 `json:"target"`
+	SourceHandle string                 `json:"sourceHandle"`
+	TargetHandle string                 `json:"targetHandle"`
+	Deletable    *bool                  `json:"deletable,omitempty"`
+	Selected     *bool                  `json:"selected,omitempty"`
+	Data         map[string]interface{} `json:"data"`
+	MarkerEnd    map[string]interface{} `json:"markerEnd,omitempty"`
+}
+
+type listContent struct {
+	Cards []*ViewCard `json:"cards"`
+}
+
+type flowContent struct {
+	Nodes []*FlowNode `json:"nodes"`
+	Edges []*FlowEdge `json:"edges"`
+	Cards []*ViewCard `json:"cards"`
+}
+
+// 卡片类型，props 为类型的属性配置
+type viewType struct {
+	Id    string
+	Icon  string
+	Props []*ViewCardProp
+}
+
+func (t *viewType) prop(id string) *ViewCardProp {
+	for _, p := range t.Props {
+		if p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// 根据视图当前数据生成视图内容
+func (srv *Service) BuildViewContent(uid int, viewId string) (*model.View, string, error) {
+	view := &model.View{}
+	err := view.GetViewById(uid, viewId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrViewNotFound
+	} else if err != nil {
+		return nil, "", err
+	}
+	if view.Config == "" {
+		p := &model.Propext{}
+		view.Config = p.GetExtPropByUid(uid, view.Id, model.TYPE_VIEW_CONFIG)
+	}
+	var content interface{}
+	if view.Type == model.TYPE_VIEW_LIST {
+		content, err = srv.buildListContent(uid, view)
+	} else {
+		content, err = srv.buildFlowContent(uid, view)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, "", err
+	}
+	return view, string(data), nil
+}
+
+// 根据卡片当前数据生成卡片分享内容，结构与列表视图一致
+// 属性值附带展示文本(选项类型为选项名称)，不包含密码类型的属性和原始属性对象
+func (srv *Service) BuildCardContent(uid int, cardId string) (*ViewCard, string, error) {
+	card := &model.Card{}
+	err := card.GetCardById(uid, cardId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrCardNotFound
+	} else if err != nil {
+		return nil, "", err
+	}
+	if err := mergeCardPropexts(uid, []*model.Card{card}); err != nil {
+		return nil, "", err
+	}
+	typeMap, err := getViewTypes(uid, []string{card.TypeId})
+	if err != nil {
+		return nil, "", err
+	}
+	vc := formatViewCard(card, typeMap[card.TypeId])
+	props := make([]*ViewCardProp, 0, len(vc.Props))
+	for _, p := range vc.Props {
+		if p.Type == render.PROP_PASSWORD {
+			continue
+		}
+		rp := &render.CardProp{Id: p.Id, Name: p.Name, Type: p.Type, Val: p.Val}
+		json.Unmarshal(p.Options, &rp.Options)
+		p.Text = render.PropText(rp)
+		props = append(props, p)
+	}
+	vc.Props = props
+	vc.PropsObj = nil
+	data, err := json.Marshal(&listContent{Cards: []*ViewCard{vc}})
+	if err != nil {
+		return nil, "", err
+	}
+	return vc, string(data), nil
+}
+
+// 列表视图：按视图规则的筛选条件查询卡片
+func (srv *Service) buildListContent(uid int, view *model.View) (*listContent, error) {
+	res := &listContent{Cards: []*ViewCard{}}
+	rule := parseViewRule(view.Config)
+	// 指定了白板时只查询白板中的卡片
+	var cardIds map[string]bool
+	if boardId := ruleValue(rule.Filters, FILTER_PROP_BOARD); boardId != "" {
+		vn := &model.Viewnode{}
+		ids, err := vn.GetCardIds(uid, boardId)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return res, nil
+		}
+		cardIds = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			cardIds[id] = true
+		}
+	}
+	typeId := ruleValue(rule.Filters, FILTER_PROP_TYPE)
+	var dbFilters []*FilterRule
+	for _, f := range rule.Filters {
+		if f.TypeId == "" && f.PropId != FILTER_PROP_BOARD {
+			dbFilters = append(dbFilters, f)
+		}
+	}
+	mc := &model.Card{}
+	all, err := mc.GetSpaceCards(uid, view.SpaceId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var cards []*model.Card
+	for i := range *all {
+		card := &(*all)[i]
+		if cardIds != nil && !cardIds[card.Id] {
+			continue
+		}
+		if !matchCardFilters(card, dbFilters, now) {
+			continue
+		}
+		cards = append(cards, card)
+		if len(cards) >= VIEW_QUERY_CARD_CNT {
+			break
+		}
+	}
+	if len(cards) == 0 {
+		return res, nil
+	}
+	if err := mergeCardPropexts(uid, cards); err != nil {
+		return nil, err
+	}
+	typeIds := []string{}
+	if typeId != "" {
+		typeIds = append(typeIds, typeId)
+	}
+	for _, card := range cards {
+		typeIds = append(typeIds, card.TypeId)
+	}
+	typeMap, err := getViewTypes(uid, typeIds)
+	if err != nil {
+		return nil, err
+	}
+	// 指定了卡片模板时，再按模板属性筛选
+	if typeInfo, ok := typeMap[typeId]; ok {
+		var propFilters []*FilterRule
+		for _, f := range rule.Filters {
+			if f.TypeId == typeInfo.Id {
+				propFilters = append(propFilters, f)
+			}
+		}
+		if len(propFilters) > 0 {
+			var list []*model.Card
+			for _, card := range cards {
+				if matchPropFilters(parseCardProps(card.Props), typeInfo, propFilters, now) {
+					list = append(list, card)
+				}
+			}
+			cards = list
+		}
+	}
+	for _, card := range cards {
+		res.Cards = append(res.Cards, formatViewCard(card, typeMap[card.TypeId]))
+	}
+	return res, nil
+}
+
+// 白板视图：组装节点、关联和节点中的卡片
+func (srv *Service) buildFlowContent(uid int, view *model.View) (*flowContent, error) {
+	res := &flowContent{Nodes: []*FlowNode{}, Edges: []*FlowEdge{}, Cards: []*ViewCard{}}
+	if view.Type != model.TYPE_VIEW_BOARD {
+		return res, nil
+	}
+	mvn := &model.Viewnode{}
+	vns, err := mvn.GetViewnodesByViewId(uid, view.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(*vns) == 0 {
+		return res, nil
+	}
+	// 节点内容超长时保存在扩展表
+	var vnIds, cardIds, viewIds []string
+	for _, vn := range *vns {
+		if vn.Content == "" {
+			vnIds = append(vnIds, vn.Id)
+		}
+		switch vn.NodeType {
+		case VN_NODE_CARD:
+			cardIds = append(cardIds, vn.NodeId)
+		case VN_NODE_VIEW:
+			viewIds = append(viewIds, vn.NodeId)
+		}
+	}
+	propMap, err := getPropextMap(uid, vnIds)
+	if err != nil {
+		return nil, err
+	}
+	for i, vn := range *vns {
+		if prop, ok := propMap[propextKey(vn.Id, model.TYPE_VIEW_CONFIG)]; ok {
+			(*vns)[i].Content = prop
+		}
+	}
+	cardMap := map[string]*ViewCard{}
+	if len(cardIds) > 0 {
+		mc := &model.Card{}
+		list, err := mc.GetCardsByIds(uid, cardIds)
+		if err != nil {
+			return nil, err
+		}
+		cards := make([]*model.Card, len(*list))
+		typeIds := make([]string, len(*list))
+		for i := range *list {
+			cards[i] = &(*list)[i]
+			typeIds[i] = cards[i].TypeId
+		}
+		if err := mergeCardPropexts(uid, cards); err != nil {
+			return nil, err
+		}
+		typeMap, err := getViewTypes(uid, typeIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range cards {
+			cardMap[card.Id] = formatViewCard(card, typeMap[card.TypeId])
+		}
+	}
+	viewMap := map[string]*ViewInfo{}
+	if len(viewIds) > 0 {
+		mv := &model.View{}
+		list, err := mv.GetViewsByIds(uid, viewIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range *list {
+			viewMap[v.Id] = &ViewInfo{
+				Id:         v.Id,
+				Name:       v.Name,
+				SpaceId:    v.SpaceId,
+				Pid:        v.Pid,
+				Type:       v.Type,
+				InlineType: v.InlineType,
+				Icon:       v.Icon,
+				Desc:       v.Desc,
+				UpdateTime: v.UpdateTime,
+			}
+		}
+	}
+	// 思维导图分组内的节点放在最后，根节点排在分组的第一位
+	var mindGroups []string
+	mindMap := map[string][]*FlowNode{}
+	for _, vn := range *vns {
+		if isAllMGroupNode(vn.VnTypeId) {
+			mindGroups = append(mindGroups, vn.Id)
+			mindMap[vn.Id] = []*FlowNode{}
+		}
+	}
+	added := map[string]bool{}
+	for i := range *vns {
+		vn := &(*vns)[i]
+		var cardInfo *ViewCard
+		var viewInfo *ViewInfo
+		if vn.NodeType == VN_NODE_CARD {
+			cardInfo = cardMap[vn.NodeId]
+			if cardInfo != nil && !added[vn.NodeId] {
+				added[vn.NodeId] = true
+				res.Cards = append(res.Cards, cardInfo)
+			}
+		} else if vn.NodeType == VN_NODE_VIEW {
+			viewInfo = viewMap[vn.NodeId]
+		}
+		_, aMindNode := mindMap[vn.GroupId]
+		aMindNode = aMindNode && vn.GroupId != ""
+		node := formatFlowNode(vn, cardInfo, viewInfo, aMindNode)
+		if aMindNode {
+			if node.Data["pid"] == MIND_ROOT_PID {
+				mindMap[vn.GroupId] = append([]*FlowNode{node}, mindMap[vn.GroupId]...)
+			} else {
+				mindMap[vn.GroupId] = append(mindMap[vn.GroupId], node)
+			}
+		} else {
+			res.Nodes = append(res.Nodes, node)
+		}
+	}
+	mve := &model.Viewedge{}
+	ves, err := mve.GetViewedgesByViewId(uid, view.Id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *ves {
+		res.Edges = append(res.Edges, formatFlowEdge(&(*ves)[i]))
+	}
+	for _, groupId := range mindGroups {
+		nodes := mindMap[groupId]
+		res.Nodes = append(res.Nodes, nodes...)
+		for i := 1; i < len(nodes); i++ {
+			res.Edges = append(res.Edges, formatMindEdge(nodes[i]))
+		}
+	}
+	return res, nil
+}
+
+// 查询保存在 propext 扩展表中的超长字段，key 为 id + 扩展类型
+func getPropextMap(uid int, ids []string) (map[string]string, error) {
+	if len(ids) == 0 {
+		return map[string]string{}, nil
+	}
+	p := &model.Propext{}
+	propMap, err := p.GetPropexts(uid, &ids)
+	if err != nil {
+		return nil, err
+	}
+	if propMap == nil {
+		return map[string]string{}, nil
+	}
+	return *propMap, nil
+}
+
+func propextKey(id string, typeId int) string {
+	return id + string(rune(typeId))
+}
+
+// 合并卡片保存在扩展表中的属性和内容
+func mergeCardPropexts(uid int, cards []*model.Card) error {
+	var ids []string
+	for _, card := range cards {
+		if card.Props == "" || card.Content == "" {
+			ids = append(ids, card.Id)
+		}
+	}
+	propMap, err := getPropextMap(uid, ids)
+	if err != nil {
+		return err
+	}
+	for _, card := range cards {
+		if prop, ok := propMap[propextKey(card.Id, model.TYPE_CARD_PROPS)]; ok {
+			card.Props = prop
+		}
+		if prop, ok := propMap[propextKey(card.Id, model.TYPE_CARD_CONTENT)]; ok {
+			card.Content = prop
+		}
+	}
+	return nil
+}
+
+// 查询卡片类型及其属性配置
+func getViewTypes(uid int, ids []string) (map[string]*viewType, error) {
+	res := map[string]*viewType{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	mt := &model.Type{}
+	types, err := mt.GetTypesByIds(uid, ids)
+	if err != nil {
+		return nil, err
+	}
+	var extIds []string
+	for _, t := range *types {
+		if t.Props == "" {
+			extIds = append(extIds, t.Id)
+		}
+	}
+	propMap, err := getPropextMap(uid, extIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range *types {
+		props := t.Props
+		if prop, ok := propMap[propextKey(t.Id, model.TYPE_TYPE_CONFIG)]; ok {
+			props = prop
+		}
+		vt := &viewType{Id: t.Id, Icon: t.Icon, Props: []*ViewCardProp{}}
+		json.Unmarshal([]byte(props), &vt.Props)
+		res[t.Id] = vt
+	}
+	return res, nil
+}
+
+func parseCardProps(props string) map[string]interface{} {
+	if props == "" {
+		return nil
+	}
+	var m map[string]interface{}
+	if json.Unmarshal([]byte(props), &m) != nil {
+		return nil
+	}
+	return m
+}
+
+// 组装卡片信息，属性按类型的属性配置排列
+func formatViewCard(card *model.Card, t *viewType) *ViewCard {
+	props := parseCardProps(card.Props)
+	vc := &ViewCard{
+		Id:         card.Id,
+		SpaceId:    card.SpaceId,
+		TypeId:     card.TypeId,
+		Name:       card.Name,
+		Tags:       []string{},
+		PropsObj:   props,
+		Props:      []*ViewCardProp{},
+		CreateTime: card.CreateTime,
+		UpdateTime: card.UpdateTime,
+	}
+	json.Unmarshal([]byte(card.Tags), &vc.Tags)
+	if json.Valid([]byte(card.Content)) {
+		vc.Content = json.RawMessage(card.Content)
+	}
+	if t != nil {
+		vc.Icon = t.Icon
+		if props != nil {
+			for _, p := range t.Props {
+				val := props[p.Id]
+				if !jsTruthy(val) {
+					val = ""
+				}
+				vc.Props = append(vc.Props, &ViewCardProp{
+					Id:      p.Id,
+					Name:    p.Name,
+					Type:    p.Type,
+					Handles: p.Handles,
+					Show:    p.Show,
+					Options: p.Options,
+					Val:     val,
+				})
+			}
+		}
+	}
+	if card.CreateTime > 0 {
+		vc.CreateDate = time.Unix(int64(card.CreateTime), 0).Format("2006-01-02")
+	}
+	if card.UpdateTime > 0 {
+		vc.UpdateDate = time.UnixMilli(card.UpdateTime).Format("2006-01-02")
+	}
+	return vc
+}
+
+func isAllMGroupNode(vnType string) bool {
+	return vnType == VN_TYPE_MGROUP || vnType == VN_TYPE_MINDGROUP
+}
+
+func isOldMindNode(vnType string) bool {
+	return vnType == VN_TYPE_MINDROOT || vnType == VN_TYPE_MINDSUB || vnType == VN_TYPE_MINDNODE || vnType == VN_TYPE_MIND
+}
+
+func isOldShapeNode(vnType string) bool {
+	return vnType == VN_TYPE_SQUARE || vnType == VN_TYPE_CIRCLE || vnType == VN_TYPE_TRIANGLE
+}
+
+// 老导图节点根据 node_type 转换为对应的节点类型
+func vnTypeByNodeType(nodeType int) string {
+	switch nodeType {
+	case VN_NODE_CARD:
+		return VN_TYPE_CARD
+	case VN_NODE_VIEW:
+		return VN_TYPE_VIEW
+	}
+	return VN_TYPE_TEXT
+}
+
+// 格式化白板节点，与前端 nodeFactory 一致，兼容老节点数据
+func formatFlowNode(vn *model.Viewnode, cardInfo *ViewCard, viewInfo *ViewInfo, aMindNode bool) *FlowNode {
+	content := map[string]interface{}{}
+	json.Unmarshal([]byte(vn.Content), &content)
+	vnType, nodeType, pid := vn.VnTypeId, vn.NodeType, vn.Pid
+	position := content["position"]
+	if position == nil {
+		position = map[string]interface{}{"x": 0, "y": 0}
+	}
+	width, autoWidth, height := content["width"], content["autoWidth"], content["height"]
+	styleId, shapeType, ext := content["styleId"], content["shapeType"], content["ext"]
+	snum := content["snum"]
+	if snum == nil {
+		snum = 0
+	}
+	if isOldMindNode(vnType) {
+		vnType = vnTypeByNodeType(nodeType)
+	} else if isOldShapeNode(vnType) {
+		shapeType = vnType
+		vnType = VN_TYPE_SHAPE
+	} else if vnType == VN_TYPE_MINDGROUP {
+		vnType = VN_TYPE_MGROUP
+		nodeType = VN_NODE_GROUP
+		autoWidth = true
+	}
+	aShapeNode := vnType == VN_TYPE_SHAPE
+	aGroupCate := vnType == VN_TYPE_IGROUP || vnType == VN_TYPE_MGROUP
+	// 老文本节点的内容保存在 name 字段
+	if nodeType == VN_NODE_TEXT && vn.Name != "" {
+		ext = textExt(vn.Name)
+	}
+	if aMindNode {
+		if !jsTruthy(width) || autoWidth == nil {
+			width = VN_NODE_WIDTH
+		}
+		if pid == "" {
+			pid = MIND_ROOT_PID
+		}
+	}
+	if aShapeNode {
+		autoWidth = false
+	} else if autoWidth == nil {
+		autoWidth = aMindNode
+	}
+	if styleId == nil && (nodeType == VN_NODE_CARD || nodeType == VN_NODE_VIEW) {
+		if aMindNode || aShapeNode {
+			styleId = VN_STYLE_FOLD
+		} else {
+			styleId = VN_STYLE_FULL
+		}
+	}
+	var style map[string]interface{}
+	if aShapeNode || aGroupCate {
+		style = compactMap(map[string]interface{}{"width": width, "height": height})
+	} else if jsTruthy(autoWidth) {
+		style = compactMap(map[string]interface{}{"maxWidth": width})
+	} else {
+		style = compactMap(map[string]interface{}{"width": width})
+	}
+	data := map[string]interface{}{
+		"nodeId":    vn.NodeId,
+		"nodeType":  nodeType,
+		"pid":       pid,
+		"width":     width,
+		"autoWidth": autoWidth,
+		"height":    height,
+		"layout":    content["layout"],
+		"styleId":   styleId,
+		"shapeType": shapeType,
+		"bgColor":   content["bgColor"],
+		"snum":      snum,
+		"ext":       ext,
+	}
+	if cardInfo != nil {
+		data["cardInfo"] = cardInfo
+	}
+	if viewInfo != nil {
+		data["viewInfo"] = viewInfo
+	}
+	return &FlowNode{
+		Id:         vn.Id,
+		Type:       vnType,
+		Position:   position,
+		ParentNode: vn.GroupId,
+		Style:      style,
+		Data:       compactMap(data),
+	}
+}
+
+// 老文本节点内容转换为文档格式
+func textExt(text string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "doc",
+		"content": []interface{}{
+			map[string]interface{}{
+				"type":    "paragraph",
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": text}},
+			},
+		},
+	})
+	return string(data)
+}
+
+// 目标连接点统一转换为 source 类型
+func convTargetHandle(th string) string {
+	switch th {
+	case "tt":
+		return "st"
+	case "tr":
+		return "sr"
+	case "tb":
+		return "sb"
+	case "tl":
+		return "sl"
+	}
+	return th
+}
+
+func formatFlowEdge(ve *model.Viewedge) *FlowEdge {
+	return &FlowEdge{
+		Id:           ve.Id,
+		Type:         ve.VeTypeId,
+		Source:       ve.Source,
+		Target:       ve.Target,
+		SourceHandle: ve.SourceHandle,
+		TargetHandle: convTargetHandle(ve.TargetHandle),
+		Data:         map[string]interface{}{"label": ve.Name},
+		MarkerEnd:    map[string]interface{}{"type": "arrowclosed"},
+	}
+}
+
+// 根据导图节点的 pid 生成导图关联
+func formatMindEdge(node *FlowNode) *FlowEdge {
+	f := false
+	pid, _ := node.Data["pid"].(string)
+	return &FlowEdge{
+		Id:           "me_" + node.Id,
+		Type:         "mindEdge",
+		Source:       pid,
+		Target:       node.Id,
+		SourceHandle: "sr",
+		TargetHandle: "sl",
+		Deletable:    &f,
+		Selected:     &f,
+		Data:         compactMap(map[string]interface{}{"groupId": nilIfEmpty(node.ParentNode)}),
+	}
+}
+
+// 删除值为 nil 的字段，与前端 JSON 序列化时忽略 undefined 一致
+func compactMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		if v == nil {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+func nilIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
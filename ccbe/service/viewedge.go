@@ -91,9 +91,9 @@ func (srv *Service) UpdateViewedges(uid int, viewedges *[]*model.Viewedge, viewe
 }
 
 // 获取节点分组列表
-func (srv *Service) GetViewedges(uid int, updateTime int64, limit int) (*[]model.Viewedge, error) {
+func (srv *Service) GetViewedges(scope *model.SyncScope, updateTime int64, limit int) (*[]model.Viewedge, error) {
 	mv := &model.Viewedge{}
-	list, err := mv.GetViewedges(uid, updateTime, limit)
+	list, err := mv.GetViewedges(scope, updateTime, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +110,7 @@ func (srv *Service) GetViewedges(uid int, updateTime int64, limit int) (*[]model
 		return list, nil
 	}
 	p := &model.Propext{}
-	propMap, err := p.GetPropexts(uid, &viewedgeIds)
+	propMap, err := p.GetPropextsByUids(scope.Uids(), &viewedgeIds)
 	if err != nil {
 		return nil, err
 	}
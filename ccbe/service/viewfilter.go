@@ -0,0 +1,537 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cc/be/model"
+)
+
+// 视图筛选条件，与前端 CondEnum 一致
+const (
+	COND_EQ     = iota // 相等
+	COND_NEQ           // 不相等
+	COND_IN            // 包含
+	COND_NIN           // 不包含
+	COND_EMPTY         // 为空
+	COND_NEMPTY        // 不为空
+	COND_GT            // 大于
+	COND_LT            // 小于
+	COND_GET           // 大于等于
+	COND_LET           // 小于等于
+	COND_DRULE         // 自定义日期筛选规则
+	COND_INALL         // 同时包含所有
+)
+
+// 日期筛选规则类型：绝对日期、绝对日期范围、相对日期、相对日期范围
+const (
+	DATE_RULE_DAD = iota
+	DATE_RULE_DAR
+	DATE_RULE_DRD
+	DATE_RULE_DRR
+)
+
+// 相对日期单位
+const (
+	DATE_UNIT_DAY = iota
+	DATE_UNIT_WEEK
+	DATE_UNIT_MONTH
+	DATE_UNIT_QUARTER
+	DATE_UNIT_YEAR
+)
+
+// 卡片通用属性
+const (
+	FILTER_PROP_BOARD       = "board"
+	FILTER_PROP_TYPE        = "type"
+	FILTER_PROP_NAME        = "name"
+	FILTER_PROP_TAGS        = "tags"
+	FILTER_PROP_CREATE_TIME = "create_time"
+	FILTER_PROP_UPDATE_TIME = "update_time"
+)
+
+// 筛选规则，typeId 为空表示卡片通用属性
+type FilterRule struct {
+	TypeId string      `json:"typeId"`
+	PropId string      `json:"propId"`
+	Cond   int         `json:"cond"`
+	Value  interface{} `json:"value"`
+}
+
+// 日期筛选规则，绝对日期的 start/end 为日期字符串，相对日期为相对当前的单位数
+type DateRule struct {
+	Type  int         `json:"type"`
+	Unit  int         `json:"unit"`
+	Start interface{} `json:"start"`
+	End   interface{} `json:"end"`
+}
+
+type viewRule struct {
+	Id      string        `json:"id"`
+	TypeId  string        `json:"typeId"`
+	Filters []*FilterRule `json:"filters"`
+}
+
+type viewConfig struct {
+	RuleId *string     `json:"ruleId"`
+	Rules  []*viewRule `json:"rules"`
+}
+
+// 解析列表视图当前使用的规则，没有配置时不筛选
+func parseViewRule(config string) *viewRule {
+	cfg := &viewConfig{}
+	json.Unmarshal([]byte(config), cfg)
+	if cfg.RuleId == nil || len(cfg.Rules) == 0 {
+		return &viewRule{}
+	}
+	for _, r := range cfg.Rules {
+		if r.Id == *cfg.RuleId {
+			return r
+		}
+	}
+	return cfg.Rules[0]
+}
+
+// 获取指定通用属性的相等条件的值
+func ruleValue(filters []*FilterRule, propId string) string {
+	for _, f := range filters {
+		if f.PropId == propId && f.Cond == COND_EQ {
+			s, _ := f.Value.(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// 卡片是否符合通用属性筛选条件
+func matchCardFilters(card *model.Card, filters []*FilterRule, now time.Time) bool {
+	for _, f := range filters {
+		switch f.PropId {
+		case FILTER_PROP_CREATE_TIME:
+			if f.Cond == COND_DRULE && f.Value != "" {
+				if !matchDateRange(parseDateRule(f.Value), time.Unix(int64(card.CreateTime), 0), now) {
+					return false
+				}
+			}
+		case FILTER_PROP_UPDATE_TIME:
+			if f.Cond == COND_DRULE && f.Value != "" {
+				if !matchDateRange(parseDateRule(f.Value), time.UnixMilli(card.UpdateTime), now) {
+					return false
+				}
+			}
+		case FILTER_PROP_NAME:
+			if !matchNameFilter(card.Name, f) {
+				return false
+			}
+		case FILTER_PROP_TAGS:
+			var tags []string
+			json.Unmarshal([]byte(card.Tags), &tags)
+			if !matchTagsFilter(tags, f) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchNameFilter(name string, f *FilterRule) bool {
+	val, _ := f.Value.(string)
+	switch f.Cond {
+	case COND_EQ:
+		return name == val
+	case COND_NEQ:
+		return name != val
+	case COND_IN:
+		return val == "" || regexTest(val, name)
+	case COND_NIN:
+		return val == "" || !regexTest(val, name)
+	case COND_EMPTY:
+		return name == ""
+	case COND_NEMPTY:
+		return name != ""
+	}
+	return true
+}
+
+func matchTagsFilter(tags []string, f *FilterRule) bool {
+	vals := toStrings(f.Value)
+	switch f.Cond {
+	case COND_IN:
+		if len(vals) > 0 {
+			return containsAny(tags, vals)
+		}
+	case COND_INALL:
+		for _, v := range vals {
+			if !containsAny(tags, []string{v}) {
+				return false
+			}
+		}
+	case COND_NIN:
+		if len(vals) > 0 {
+			return !containsAny(tags, vals)
+		}
+	case COND_EMPTY:
+		return len(tags) == 0
+	case COND_NEMPTY:
+		return len(tags) > 0
+	}
+	return true
+}
+
+// 卡片是否符合模板属性筛选条件，判断方式与前端一致
+func matchPropFilters(props map[string]interface{}, t *viewType, filters []*FilterRule, now time.Time) bool {
+	for _, f := range filters {
+		propInfo := t.prop(f.PropId)
+		if propInfo == nil {
+			return false
+		}
+		val, ok := props[f.PropId]
+		if !ok {
+			return false
+		}
+		if !matchPropFilter(propInfo.Type, val, f, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPropFilter(propType string, val interface{}, f *FilterRule, now time.Time) bool {
+	ruleVals, ruleIsArr := f.Value.([]interface{})
+	switch f.Cond {
+	case COND_EQ, COND_NEQ:
+		var eq bool
+		if propType == "link" {
+			text, link := parseLinkVal(val)
+			eq = f.Value == text || f.Value == link
+		} else if propType == "mselect" && ruleIsArr {
+			eq = sameStrings(toStrings(ruleVals), toStrings(val))
+		} else {
+			eq = jsStrictEqual(val, f.Value)
+		}
+		if f.Cond == COND_EQ {
+			return eq
+		}
+		return !eq
+	case COND_IN, COND_NIN:
+		var in bool
+		if propType == "select" && ruleIsArr {
+			if len(ruleVals) == 0 {
+				return false
+			}
+			in = containsAny(toStrings(ruleVals), toStrings(val))
+		} else if propType == "mselect" && ruleIsArr {
+			if len(ruleVals) == 0 {
+				return false
+			}
+			in = containsAny(toStrings(val), toStrings(ruleVals))
+		} else {
+			if f.Value == "" {
+				return false
+			}
+			in = regexTest(jsString(f.Value), jsString(val))
+		}
+		if f.Cond == COND_IN {
+			return in
+		}
+		return !in
+	case COND_EMPTY:
+		if propType == "mselect" && ruleIsArr {
+			return len(ruleVals) == 0
+		}
+		return !jsTruthy(val)
+	case COND_NEMPTY:
+		if propType == "mselect" && ruleIsArr {
+			return len(ruleVals) != 0
+		}
+		return jsTruthy(val)
+	case COND_GT:
+		c, ok := jsCompare(val, f.Value)
+		return ok && c > 0
+	case COND_LT:
+		c, ok := jsCompare(val, f.Value)
+		return ok && c < 0
+	case COND_GET:
+		c, ok := jsCompare(val, f.Value)
+		return ok && c >= 0
+	case COND_LET:
+		c, ok := jsCompare(val, f.Value)
+		return ok && c <= 0
+	case COND_DRULE:
+		date, _ := val.(string)
+		if len(date) < 10 {
+			return false
+		}
+		d, err := time.ParseInLocation("2006-01-02", date[:10], time.Local)
+		if err != nil {
+			return false
+		}
+		return matchDateRange(parseDateRule(f.Value), d, now)
+	}
+	return false
+}
+
+func parseDateRule(v interface{}) *DateRule {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	rule := &DateRule{}
+	if json.Unmarshal(data, rule) != nil {
+		return nil
+	}
+	return rule
+}
+
+// 日期是否在筛选规则的范围内，按天比较
+func matchDateRange(rule *DateRule, t time.Time, now time.Time) bool {
+	if rule == nil {
+		return false
+	}
+	start, end, ok := dateRuleRange(rule, now)
+	if !ok {
+		return false
+	}
+	return (start.IsZero() || !t.Before(start)) && (end.IsZero() || t.Before(end))
+}
+
+// 解析日期筛选规则为 [start, end) 时间范围，零值表示不限
+func dateRuleRange(rule *DateRule, now time.Time) (time.Time, time.Time, bool) {
+	switch rule.Type {
+	case DATE_RULE_DAD:
+		start, ok := parseRuleDate(rule.Start)
+		if !ok {
+			return start, start, false
+		}
+		return start, start.AddDate(0, 0, 1), true
+	case DATE_RULE_DAR:
+		start, hasStart := parseRuleDate(rule.Start)
+		end, hasEnd := parseRuleDate(rule.End)
+		if hasEnd {
+			end = end.AddDate(0, 0, 1)
+		}
+		return start, end, hasStart || hasEnd
+	case DATE_RULE_DRD:
+		n, ok := ruleNumber(rule.Start)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		start := periodStart(now, rule.Unit, n)
+		return start, periodStart(now, rule.Unit, n+1), true
+	case DATE_RULE_DRR:
+		start, hasStart := ruleNumber(rule.Start)
+		end, hasEnd := ruleNumber(rule.End)
+		if hasStart && hasEnd && start > end {
+			start, end = end, start
+		}
+		var s, e time.Time
+		if hasStart {
+			s = periodStart(now, rule.Unit, start)
+		}
+		if hasEnd {
+			e = periodStart(now, rule.Unit, end+1)
+		}
+		return s, e, hasStart || hasEnd
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// 相对当前第 n 个周期的开始时间，周从周一开始计算(与前端 startOf("week") 加一天一致)
+func periodStart(now time.Time, unit, n int) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch unit {
+	case DATE_UNIT_WEEK:
+		return today.AddDate(0, 0, 1-int(today.Weekday())+7*n)
+	case DATE_UNIT_MONTH:
+		return time.Date(today.Year(), today.Month()+time.Month(n), 1, 0, 0, 0, 0, today.Location())
+	case DATE_UNIT_QUARTER:
+		q := (int(today.Month()) - 1) / 3
+		return time.Date(today.Year(), time.Month(q*3+1+3*n), 1, 0, 0, 0, 0, today.Location())
+	case DATE_UNIT_YEAR:
+		return time.Date(today.Year()+n, 1, 1, 0, 0, 0, 0, today.Location())
+	}
+	return today.AddDate(0, 0, n)
+}
+
+func parseRuleDate(v interface{}) (time.Time, bool) {
+	s, _ := v.(string)
+	if len(s) < 10 {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", s[:10], time.Local)
+	return t, err == nil
+}
+
+func ruleNumber(v interface{}) (int, bool) {
+	switch val := v.(type) {
+	case float64:
+		return int(math.Floor(val)), true
+	case string:
+		i, err := strconv.Atoi(val)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// 解析链接属性值，支持 [文本](地址) 格式和 {text, link} 对象
+var linkValRegexp = regexp.MustCompile(`^\[(.*?)\]\((.*?)\)$`)
+
+func parseLinkVal(v interface{}) (string, string) {
+	switch val := v.(type) {
+	case string:
+		if m := linkValRegexp.FindStringSubmatch(val); m != nil {
+			return m[1], m[2]
+		}
+		return "", val
+	case map[string]interface{}:
+		text, _ := val["text"].(string)
+		link, _ := val["link"].(string)
+		return text, link
+	}
+	return "", ""
+}
+
+// 不区分大小写的正则匹配，表达式不合法时按子串匹配
+func regexTest(pattern, s string) bool {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(pattern))
+	}
+	return re.MatchString(s)
+}
+
+func toStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case []interface{}:
+		res := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	case []string:
+		return val
+	case string:
+		return []string{val}
+	}
+	return nil
+}
+
+func containsAny(list, vals []string) bool {
+	for _, v := range vals {
+		for _, item := range list {
+			if item == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+// 以下为 JavaScript 比较语义的简单实现，保证与前端筛选结果一致
+func jsTruthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case float64:
+		return val != 0 && !math.IsNaN(val)
+	case int:
+		return val != 0
+	}
+	return true
+}
+
+func jsStrictEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case nil, string, float64, bool:
+		return reflect.TypeOf(a) == reflect.TypeOf(b) && av == b
+	}
+	return false
+}
+
+func jsString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			if item != nil {
+				items[i] = jsString(item)
+			}
+		}
+		return strings.Join(items, ",")
+	}
+	return "[object Object]"
+}
+
+// 两个字符串按字典序比较，否则转换为数字比较
+func jsCompare(a, b interface{}) (int, bool) {
+	as, aStr := a.(string)
+	bs, bStr := b.(string)
+	if aStr && bStr {
+		return strings.Compare(as, bs), true
+	}
+	an, ok1 := jsNumber(a)
+	bn, ok2 := jsNumber(b)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	switch {
+	case an > bn:
+		return 1, true
+	case an < bn:
+		return -1, true
+	}
+	return 0, true
+}
+
+func jsNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case nil:
+		return 0, true
+	case float64:
+		return val, true
+	case bool:
+		if val {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s := strings.TrimSpace(val)
+		if s == "" {
+			return 0, true
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -91,9 +91,9 @@ func (srv *Service) UpdateViewnodes(uid int, viewnodes *[]*model.Viewnode, viewn
 }
 
 // 获取节点分组列表
-func (srv *Service) GetViewnodes(uid int, updateTime int64, limit int) (*[]model.Viewnode, error) {
+func (srv *Service) GetViewnodes(scope *model.SyncScope, updateTime int64, limit int) (*[]model.Viewnode, error) {
 	mv := &model.Viewnode{}
-	list, err := mv.GetViewnodes(uid, updateTime, limit)
+	list, err := mv.GetViewnodes(scope, updateTime, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +110,7 @@ func (srv *Service) GetViewnodes(uid int, updateTime int64, limit int) (*[]model
 		return list, nil
 	}
 	p := &model.Propext{}
-	propMap, err := p.GetPropexts(uid, &viewnodeIds)
+	propMap, err := p.GetPropextsByUids(scope.Uids(), &viewnodeIds)
 	if err != nil {
 		return nil, err
 	}
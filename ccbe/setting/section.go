@@ -18,10 +18,22 @@ type AppSetting struct {
 	LogFileExt      string
 }
 
+// SigningKey 为当前签名密钥的 kid，为空时使用 Secret(HS256)
 type JwtSetting struct {
-	Secret string
-	Issuer string
-	Expire time.Duration
+	Secret     string
+	Issuer     string
+	Expire     time.Duration
+	SigningKey string
+	Keys       []JwtKeySetting
+}
+
+// 签名密钥，Algorithm 可选 HS256/RS256/EdDSA，只配置公钥时仅用于校验
+type JwtKeySetting struct {
+	Kid            string
+	Algorithm      string
+	Secret         string
+	PrivateKeyFile string
+	PublicKeyFile  string
 }
 
 type DatabaseSetting struct {
@@ -43,11 +55,124 @@ type RedisSetting struct {
 	DB       int
 }
 
-type QiniuSetting struct {
-	AccessKey  string
-	SecretKey  string
-	Bucket     string
+// 文件存储配置，Provider 可选 local/qiniu/s3，ExpireTime 为上传凭证有效期(s)
+// TempDir 为直接上传和分片上传的临时目录
+type StorageSetting struct {
+	Provider   string
 	ExpireTime int64
+	TempDir    string
+	Local      LocalStorageSetting
+	Qiniu      QiniuSetting
+	S3         S3Setting
+	Image      ImageSetting
+}
+
+// 图片处理配置，Variants 为生成的缩略图规格，MaxPixels 为解码的图片像素数上限
+type ImageSetting struct {
+	Quality   int
+	MaxPixels int
+	Variants  []ImageVariantSetting
+}
+
+// 缩略图规格，Width 为最长边(px)
+type ImageVariantSetting struct {
+	Name  string
+	Width int
+}
+
+// 存储额度配置，用户未单独设置时使用 DefaultPlan 的额度
+type QuotaSetting struct {
+	DefaultPlan string
+	Plans       []QuotaPlanSetting
+}
+
+// 存储套餐，Limit 为硬限制(字节)，超出后不能上传
+// SoftLimit 为软限制，超出后上传成功但返回提醒，为 0 时取硬限制的 80%
+type QuotaPlanSetting struct {
+	Name      string
+	Limit     int64
+	SoftLimit int64
+}
+
+// 本地磁盘存储，BaseURL 为服务端对外地址，用于生成上传和下载地址
+// Secret 用于签发上传凭证，MaxSize 为单个文件大小上限(字节)
+type LocalStorageSetting struct {
+	Root    string
+	BaseURL string
+	Secret  string
+	MaxSize int64
+}
+
+// 七牛云存储，CallbackURL 为空时不回调，由客户端上传完成后登记文件
+type QiniuSetting struct {
+	AccessKey   string
+	SecretKey   string
+	Bucket      string
+	Domain      string
+	CallbackURL string
+	MaxSize     int64
+}
+
+// 兼容 S3 协议的对象存储，PathStyle 用于 MinIO 等不支持虚拟主机域名的服务
+// Domain 为下载域名，为空时使用 Endpoint
+type S3Setting struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	Domain    string
+	MaxSize   int64
+}
+
+// 第三方身份提供方配置
+type IdentitySetting struct {
+	Wechat WechatSetting
+	Oidc   []OidcSetting
+}
+
+// 微信开放平台网站应用
+type WechatSetting struct {
+	AppId       string
+	AppSecret   string
+	RedirectURI string
+}
+
+// 通用 OIDC 提供方，Name 作为登录方式标识
+type OidcSetting struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       string
+}
+
+// 验证码发送配置，Sms 可选 console/file，Email 可选 console/file/smtp
+type SenderSetting struct {
+	Sms      string
+	Email    string
+	FilePath string
+	Smtp     SmtpSetting
+}
+
+type SmtpSetting struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// 分享页面配置，ShareURL 为分享链接前缀，StaticURL 为图片等静态资源域名
+// EmbedURL 为嵌入页面地址前缀，MaxRevisions 为每个快照分享保留的历史版本数
+type ShareSetting struct {
+	ShareURL     string
+	EmbedURL     string
+	StaticURL    string
+	DefaultImage string
+	MaxRevisions int
 }
 
 func (s *Setting) ReadSection(k string, v interface{}) error {
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"hash"
+	"io"
+	"os"
+)
+
+// 七牛云 etag 算法: 按 4M 分块计算 sha1
+// 只有一块时为 0x16 + sha1，多块时为 0x96 + 各块 sha1 拼接后的 sha1，结果做 URL 安全的 base64 编码
+const etagBlockSize = 1 << 22
+
+type etagHash struct {
+	block  hash.Hash
+	n      int
+	blocks [][]byte
+}
+
+func newEtag() *etagHash {
+	return &etagHash{block: sha1.New()}
+}
+
+func (e *etagHash) Write(b []byte) (int, error) {
+	total := len(b)
+	for len(b) > 0 {
+		size := etagBlockSize - e.n
+		if size > len(b) {
+			size = len(b)
+		}
+		e.block.Write(b[:size])
+		e.n += size
+		b = b[size:]
+		if e.n == etagBlockSize {
+			e.blocks = append(e.blocks, e.block.Sum(nil))
+			e.block.Reset()
+			e.n = 0
+		}
+	}
+	return total, nil
+}
+
+func (e *etagHash) String() string {
+	blocks := e.blocks
+	if e.n > 0 || len(blocks) == 0 {
+		blocks = append(blocks, e.block.Sum(nil))
+	}
+	var sum []byte
+	if len(blocks) == 1 {
+		sum = append([]byte{0x16}, blocks[0]...)
+	} else {
+		h := sha1.New()
+		for _, b := range blocks {
+			h.Write(b)
+		}
+		sum = append([]byte{0x96}, h.Sum(nil)...)
+	}
+	return base64.URLEncoding.EncodeToString(sum)
+}
+
+// 写入临时文件的上传内容
+type Spooled struct {
+	File *os.File
+	Hash string
+	Size int64
+}
+
+// 将上传内容写入临时文件并计算 etag，超出 maxSize 时返回 ErrTooLarge
+func Spool(r io.Reader, maxSize int64) (*Spooled, error) {
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(tempDir, "spool-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &Spooled{File: f}
+	etag := newEtag()
+	s.Size, err = io.Copy(io.MultiWriter(f, etag), io.LimitReader(r, maxSize+1))
+	if err == nil && s.Size > maxSize {
+		err = ErrTooLarge
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.Hash = etag.String()
+	return s, nil
+}
+
+// 关闭并删除临时文件
+func (s *Spooled) Close() error {
+	s.File.Close()
+	return os.Remove(s.File.Name())
+}
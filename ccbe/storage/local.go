@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cc/be/setting"
+)
+
+var ErrInvalidToken = errors.New("上传凭证无效或已过期")
+
+// 本地磁盘存储，由服务端接收上传文件并提供下载，无需联网
+type LocalProvider struct {
+	root    string
+	baseURL string
+	secret  []byte
+	maxSize int64
+}
+
+func NewLocalProvider(s setting.LocalStorageSetting) *LocalProvider {
+	p := &LocalProvider{root: s.Root, baseURL: s.BaseURL, secret: []byte(s.Secret), maxSize: s.MaxSize}
+	if p.root == "" {
+		p.root = "storage/files"
+	}
+	if p.maxSize <= 0 {
+		p.maxSize = DEFAULT_MAX_SIZE
+	}
+	if len(p.secret) == 0 {
+		// 未配置密钥时使用随机密钥，重启后已签发的凭证失效
+		p.secret = make([]byte, 32)
+		if _, err := rand.Read(p.secret); err != nil {
+			log.Printf("生成本地存储密钥异常: %s", err)
+		}
+	}
+	return p
+}
+
+func (p *LocalProvider) Name() string {
+	return TYPE_LOCAL
+}
+
+// 文件保存的根目录
+func (p *LocalProvider) Root() string {
+	return p.root
+}
+
+func (p *LocalProvider) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// 凭证格式: uid.过期时间.签名
+func (p *LocalProvider) UploadToken(uid int, expire int64) (*Token, error) {
+	expireTime := time.Now().Unix() + expire
+	payload := strconv.Itoa(uid) + "." + strconv.FormatInt(expireTime, 10)
+	return &Token{
+		Token:      payload + "." + p.sign(payload),
+		UploadURL:  joinURL(p.baseURL, "api/upload"),
+		ExpireTime: expireTime,
+	}, nil
+}
+
+// 校验上传凭证，返回凭证所属的 uid
+func (p *LocalProvider) VerifyToken(token string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(p.sign(payload)), []byte(parts[2])) {
+		return 0, ErrInvalidToken
+	}
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil || uid <= 0 {
+		return 0, ErrInvalidToken
+	}
+	expireTime, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || expireTime < time.Now().Unix() {
+		return 0, ErrInvalidToken
+	}
+	return uid, nil
+}
+
+// 先写入同目录的临时文件再重命名，避免读取到未写完的文件
+// 文件名为内容的 etag，已存在时无需重复写入
+func (p *LocalProvider) Put(key string, r io.Reader, size int64) error {
+	dst := p.path(key)
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (p *LocalProvider) Get(key string) (io.ReadCloser, error) {
+	return os.Open(p.path(key))
+}
+
+func (p *LocalProvider) MaxSize() int64 {
+	return p.maxSize
+}
+
+func (p *LocalProvider) path(key string) string {
+	return filepath.Join(p.root, filepath.FromSlash(key))
+}
+
+func (p *LocalProvider) VerifyCallback(req *http.Request) (bool, error) {
+	return false, nil
+}
+
+func (p *LocalProvider) Stat(key string) (*Object, error) {
+	info, err := os.Stat(p.path(key))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	return &Object{Key: key, Hash: KeyHash(key), Size: info.Size()}, nil
+}
+
+func (p *LocalProvider) DownloadURL(key string) string {
+	return joinURL(p.baseURL, key)
+}
+
+func (p *LocalProvider) Delete(key string) error {
+	err := os.Remove(p.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (p *LocalProvider) DeletePrefix(prefix string) (int, error) {
+	dir := p.path(prefix)
+	total := 0
+	err := filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			total++
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return total, os.RemoveAll(dir)
+}
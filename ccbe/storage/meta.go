@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// 文件的 MIME 类型，图片文件包含宽高
+type Meta struct {
+	Mime   string
+	Width  int
+	Height int
+}
+
+// 根据文件内容识别 MIME 类型，无法识别时按扩展名判断
+// 图片文件读取宽高，读取完成后回到文件开头
+func Inspect(r io.ReadSeeker, filename string) (*Meta, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	m := &Meta{Mime: http.DetectContentType(head[:n])}
+	if strings.HasPrefix(m.Mime, "application/octet-stream") || strings.HasPrefix(m.Mime, "text/plain") {
+		if t := mime.TypeByExtension(Ext(filename)); t != "" {
+			m.Mime = t
+		}
+	}
+	if strings.HasPrefix(m.Mime, "image/") {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// 不支持的图片格式只记录类型
+		if cfg, _, err := image.DecodeConfig(r); err == nil {
+			m.Width = cfg.Width
+			m.Height = cfg.Height
+		}
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
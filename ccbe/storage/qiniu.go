@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"cc/be/setting"
+
+	"github.com/qiniu/go-sdk/v7/auth/qbox"
+	"github.com/qiniu/go-sdk/v7/client"
+	qiniu "github.com/qiniu/go-sdk/v7/storage"
+)
+
+// 七牛云存储，客户端直传，上传完成后由七牛回调服务端登记文件
+type QiniuProvider struct {
+	setting setting.QiniuSetting
+	mac     *qbox.Mac
+}
+
+func NewQiniuProvider(s setting.QiniuSetting) *QiniuProvider {
+	if s.MaxSize <= 0 {
+		s.MaxSize = DEFAULT_MAX_SIZE
+	}
+	return &QiniuProvider{setting: s, mac: qbox.NewMac(s.AccessKey, s.SecretKey)}
+}
+
+func (p *QiniuProvider) Name() string {
+	return TYPE_QINIU
+}
+
+func (p *QiniuProvider) UploadToken(uid int, expire int64) (*Token, error) {
+	uidStr := strconv.Itoa(uid)
+	putPolicy := qiniu.PutPolicy{
+		Scope:   p.setting.Bucket,
+		Expires: uint64(expire),
+		SaveKey: UserPrefix(uid) + "${etag}${ext}",
+	}
+	if p.setting.CallbackURL != "" {
+		putPolicy.CallbackURL = p.setting.CallbackURL
+		// 图片宽高需要额外查询 imageInfo，回调只带文件名和 MIME 类型
+		putPolicy.CallbackBody = `{"key":"$(key)","hash":"$(etag)","fsize":$(fsize),"fname":"$(fname)","mime":"$(mimeType)","uid":` + uidStr + `}`
+		putPolicy.CallbackBodyType = "application/json"
+	}
+	upToken := putPolicy.UploadToken(p.mac)
+	if upToken == "" {
+		return nil, errors.New("生成上传token异常")
+	}
+	return &Token{Token: upToken, ExpireTime: time.Now().Unix() + expire}, nil
+}
+
+// 七牛云文件上传回调校验
+// QBox ljccqOeFycTZDqb5N4cLADkvI1E4YJYortghRmS1:Nmm3ICp_Jt_lJt33ttWt6jjdLrU=
+func (p *QiniuProvider) VerifyCallback(req *http.Request) (bool, error) {
+	return qbox.VerifyCallback(p.mac, req)
+}
+
+func (p *QiniuProvider) bucketManager() *qiniu.BucketManager {
+	return qiniu.NewBucketManager(p.mac, &qiniu.Config{UseHTTPS: true})
+}
+
+// 服务端表单上传，文件已存在且内容相同时七牛云直接返回成功
+func (p *QiniuProvider) Put(key string, r io.Reader, size int64) error {
+	putPolicy := qiniu.PutPolicy{Scope: p.setting.Bucket}
+	uploader := qiniu.NewFormUploader(&qiniu.Config{UseHTTPS: true})
+	ret := qiniu.PutRet{}
+	return uploader.Put(context.Background(), &ret, putPolicy.UploadToken(p.mac), key, r, size, nil)
+}
+
+// 通过下载域名读取文件
+func (p *QiniuProvider) Get(key string) (io.ReadCloser, error) {
+	res, err := http.Get(p.DownloadURL(key))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, os.ErrNotExist
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.New("读取七牛云文件失败: " + res.Status)
+	}
+	return res.Body, nil
+}
+
+func (p *QiniuProvider) MaxSize() int64 {
+	return p.setting.MaxSize
+}
+
+func (p *QiniuProvider) Stat(key string) (*Object, error) {
+	info, err := p.bucketManager().Stat(p.setting.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Key: key, Hash: info.Hash, Size: info.Fsize, Mime: info.MimeType}, nil
+}
+
+func (p *QiniuProvider) DownloadURL(key string) string {
+	return joinURL(p.setting.Domain, key)
+}
+
+// 使用七牛云图片处理生成缩略图，imageView2/2 限定最长边，处理失败时返回原图
+func (p *QiniuProvider) ImageURL(key string, width int) string {
+	w := strconv.Itoa(width)
+	return p.DownloadURL(key) + "?imageView2/2/w/" + w + "/h/" + w + "/ignore-error/1"
+}
+
+func (p *QiniuProvider) Delete(key string) error {
+	err := p.bucketManager().Delete(p.setting.Bucket, key)
+	// 612: 文件不存在
+	var e *client.ErrorInfo
+	if errors.As(err, &e) && e.Code == 612 {
+		return nil
+	}
+	return err
+}
+
+func (p *QiniuProvider) DeletePrefix(prefix string) (int, error) {
+	if p.setting.AccessKey == "" || p.setting.Bucket == "" {
+		return 0, nil
+	}
+	bm := p.bucketManager()
+	bucket := p.setting.Bucket
+	total := 0
+	marker := ""
+	for {
+		entries, _, nextMarker, hasNext, err := bm.ListFiles(bucket, prefix, "", marker, 1000)
+		if err != nil {
+			return total, err
+		}
+		ops := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			ops = append(ops, qiniu.URIDelete(bucket, entry.Key))
+		}
+		if len(ops) > 0 {
+			_, err = bm.Batch(ops)
+			if err != nil {
+				return total, err
+			}
+			total += len(ops)
+		}
+		if !hasNext {
+			return total, nil
+		}
+		marker = nextMarker
+	}
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cc/be/setting"
+)
+
+// 空请求体的 sha256
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// 兼容 S3 协议的对象存储，客户端使用 POST 表单直传，上传完成后由客户端登记文件
+// 请求使用 AWS Signature V4 签名
+type S3Provider struct {
+	setting  setting.S3Setting
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Provider(s setting.S3Setting) (*S3Provider, error) {
+	if s.Endpoint == "" || s.Bucket == "" {
+		return nil, errors.New("未配置 S3 存储的 Endpoint 或 Bucket")
+	}
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, errors.New("S3 存储的 Endpoint 格式不正确")
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.MaxSize <= 0 {
+		s.MaxSize = DEFAULT_MAX_SIZE
+	}
+	return &S3Provider{setting: s, endpoint: u, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *S3Provider) Name() string {
+	return TYPE_S3
+}
+
+// 存储桶的主机和路径
+func (p *S3Provider) bucketHost() (string, string) {
+	if p.setting.PathStyle {
+		return p.endpoint.Host, p.endpoint.Path + "/" + p.setting.Bucket
+	}
+	return p.setting.Bucket + "." + p.endpoint.Host, p.endpoint.Path
+}
+
+func (p *S3Provider) objectURL(key string) string {
+	host, path := p.bucketHost()
+	return p.endpoint.Scheme + "://" + host + escapePath(path+"/"+key)
+}
+
+func (p *S3Provider) scope(date string) string {
+	return date + "/" + p.setting.Region + "/s3/aws4_request"
+}
+
+func (p *S3Provider) signature(date, data string) string {
+	key := signingKey(p.setting.SecretKey, date, p.setting.Region, "s3")
+	return hex.EncodeToString(hmacSHA256(key, data))
+}
+
+// 生成 POST 表单上传策略，token 为需附加到上传表单的字段(url 编码)
+// 表单中的 key 为 img/{uid}/${filename}，客户端需以文件的 etag 和扩展名作为文件名
+func (p *S3Provider) UploadToken(uid int, expire int64) (*Token, error) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	credential := p.setting.AccessKey + "/" + p.scope(date)
+	prefix := UserPrefix(uid)
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(time.Duration(expire) * time.Second).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": []interface{}{
+			map[string]string{"bucket": p.setting.Bucket},
+			[]interface{}{"starts-with", "$key", prefix},
+			[]interface{}{"content-length-range", 0, p.setting.MaxSize},
+			map[string]string{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+			map[string]string{"x-amz-credential": credential},
+			map[string]string{"x-amz-date": amzDate},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(policy)
+	fields := url.Values{}
+	fields.Set("key", prefix+"${filename}")
+	fields.Set("policy", encoded)
+	fields.Set("x-amz-algorithm", "AWS4-HMAC-SHA256")
+	fields.Set("x-amz-credential", credential)
+	fields.Set("x-amz-date", amzDate)
+	fields.Set("x-amz-signature", p.signature(date, encoded))
+	return &Token{
+		Token:      fields.Encode(),
+		UploadURL:  p.objectURL(""),
+		ExpireTime: now.Unix() + expire,
+	}, nil
+}
+
+func (p *S3Provider) VerifyCallback(req *http.Request) (bool, error) {
+	return false, nil
+}
+
+// 发送签名请求，有请求体时不对内容签名(UNSIGNED-PAYLOAD)
+func (p *S3Provider) do(method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	host, path := p.bucketHost()
+	path = escapePath(path + "/" + key)
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	canonical := strings.Join([]string{
+		method,
+		path,
+		rawQuery,
+		"host:" + host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		payloadHash,
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + p.scope(date) + "\n" + hex.EncodeToString(sum[:])
+	u := p.endpoint.Scheme + "://" + host + path
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+p.setting.AccessKey+"/"+p.scope(date)+
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="+p.signature(date, stringToSign))
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, os.ErrNotExist
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		res.Body.Close()
+		return nil, errors.New("S3 请求失败: " + res.Status + " " + string(body))
+	}
+	return res, nil
+}
+
+func (p *S3Provider) Put(key string, r io.Reader, size int64) error {
+	res, err := p.do(http.MethodPut, key, nil, r, size)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (p *S3Provider) Get(key string) (io.ReadCloser, error) {
+	res, err := p.do(http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (p *S3Provider) MaxSize() int64 {
+	return p.setting.MaxSize
+}
+
+func (p *S3Provider) Stat(key string) (*Object, error) {
+	res, err := p.do(http.MethodHead, key, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	size, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+	return &Object{Key: key, Hash: KeyHash(key), Size: size, Mime: res.Header.Get("Content-Type")}, nil
+}
+
+func (p *S3Provider) DownloadURL(key string) string {
+	if p.setting.Domain != "" {
+		return joinURL(p.setting.Domain, key)
+	}
+	return p.objectURL(key)
+}
+
+func (p *S3Provider) Delete(key string) error {
+	res, err := p.do(http.MethodDelete, key, nil, nil, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+type s3ListResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key string
+	}
+}
+
+func (p *S3Provider) DeletePrefix(prefix string) (int, error) {
+	if p.setting.AccessKey == "" {
+		return 0, nil
+	}
+	total := 0
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		res, err := p.do(http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return total, err
+		}
+		list := &s3ListResult{}
+		err = xml.NewDecoder(res.Body).Decode(list)
+		res.Body.Close()
+		if err != nil {
+			return total, err
+		}
+		for _, obj := range list.Contents {
+			res, err := p.do(http.MethodDelete, obj.Key, nil, nil, 0)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return total, err
+			}
+			if res != nil {
+				res.Body.Close()
+			}
+			total++
+		}
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			return total, nil
+		}
+		token = list.NextContinuationToken
+	}
+}
+
+// 签名密钥: 依次以日期、区域、服务和 aws4_request 派生
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// 按 S3 规则编码路径，保留非保留字符和 /
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
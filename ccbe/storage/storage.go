@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cc/be/setting"
+)
+
+// 存储方式
+const TYPE_LOCAL = "local"
+const TYPE_QINIU = "qiniu"
+const TYPE_S3 = "s3"
+
+// 上传凭证默认有效期(s)
+const DEFAULT_EXPIRE_TIME = 7200
+
+// 单个文件默认大小上限: 10M
+const DEFAULT_MAX_SIZE = 10485760
+
+// 用户文件统一保存在 img/{uid}/ 目录下，分享页面按 /img 前缀拼接静态资源域名
+const KEY_PREFIX = "img/"
+
+var ErrNotSupported = errors.New("当前存储方式不支持该操作")
+var ErrTooLarge = errors.New("文件大小超出限制")
+
+// 允许保留的文件扩展名
+var extRegexp = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// 小于 4M 的文件 etag 以 F 开头，大于 4M 的以 l 开头，均为 28 位
+var etagRegexp = regexp.MustCompile(`^[Fl][A-Za-z0-9_-]{27}$`)
+
+// 内容中引用的用户文件 img/{uid}/{etag}，兼容 JSON 中转义的 /
+var refRegexp = regexp.MustCompile(`img\\?/(\d+)\\?/([Fl][A-Za-z0-9_-]{27})`)
+
+// 上传凭证，UploadURL 为客户端上传地址
+type Token struct {
+	Token      string
+	UploadURL  string
+	ExpireTime int64
+}
+
+// 内容中引用的文件
+type Ref struct {
+	Uid  int
+	Hash string
+}
+
+// 已上传的文件信息
+type Object struct {
+	Key  string
+	Hash string
+	Size int64
+	Mime string
+}
+
+// 文件存储接口，接入新的存储服务时实现该接口
+type Provider interface {
+	// 存储方式名称
+	Name() string
+	// 生成用户的上传凭证，文件只能上传到 UserPrefix(uid) 目录下
+	UploadToken(uid int, expire int64) (*Token, error)
+	// 校验上传完成回调，不支持回调的存储返回 false
+	VerifyCallback(req *http.Request) (bool, error)
+	// 服务端写入文件，用于直接上传到服务端的文件
+	Put(key string, r io.Reader, size int64) error
+	// 读取文件内容，文件不存在时返回 os.ErrNotExist
+	Get(key string) (io.ReadCloser, error)
+	// 查询已上传文件的信息
+	Stat(key string) (*Object, error)
+	// 文件下载地址
+	DownloadURL(key string) string
+	// 删除文件，文件不存在时不返回错误
+	Delete(key string) error
+	// 删除指定前缀的全部文件，返回删除的文件数
+	DeletePrefix(prefix string) (int, error)
+	// 单个文件大小上限
+	MaxSize() int64
+}
+
+// 支持图片处理的存储，按宽度生成缩略图的下载地址，无需服务端生成
+type ImageProcessor interface {
+	ImageURL(key string, width int) string
+}
+
+// 默认使用本地磁盘，便于离线开发
+var provider Provider = NewLocalProvider(setting.LocalStorageSetting{})
+
+// 上传文件和分片的临时目录
+var tempDir = filepath.Join(os.TempDir(), "cardcool-upload")
+
+func Default() Provider {
+	return provider
+}
+
+// 用户文件目录
+func UserPrefix(uid int) string {
+	return KEY_PREFIX + strconv.Itoa(uid) + "/"
+}
+
+// 校验文件是否属于指定用户
+func IsUserKey(uid int, key string) bool {
+	return strings.HasPrefix(key, UserPrefix(uid)) && !strings.Contains(key, "..") && !strings.HasSuffix(key, "/")
+}
+
+// 根据配置初始化存储方式
+func Init(s *setting.StorageSetting) error {
+	if s == nil {
+		return nil
+	}
+	if s.TempDir != "" {
+		tempDir = s.TempDir
+	}
+	switch s.Provider {
+	case "", TYPE_LOCAL:
+		provider = NewLocalProvider(s.Local)
+	case TYPE_QINIU:
+		provider = NewQiniuProvider(s.Qiniu)
+	case TYPE_S3:
+		p, err := NewS3Provider(s.S3)
+		if err != nil {
+			return err
+		}
+		provider = p
+	default:
+		return errors.New("不支持的存储方式: " + s.Provider)
+	}
+	return nil
+}
+
+// 上传凭证有效期
+func ExpireTime(s *setting.StorageSetting) int64 {
+	if s == nil || s.ExpireTime <= 0 {
+		return DEFAULT_EXPIRE_TIME
+	}
+	return s.ExpireTime
+}
+
+func TempDir() string {
+	return tempDir
+}
+
+// 保留文件的扩展名，不合法时返回空
+func Ext(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !extRegexp.MatchString(ext) {
+		return ""
+	}
+	return ext
+}
+
+// 文件名中的 etag，即去掉目录和扩展名的部分
+func KeyHash(key string) string {
+	name := path.Base(key)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+func IsEtag(hash string) bool {
+	return etagRegexp.MatchString(hash)
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
+
+// 查找内容中引用的用户文件，下载地址和文件路径都可以匹配
+func FindRefs(content string) []Ref {
+	matches := refRegexp.FindAllStringSubmatch(content, -1)
+	refs := make([]Ref, 0, len(matches))
+	for _, m := range matches {
+		uid, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		refs = append(refs, Ref{Uid: uid, Hash: m[2]})
+	}
+	return refs
+}
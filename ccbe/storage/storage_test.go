@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cc/be/setting"
+)
+
+func TestEtag(t *testing.T) {
+	// 空文件的 etag 为七牛云文档中的固定值
+	if got := newEtag().String(); got != "Fto5o-5ea0sNMlW_75VgGJCv2AcJ" {
+		t.Fatalf("空文件 etag = %s", got)
+	}
+	// 超过 4M 时按块计算
+	data := bytes.Repeat([]byte("a"), etagBlockSize+10)
+	e := newEtag()
+	e.Write(data[:100])
+	e.Write(data[100:])
+	b1 := sha1.Sum(data[:etagBlockSize])
+	b2 := sha1.Sum(data[etagBlockSize:])
+	sum := sha1.Sum(append(b1[:], b2[:]...))
+	want := base64.URLEncoding.EncodeToString(append([]byte{0x96}, sum[:]...))
+	if got := e.String(); got != want {
+		t.Fatalf("分块 etag = %s, want %s", got, want)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	tempDir = t.TempDir()
+	if _, err := Spool(strings.NewReader(strings.Repeat("a", 17)), 16); err != ErrTooLarge {
+		t.Fatalf("超出大小限制应返回 ErrTooLarge: %v", err)
+	}
+	sp, err := Spool(strings.NewReader("hello"), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(sp.File)
+	if string(b) != "hello" || sp.Size != 5 {
+		t.Fatalf("Spool = %q %d", b, sp.Size)
+	}
+	e := newEtag()
+	e.Write([]byte("hello"))
+	if sp.Hash != e.String() {
+		t.Fatalf("Hash = %s", sp.Hash)
+	}
+	sp.Close()
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Fatal("临时文件未删除")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+	m, err := Inspect(r, "a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Mime != "image/png" || m.Width != 3 || m.Height != 2 {
+		t.Fatalf("Inspect = %+v", m)
+	}
+	if pos, _ := r.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Fatal("读取后未回到文件开头")
+	}
+	m, err = Inspect(strings.NewReader("a,b\n1,2\n"), "a.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(m.Mime, "text/csv") || m.Width != 0 {
+		t.Fatalf("Inspect = %+v", m)
+	}
+}
+
+func TestLocalProvider(t *testing.T) {
+	root := t.TempDir()
+	p := NewLocalProvider(setting.LocalStorageSetting{Root: root, BaseURL: "http://127.0.0.1:6789/", Secret: "secret"})
+	token, err := p.UploadToken(7, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.UploadURL != "http://127.0.0.1:6789/api/upload" {
+		t.Fatalf("UploadURL = %s", token.UploadURL)
+	}
+	if _, err := p.VerifyToken(token.Token + "x"); err != ErrInvalidToken {
+		t.Fatalf("篡改的凭证应校验失败: %v", err)
+	}
+	if uid, err := p.VerifyToken(token.Token); err != nil || uid != 7 {
+		t.Fatalf("VerifyToken = %d, %v", uid, err)
+	}
+	expired, _ := p.UploadToken(7, -1)
+	if _, err := p.VerifyToken(expired.Token); err != ErrInvalidToken {
+		t.Fatalf("过期的凭证应校验失败: %v", err)
+	}
+	key := UserPrefix(7) + "abc" + Ext("../A.PNG")
+	if err := p.Put(key, strings.NewReader("hello"), 5); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := p.Stat(key)
+	if err != nil || stat.Hash != "abc" || stat.Size != 5 || stat.Key != "img/7/abc.png" {
+		t.Fatalf("Stat = %+v, %v", stat, err)
+	}
+	if got := p.DownloadURL(key); got != "http://127.0.0.1:6789/img/7/abc.png" {
+		t.Fatalf("DownloadURL = %s", got)
+	}
+	rc, err := p.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "hello" {
+		t.Fatalf("Get = %q", b)
+	}
+	n, err := p.DeletePrefix(UserPrefix(7))
+	if err != nil || n != 1 {
+		t.Fatalf("DeletePrefix = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "img", "7")); !os.IsNotExist(err) {
+		t.Fatal("用户目录未删除")
+	}
+	if n, err := p.DeletePrefix(UserPrefix(8)); err != nil || n != 0 {
+		t.Fatalf("删除不存在的目录 = %d, %v", n, err)
+	}
+}
+
+func TestIsUserKey(t *testing.T) {
+	cases := map[string]bool{
+		"img/7/abc.png":    true,
+		"img/70/abc.png":   false,
+		"img/7/../8/a.png": false,
+		"img/7/":           false,
+	}
+	for key, want := range cases {
+		if got := IsUserKey(7, key); got != want {
+			t.Errorf("IsUserKey(%s) = %v", key, got)
+		}
+	}
+}
+
+func TestFindRefs(t *testing.T) {
+	hash := "Fo" + strings.Repeat("a", 26)
+	content := `{"src":"https://cdn.example.com/img/7/` + hash + `.png"}` +
+		`{"src":"img\/8\/` + hash + `"}` +
+		`{"src":"img/9/Fshort.png"}`
+	refs := FindRefs(content)
+	if len(refs) != 2 || refs[0] != (Ref{7, hash}) || refs[1] != (Ref{8, hash}) {
+		t.Fatalf("FindRefs = %+v", refs)
+	}
+}
+
+// AWS 文档中派生签名密钥的示例
+func TestSigningKey(t *testing.T) {
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if got := hex.EncodeToString(key); got != "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d" {
+		t.Fatalf("signingKey = %s", got)
+	}
+}
+
+func TestS3DeletePrefix(t *testing.T) {
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=ak/") || r.Header.Get("x-amz-date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Path != "/bucket/" || r.URL.Query().Get("prefix") != "img/7/" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("continuation-token") == "" {
+				w.Write([]byte(`<ListBucketResult><IsTruncated>true</IsTruncated><NextContinuationToken>next</NextContinuationToken><Contents><Key>img/7/a b.png</Key></Contents></ListBucketResult>`))
+				return
+			}
+			w.Write([]byte(`<ListBucketResult><IsTruncated>false</IsTruncated><Contents><Key>img/7/c.png</Key></Contents></ListBucketResult>`))
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodHead:
+			w.Header().Set("Content-Length", "12")
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			if r.URL.Path != "/bucket/img/7/d.png" || string(b) != "hello" || r.Header.Get("x-amz-content-sha256") != "UNSIGNED-PAYLOAD" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	}))
+	defer server.Close()
+	p, err := NewS3Provider(setting.S3Setting{Endpoint: server.URL, Bucket: "bucket", AccessKey: "ak", SecretKey: "sk", PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := p.DeletePrefix(UserPrefix(7))
+	if err != nil || n != 2 {
+		t.Fatalf("DeletePrefix = %d, %v", n, err)
+	}
+	if strings.Join(deleted, ",") != "/bucket/img/7/a%20b.png,/bucket/img/7/c.png" {
+		t.Fatalf("deleted = %v", deleted)
+	}
+	obj, err := p.Stat("img/7/c.png")
+	if err != nil || obj.Hash != "c" || obj.Size != 12 {
+		t.Fatalf("Stat = %+v, %v", obj, err)
+	}
+	if err := p.Put("img/7/d.png", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("Put = %v", err)
+	}
+	token, err := p.UploadToken(7, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := url.ParseQuery(token.Token)
+	if err != nil || fields.Get("key") != "img/7/${filename}" || fields.Get("x-amz-signature") == "" {
+		t.Fatalf("UploadToken = %+v, %v", token, err)
+	}
+	if token.UploadURL != server.URL+"/bucket/" {
+		t.Fatalf("UploadURL = %s", token.UploadURL)
+	}
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "net"
+
+// 匿名化 IP 地址：IPv4 保留前 24 位，IPv6 保留前 48 位
+func AnonymizeIP(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return addr.Mask(net.CIDRMask(48, 128)).String()
+}
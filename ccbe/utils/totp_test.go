@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 密钥 "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取 8 位验证码的后 6 位
+func TestTotpCode(t *testing.T) {
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for ts, want := range cases {
+		got, err := TotpCode(rfcSecret, TotpCounter(time.Unix(ts, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("TotpCode(%d) = %s, want %s", ts, got, want)
+		}
+	}
+	if _, err := TotpCode("not base32!", 1); err == nil {
+		t.Fatal("非法密钥应返回错误")
+	}
+}
+
+func TestVerifyTotp(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := TotpCounter(now)
+	code := func(c int64) string {
+		s, _ := TotpCode(rfcSecret, c)
+		return s
+	}
+	// 允许前后偏移一步
+	for _, c := range []int64{counter - 1, counter, counter + 1} {
+		if got, ok := VerifyTotp(rfcSecret, code(c), now, 0); !ok || got != c {
+			t.Errorf("步数 %d 的验证码校验 = %d, %v", c, got, ok)
+		}
+	}
+	for _, c := range []int64{counter - 2, counter + 2} {
+		if _, ok := VerifyTotp(rfcSecret, code(c), now, 0); ok {
+			t.Errorf("步数 %d 超出允许的偏移", c)
+		}
+	}
+	if _, ok := VerifyTotp(rfcSecret, code(counter)[:5], now, 0); ok {
+		t.Error("位数不正确的验证码应校验失败")
+	}
+	// 已使用的步数不能再次使用
+	last, ok := VerifyTotp(rfcSecret, code(counter), now, 0)
+	if !ok {
+		t.Fatal("验证码校验失败")
+	}
+	if _, ok := VerifyTotp(rfcSecret, code(counter), now, last); ok {
+		t.Error("重放的验证码应校验失败")
+	}
+	if _, ok := VerifyTotp(rfcSecret, code(counter-1), now, last); ok {
+		t.Error("早于已使用步数的验证码应校验失败")
+	}
+	if got, ok := VerifyTotp(rfcSecret, code(counter+1), now, last); !ok || got != counter+1 {
+		t.Errorf("之后步数的验证码校验 = %d, %v", got, ok)
+	}
+}
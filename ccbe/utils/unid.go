@@ -1,6 +1,9 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"math/rand"
 	"time"
 )
@@ -44,3 +47,21 @@ func UnidByNum(num int) string {
 	}
 	return string(arr) + RandStrBySeed(num-7)
 }
+
+// 生成加密安全的随机字符串，用于令牌等敏感凭证
+func SecureRandStr(n int) string {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = base[int(b[i])%int(cnt)]
+	}
+	return string(b)
+}
+
+// 计算字符串的 sha256 摘要
+func Sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
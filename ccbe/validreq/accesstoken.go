@@ -0,0 +1,13 @@
+package validreq
+
+// 创建个人访问令牌
+type CreateAccessTokenReq struct {
+	Name       string   `json:"name" binding:"required,max=32"`
+	Scopes     []string `json:"scopes" binding:"required"`
+	ExpireTime int      `json:"expire_time"`
+}
+
+// 撤销个人访问令牌
+type RevokeAccessTokenReq struct {
+	Id int `json:"id" binding:"required,min=1"`
+}
@@ -1,8 +1,15 @@
 package validreq
 
+// max_uses 为 0 时不限使用次数，limit_type 为 1 时默认只能使用一次
 type GenerateCodesReq struct {
 	LimitType int8 `json:"limit_type"`
 	StartTime int  `json:"start_time"`
 	EndTime   int  `json:"end_time"`
+	MaxUses   int  `json:"max_uses"`
 	Num       int  `json:"num" binding:"required"`
 }
+
+// 作废邀请码
+type RevokeInviteReq struct {
+	Id int `json:"id" binding:"required,min=1"`
+}
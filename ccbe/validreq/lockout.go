@@ -0,0 +1,7 @@
+package validreq
+
+// 解除登录或注册锁定
+type UnlockReq struct {
+	Scope string `json:"scope" binding:"required"`
+	Value string `json:"value" binding:"required"`
+}
@@ -0,0 +1,9 @@
+package validreq
+
+// 设置用户存储额度，plan 为空时使用默认套餐，hard_limit/soft_limit 为 0 时使用套餐的额度
+type UpdateQuotaReq struct {
+	Uid       int    `json:"uid" binding:"required,min=1"`
+	Plan      string `json:"plan"`
+	HardLimit int64  `json:"hard_limit" binding:"min=0"`
+	SoftLimit int64  `json:"soft_limit" binding:"min=0"`
+}
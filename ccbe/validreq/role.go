@@ -0,0 +1,7 @@
+package validreq
+
+// 授予或撤销用户角色
+type UserRoleReq struct {
+	Uid  int    `json:"uid" binding:"required,min=1"`
+	Role string `json:"role" binding:"required"`
+}
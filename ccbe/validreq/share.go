@@ -1,12 +1,22 @@
 package validreq
 
 // 创建或刷新视图分享
+// mode 为 1 时为实时分享，访问时根据视图当前数据生成内容，无需上传 content
+// 不传 password 时不修改访问密码，传空字符串时取消访问密码；expire_time 为 0 时不过期；max_views 为 0 时不限访问次数
+// rev_public 为 1 时允许通过分享链接访问快照分享的历史版本
+// embed_origins 为允许嵌入的来源，多个以空格分隔，* 为任意来源，空字符串时禁止嵌入，不传时不修改
 type CreateShareReq struct {
-	ViewId  string `json:"view_id" binding:"required"`
-	Name    string `json:"name" binding:"required"`
-	Type    int8   `json:"type"`
-	Icon    string `json:"icon" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	ViewId       string  `json:"view_id" binding:"required"`
+	Name         string  `json:"name" binding:"required"`
+	Type         int8    `json:"type"`
+	Icon         string  `json:"icon" binding:"required"`
+	Mode         int8    `json:"mode" binding:"oneof=0 1"`
+	Content      string  `json:"content" binding:"required_if=Mode 0"`
+	Password     *string `json:"password" binding:"omitempty,max=32"`
+	ExpireTime   int     `json:"expire_time" binding:"min=0"`
+	MaxViews     int     `json:"max_views" binding:"min=0"`
+	RevPublic    int8    `json:"rev_public" binding:"oneof=0 1"`
+	EmbedOrigins *string `json:"embed_origins" binding:"omitempty,max=512"`
 }
 
 // 更新视图分享状态
@@ -14,3 +24,36 @@ type UpdateShareStatusReq struct {
 	ViewId string `json:"view_id" binding:"required"`
 	Status int8   `json:"status"`
 }
+
+// 输入访问密码获取分享访问令牌
+type ShareAccessReq struct {
+	ShareId  string `json:"share_id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// 创建或刷新卡片分享，访问限制和嵌入参数与视图分享一致
+type CreateCardShareReq struct {
+	CardId       string  `json:"card_id" binding:"required"`
+	Password     *string `json:"password" binding:"omitempty,max=32"`
+	ExpireTime   int     `json:"expire_time" binding:"min=0"`
+	MaxViews     int     `json:"max_views" binding:"min=0"`
+	EmbedOrigins *string `json:"embed_origins" binding:"omitempty,max=512"`
+}
+
+// 更新卡片分享状态
+type UpdateCardShareStatusReq struct {
+	CardId string `json:"card_id" binding:"required"`
+	Status int8   `json:"status"`
+}
+
+// 固定公开的分享版本，rev 为 0 时取消固定
+type PinShareRevisionReq struct {
+	ViewId string `json:"view_id" binding:"required"`
+	Rev    int    `json:"rev" binding:"min=0"`
+}
+
+// 回滚分享到指定的历史版本
+type RollbackShareRevisionReq struct {
+	ViewId string `json:"view_id" binding:"required"`
+	Rev    int    `json:"rev" binding:"required,min=1"`
+}
@@ -0,0 +1,26 @@
+package validreq
+
+// 根据用户编码邀请成员加入空间，role: 2-编辑者，3-查看者
+type InviteSpaceMemberReq struct {
+	SpaceId string `json:"space_id" binding:"required"`
+	Code    string `json:"code" binding:"required"`
+	Role    int8   `json:"role" binding:"oneof=2 3"`
+}
+
+// 修改空间成员角色
+type UpdateSpaceMemberReq struct {
+	SpaceId string `json:"space_id" binding:"required"`
+	Uid     int    `json:"uid" binding:"required,min=1"`
+	Role    int8   `json:"role" binding:"oneof=2 3"`
+}
+
+// 移除空间成员，uid 为当前用户时退出空间
+type RemoveSpaceMemberReq struct {
+	SpaceId string `json:"space_id" binding:"required"`
+	Uid     int    `json:"uid" binding:"required,min=1"`
+}
+
+// 接受或拒绝空间邀请
+type SpaceInviteReq struct {
+	SpaceId string `json:"space_id" binding:"required"`
+}
@@ -6,34 +6,78 @@ type LoginReq struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// 两步验证登录，code 为认证器验证码或恢复码
+type LoginTotpReq struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+// 第三方授权回调，provider 为空时默认为微信
 type CallbackReq struct {
-	Code       string `json:"code" binding:"required"`
-	InviteCode string `json:"invite_code"`
+	Provider string `json:"provider"`
+	Code     string `json:"code" binding:"required"`
+	State    string `json:"state" binding:"required"`
 }
 
 type RegisterReq struct {
-	Mobile string `json:"mobile" binding:"required,len=11"`
-	Code   string `json:"code" binding:"required"`
-	// AuthCode string `json:"auth_code" binding:"required"`
+	Mobile   string `json:"mobile" binding:"required,len=11"`
+	Code     string `json:"code" binding:"required"`
+	AuthCode string `json:"auth_code" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// 获取验证码，purpose: register-注册账号，reset_password-重置密码
+type SendCodeReq struct {
+	Purpose string `json:"purpose" binding:"required"`
+	Mobile  string `json:"mobile" binding:"required,len=11"`
+}
+
+// 通过短信验证码重置密码
+type ResetPasswordReq struct {
+	Mobile   string `json:"mobile" binding:"required,len=11"`
+	AuthCode string `json:"auth_code" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// 根据授权回调下发的身份凭证和邀请码注册账号
 type RegcodeReq struct {
-	Code     string `json:"code" binding:"required"`
-	Openid   string `json:"openid" binding:"required,min=20"`
-	Unionid  string `json:"unionid"`
-	Username string `json:"username"`
-	Avatar   string `json:"avatar"`
+	Code   string `json:"code" binding:"required"`
+	Ticket string `json:"ticket" binding:"required"`
 }
 
-// 根据微信扫码授权回调绑定原手机账号
+// 根据授权回调下发的身份凭证绑定原手机账号
 type BindUserReq struct {
 	Mobile   string `json:"mobile" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Openid   string `json:"openid" binding:"required,min=20"`
-	Unionid  string `json:"unionid"`
-	Username string `json:"username"`
-	Avatar   string `json:"avatar"`
+	Ticket   string `json:"ticket" binding:"required"`
+}
+
+// 登记客户端直传完成的文件，name 为原始文件名，mime 为空时使用存储记录的类型
+type UploadCompleteReq struct {
+	Key    string `json:"key" binding:"required"`
+	Name   string `json:"name" binding:"max=255"`
+	Mime   string `json:"mime" binding:"max=128"`
+	Width  int    `json:"width" binding:"min=0"`
+	Height int    `json:"height" binding:"min=0"`
+}
+
+// 创建分片上传任务，size 为文件大小(字节)
+type InitChunkUploadReq struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required,gt=0"`
+}
+
+type ChunkUploadReq struct {
+	UploadId string `json:"upload_id" binding:"required"`
+}
+
+type DeleteFileReq struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// 从文件库批量删除文件
+type DeleteFilesReq struct {
+	Ids []int `json:"ids" binding:"required,min=1,max=100"`
 }
 
 // 七牛云文件上传回调
@@ -41,5 +85,7 @@ type QiniuCallbackReq struct {
 	Key   string `json:"key" binding:"required"`
 	Hash  string `json:"hash" binding:"required"`
 	Fsize int64  `json:"fsize" binding:"required"`
+	Fname string `json:"fname"`
+	Mime  string `json:"mime"`
 	Uid   int    `json:"uid" binding:"required"`
 }
@@ -0,0 +1,11 @@
+package validreq
+
+// 两步验证操作，code 为认证器验证码，关闭时也可使用恢复码
+type TotpCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// 管理员重置用户两步验证
+type ResetTotpReq struct {
+	Uid int `json:"uid" binding:"required,min=1"`
+}
@@ -4,15 +4,29 @@ type AddAccountReq struct {
 	Mobile string `json:"mobile" binding:"required"`
 }
 
-type BindWechatReq struct {
-	Code string `json:"code" binding:"required"`
+// 已登录用户绑定第三方身份，provider 为空时默认为微信
+type BindIdentityReq struct {
+	Provider string `json:"provider"`
+	Code     string `json:"code" binding:"required"`
+	State    string `json:"state" binding:"required"`
 }
 
+type UnbindIdentityReq struct {
+	Id int `json:"id" binding:"required,min=1"`
+}
+
+// 手机号变更时需要 auth_code 短信验证码
 type UpdateMobileAccountReq struct {
 	Mobile      string `json:"mobile" binding:"required"`
 	EditType    int32  `json:"edit_type" binding:"required"`
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
+	AuthCode    string `json:"auth_code"`
+}
+
+// 获取修改手机号的验证码
+type SendMobileCodeReq struct {
+	Mobile string `json:"mobile" binding:"required,len=11"`
 }
 
 type UpdateUserinfoReq struct {
@@ -28,6 +42,8 @@ type ChangeReq struct {
 	Mobile   string `json:"mobile" binding:"required"`
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// 修改手机号时需要新手机号的验证码
+	AuthCode string `json:"auth_code"`
 }
 
 type UpdateClientReq struct {
@@ -35,3 +51,9 @@ type UpdateClientReq struct {
 	Baidu   string `json:"baidu" binding:"required"`
 	Kuake   string `json:"kuake" binding:"required"`
 }
+
+// 申请注销账号，已设置密码时需要 password，启用两步验证时需要 code
+type RequestDeletionReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
@@ -41,6 +41,51 @@ VALUES
 	(1, 'U8QjJnZ5284h', 'U8QjJnOEulR5', 'U8QjJnPAhsmH', '孙悟空', '[]', '{\"TdqTDfDqrVq_\":\"0101-01-01\",\"TdqTMYfggFt_\":\"16666666666\",\"TdqTQeDUqLt_\":\"TdqTcWLVedx_\",\"TdqTLBKlOZl_\":\"花果山水帘洞\",\"links\":[\"U8QjJnYS69XS\"]}', '{\"type\":\"doc\",\"content\":[{\"type\":\"blockquote\",\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"猴哥猴哥，你真了不得！\"}]}]},{\"type\":\"nbl\",\"content\":[{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"护送 \"},{\"type\":\"mention\",\"attrs\":{\"id\":\"U8QjJnYS69XS\",\"label\":\"唐僧\",\"type\":1,\"icon\":\"card\"}},{\"type\":\"text\",\"text\":\" 西天取经，降妖除魔，历经九九八十一难，终成正果，封\"},{\"type\":\"text\",\"marks\":[{\"type\":\"bold\"}],\"text\":\"斗战神佛\"},{\"type\":\"text\",\"text\":\"！\"}]}]}]}]}', 0, 1711731115781, 0, 0),
 	(1, 'U8QjJna22vQv', 'U8QjJnOEulR5', 'U8QjJnQkcD2Z', '西游第一日', '[]', '{\"U0gWTEhJkRJ_\":\"0629-06-06\",\"links\":[\"U8QjJnYS69XS\",\"U8QjJnZ5284h\"]}', '{\"type\":\"doc\",\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"净业寺里， \"},{\"type\":\"mention\",\"attrs\":{\"id\":\"U8QjJnYS69XS\",\"label\":\"唐僧\",\"type\":1,\"icon\":\"card\"}},{\"type\":\"text\",\"text\":\" 与 \"},{\"type\":\"mention\",\"attrs\":{\"id\":\"U8QjJnZ5284h\",\"label\":\"孙悟空\",\"type\":1,\"icon\":\"card\"}},{\"type\":\"text\",\"text\":\" 对视一笑，眼中藏着迷人的火光。\"}]},{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"猪八戒嘴角挑起，歪嘴邪笑，沙悟净则俏皮地眨眼。\"}]},{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"一场禁忌的邂逅，心跳不已。\"}]},{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"唐僧心头涌起莫名的悸动，四人的相遇，注定要引发一场爱的冒险。\"}]}]}', 0, 1711731115782, 0, 0);
 
+# Dump of table accesstoken
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `accesstoken`;
+
+CREATE TABLE `accesstoken` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `name` varchar(32) NOT NULL DEFAULT '' COMMENT '令牌名称',
+  `prefix` varchar(16) NOT NULL DEFAULT '' COMMENT '令牌前缀，用于展示',
+  `hash` char(64) NOT NULL DEFAULT '' COMMENT '令牌 sha256',
+  `scopes` varchar(256) NOT NULL DEFAULT '' COMMENT '权限范围，逗号分隔',
+  `expire_time` int unsigned NOT NULL DEFAULT '0' COMMENT '过期时间，0-永不过期',
+  `last_used_time` int unsigned NOT NULL DEFAULT '0' COMMENT '最近使用时间',
+  `status` tinyint NOT NULL DEFAULT '1' COMMENT '状态，1-有效，0-已撤销',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_hash` (`hash`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='个人访问令牌表';
+
+
+
+# Dump of table auditlog
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `auditlog`;
+
+CREATE TABLE `auditlog` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '操作用户 id',
+  `action` varchar(32) NOT NULL DEFAULT '' COMMENT '操作权限名称',
+  `path` varchar(128) NOT NULL DEFAULT '' COMMENT '请求路由',
+  `target` varchar(256) NOT NULL DEFAULT '' COMMENT '操作对象',
+  `ip` varchar(64) NOT NULL DEFAULT '' COMMENT '客户端 IP',
+  `code` int NOT NULL DEFAULT '0' COMMENT '响应错误码，0-成功',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_uid` (`uid`),
+  KEY `idx_action` (`action`)
+) ENGINE=InnoDB COMMENT='特权操作审计日志表';
+
+
+
 # Dump of table card
 # ------------------------------------------------------------
 
@@ -67,6 +112,49 @@ CREATE TABLE `card` (
 
 
 
+# Dump of table deletion
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `deletion`;
+
+CREATE TABLE `deletion` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态，0-等待清除，1-已撤销，2-已清除',
+  `purge_time` int unsigned NOT NULL DEFAULT '0' COMMENT '计划清除时间',
+  `finish_time` int unsigned NOT NULL DEFAULT '0' COMMENT '实际清除时间',
+  `receipt` text COMMENT '清除回执(JSON)',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_uid` (`uid`),
+  KEY `idx_status_purge_time` (`status`,`purge_time`)
+) ENGINE=InnoDB COMMENT='账号注销记录表';
+
+
+
+# Dump of table fileblob
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `fileblob`;
+
+CREATE TABLE `fileblob` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `hash` varchar(28) NOT NULL DEFAULT '' COMMENT '文件 hash',
+  `file_key` varchar(255) NOT NULL DEFAULT '' COMMENT '存储路径',
+  `size` int unsigned NOT NULL DEFAULT '0' COMMENT '文件大小(B)',
+  `variants` varchar(128) NOT NULL DEFAULT '' COMMENT '已生成的缩略图规格，逗号分隔',
+  `ref_count` int unsigned NOT NULL DEFAULT '0' COMMENT '持有该文件的用户数',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_hash` (`hash`),
+  KEY `idx_file_key` (`file_key`),
+  KEY `idx_ref_count_update_time` (`ref_count`,`update_time`)
+) ENGINE=InnoDB COMMENT='文件内容表';
+
+
+
 # Dump of table filelog
 # ------------------------------------------------------------
 
@@ -76,15 +164,45 @@ CREATE TABLE `filelog` (
   `id` int unsigned NOT NULL AUTO_INCREMENT,
   `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
   `hash` varchar(28) NOT NULL DEFAULT '' COMMENT '文件 hash',
+  `file_key` varchar(255) NOT NULL DEFAULT '' COMMENT '存储路径',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '原始文件名',
+  `mime` varchar(128) NOT NULL DEFAULT '' COMMENT 'MIME 类型',
+  `width` int unsigned NOT NULL DEFAULT '0' COMMENT '图片宽度',
+  `height` int unsigned NOT NULL DEFAULT '0' COMMENT '图片高度',
+  `ref_count` int unsigned NOT NULL DEFAULT '1' COMMENT '引用计数',
   `size` int unsigned NOT NULL DEFAULT '0' COMMENT '文件大小(B)',
+  `orphan_time` int unsigned NOT NULL DEFAULT '0' COMMENT '未被引用的时间，0-被引用',
   `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uniq_hash` (`hash`),
+  UNIQUE KEY `uniq_uid_hash` (`uid`,`hash`),
   KEY `idx_uid_size` (`uid`,`size`)
 ) ENGINE=InnoDB COMMENT='文件上传记录表';
 
 
 
+# Dump of table identity
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `identity`;
+
+CREATE TABLE `identity` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `provider` varchar(32) NOT NULL DEFAULT '' COMMENT '身份提供方：wechat 或配置的 OIDC 名称',
+  `subject` varchar(128) NOT NULL DEFAULT '' COMMENT '提供方内的用户标识：openid/sub',
+  `unionid` varchar(64) NOT NULL DEFAULT '' COMMENT '微信 unionid',
+  `username` varchar(64) NOT NULL DEFAULT '' COMMENT '第三方昵称',
+  `avatar` varchar(256) NOT NULL DEFAULT '' COMMENT '第三方头像',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_provider_subject` (`provider`,`subject`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='第三方身份关联表';
+
+
+
 # Dump of table invite
 # ------------------------------------------------------------
 
@@ -96,7 +214,9 @@ CREATE TABLE `invite` (
   `limit_type` tinyint NOT NULL DEFAULT '0' COMMENT '类型：0-无限制，1-一次性',
   `start_time` int unsigned NOT NULL DEFAULT '0' COMMENT '开始时间',
   `end_time` int unsigned NOT NULL DEFAULT '0' COMMENT '截止时间',
-  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态，0-正常，-1-已删除',
+  `max_uses` int unsigned NOT NULL DEFAULT '0' COMMENT '最大使用次数，0-不限',
+  `used_count` int unsigned NOT NULL DEFAULT '0' COMMENT '已使用次数',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态，0-正常，1-已用完，-1-已作废',
   `create_uid` int unsigned NOT NULL DEFAULT '0' COMMENT '创建邀请码的用户 id',
   `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
   `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
@@ -127,6 +247,42 @@ VALUES
 	(1, 'U8QjJniSBGWq', 9, '{\"type\":\"doc\",\"content\":[{\"type\":\"blockquote\",\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"问题反馈、意见建议、学习交流，欢迎加开发者微信（\"},{\"type\":\"text\",\"marks\":[{\"type\":\"bold\"}],\"text\":\"cardcool666\"},{\"type\":\"text\",\"text\":\"）\"}]}]},{\"type\":\"heading\",\"attrs\":{\"level\":3},\"content\":[{\"type\":\"text\",\"text\":\"文档基本功能\"}]},{\"type\":\"nbl\",\"content\":[{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"支持常用 \"},{\"type\":\"text\",\"marks\":[{\"type\":\"code\"}],\"text\":\"Markdown\"},{\"type\":\"text\",\"text\":\" 语法\"}]}]},{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"使用 \"},{\"type\":\"text\",\"marks\":[{\"type\":\"code\"}],\"text\":\"/\"},{\"type\":\"text\",\"text\":\" 可唤起命令\"}]}]},{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"选中文本可弹窗浮动菜单，修改文本样式\"}]}]},{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"输入 \"},{\"type\":\"text\",\"marks\":[{\"type\":\"code\"}],\"text\":\"@+关键词\"},{\"type\":\"text\",\"text\":\" 可引用卡片或其他视图， \"},{\"type\":\"mention\",\"attrs\":{\"id\":\"U8QjJnZ5284h\",\"label\":\"孙悟空\",\"type\":1,\"icon\":\"card\"}},{\"type\":\"text\",\"text\":\" \"}]}]}]},{\"type\":\"heading\",\"attrs\":{\"level\":3},\"content\":[{\"type\":\"text\",\"text\":\"功能规划\"}]},{\"type\":\"nbl\",\"content\":[{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"更完善的编辑体验\"}]}]},{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"文档、大纲、白板、看板多种视图融合，可在一个页面同时打开多个视图\"}]}]},{\"type\":\"nli\",\"attrs\":{\"coll\":false},\"content\":[{\"type\":\"paragraph\",\"content\":[{\"type\":\"text\",\"text\":\"面向应用场景进行功能迭代…\"}]}]}]},{\"type\":\"paragraph\"},{\"type\":\"paragraph\"}]}');
 
 
+# Dump of table recoverycode
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `recoverycode`;
+
+CREATE TABLE `recoverycode` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `hash` char(64) NOT NULL DEFAULT '' COMMENT '恢复码 sha256 摘要',
+  `used_time` int unsigned NOT NULL DEFAULT '0' COMMENT '使用时间，0-未使用',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_uid_hash` (`uid`,`hash`)
+) ENGINE=InnoDB COMMENT='两步验证恢复码表';
+
+
+
+# Dump of table redemption
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `redemption`;
+
+CREATE TABLE `redemption` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `invite_id` int unsigned NOT NULL DEFAULT '0' COMMENT '邀请码 id',
+  `code` varchar(12) NOT NULL DEFAULT '' COMMENT '邀请码',
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '注册用户 id',
+  `pid` int unsigned NOT NULL DEFAULT '0' COMMENT '邀请人用户 id',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '使用时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_invite_id` (`invite_id`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='邀请码使用记录表';
+
+
+
 # Dump of table share
 # ------------------------------------------------------------
 
@@ -137,17 +293,69 @@ CREATE TABLE `share` (
   `uuid` varchar(24) NOT NULL DEFAULT '' COMMENT 'uuid',
   `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户id',
   `view_id` varchar(12) NOT NULL DEFAULT '' COMMENT '视图 id',
+  `card_id` varchar(12) NOT NULL DEFAULT '' COMMENT '卡片 id，卡片分享时不为空',
   `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称',
   `type` tinyint(1) NOT NULL DEFAULT '0' COMMENT '视图类型: 0-List, 1-Graph',
   `icon` varchar(16) NOT NULL DEFAULT '' COMMENT 'Icon',
   `status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '状态: 0-失效, 1-有效',
+  `mode` tinyint(1) NOT NULL DEFAULT '0' COMMENT '分享模式: 0-快照, 1-实时',
   `content` text NOT NULL COMMENT '视图内容',
+  `password` varchar(60) NOT NULL DEFAULT '' COMMENT '访问密码 bcrypt 摘要，空-无需密码',
+  `expire_time` int unsigned NOT NULL DEFAULT '0' COMMENT '过期时间，0-不过期',
+  `max_views` int unsigned NOT NULL DEFAULT '0' COMMENT '最大访问次数，0-不限',
+  `view_count` int unsigned NOT NULL DEFAULT '0' COMMENT '访问次数',
+  `rev` int unsigned NOT NULL DEFAULT '0' COMMENT '最新的历史版本号',
+  `pin_rev` int unsigned NOT NULL DEFAULT '0' COMMENT '固定公开的版本号，0-公开最新版本',
+  `rev_public` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否允许通过链接访问历史版本: 0-否, 1-是',
+  `embed_origins` varchar(512) NOT NULL DEFAULT '' COMMENT '允许嵌入的来源，多个以空格分隔，*-任意来源，空-禁止嵌入',
   `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
   `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
   PRIMARY KEY (`id`),
   UNIQUE KEY `idx_uuid` (`uuid`),
-  UNIQUE KEY `idx_uid_view_id` (`uid`,`view_id`)
-) ENGINE=InnoDB COMMENT='视图分享表';
+  UNIQUE KEY `idx_uid_view_id_card_id` (`uid`,`view_id`,`card_id`)
+) ENGINE=InnoDB COMMENT='视图和卡片分享表';
+
+
+
+# Dump of table sharerevision
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `sharerevision`;
+
+CREATE TABLE `sharerevision` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `share_id` int unsigned NOT NULL DEFAULT '0' COMMENT '分享 id',
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '分享者用户 id',
+  `rev` int unsigned NOT NULL DEFAULT '0' COMMENT '版本号',
+  `name` varchar(32) NOT NULL DEFAULT '' COMMENT '名称',
+  `type` tinyint(1) NOT NULL DEFAULT '0' COMMENT '视图类型: 0-List, 1-Graph',
+  `icon` varchar(16) NOT NULL DEFAULT '' COMMENT 'Icon',
+  `content` mediumtext NOT NULL COMMENT '视图内容',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_share_id_rev` (`share_id`,`rev`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='视图分享历史版本表';
+
+
+
+# Dump of table shareview
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `shareview`;
+
+CREATE TABLE `shareview` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `share_id` int unsigned NOT NULL DEFAULT '0' COMMENT '分享 id',
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '分享者用户 id',
+  `ip` varchar(40) NOT NULL DEFAULT '' COMMENT '匿名化后的访问 IP',
+  `visitor` char(16) NOT NULL DEFAULT '' COMMENT '访客标识，匿名 IP 和 UA 的摘要',
+  `referer` varchar(256) NOT NULL DEFAULT '' COMMENT '来源地址',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '访问时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_share_id_create_time` (`share_id`,`create_time`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='视图分享访问记录表';
 
 
 
@@ -177,6 +385,29 @@ VALUES
 	(1, 'U8QjJnOEulR5', '默认空间', 'planet', '你的默认卡片空间！', 10000, 1711731115770, 0, 0);
 
 
+# Dump of table spacemember
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `spacemember`;
+
+CREATE TABLE `spacemember` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `space_id` varchar(12) NOT NULL DEFAULT '' COMMENT '空间id',
+  `owner_uid` int unsigned NOT NULL DEFAULT '0' COMMENT '空间所有者用户id',
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '成员用户id',
+  `role` tinyint(1) NOT NULL DEFAULT '3' COMMENT '成员角色: 2-编辑者, 3-查看者',
+  `status` tinyint(1) NOT NULL DEFAULT '0' COMMENT '成员状态: 0-已邀请, 1-已加入',
+  `invite_uid` int unsigned NOT NULL DEFAULT '0' COMMENT '邀请人用户id',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_space_id_uid` (`space_id`,`uid`),
+  KEY `idx_owner_uid_space_id` (`owner_uid`,`space_id`),
+  KEY `idx_uid_status` (`uid`,`status`)
+) ENGINE=InnoDB COMMENT='共享空间成员表';
+
+
+
 # Dump of table tag
 # ------------------------------------------------------------
 
@@ -201,6 +432,25 @@ CREATE TABLE `tag` (
 
 
 
+# Dump of table totp
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `totp`;
+
+CREATE TABLE `totp` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `secret` varchar(64) NOT NULL DEFAULT '' COMMENT 'TOTP 密钥(base32)',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态，0-待激活，1-已启用',
+  `last_counter` bigint unsigned NOT NULL DEFAULT '0' COMMENT '最近一次验证通过的时间步数',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='两步验证表';
+
+
+
 # Dump of table type
 # ------------------------------------------------------------
 
@@ -238,8 +488,6 @@ DROP TABLE IF EXISTS `user`;
 CREATE TABLE `user` (
   `id` int unsigned NOT NULL AUTO_INCREMENT,
   `mobile` varchar(16) NOT NULL DEFAULT '' COMMENT '用户手机号码',
-  `openid` varchar(32) NOT NULL DEFAULT '' COMMENT 'openid',
-  `unionid` varchar(32) NOT NULL DEFAULT '' COMMENT 'unionid',
   `username` varchar(32) NOT NULL DEFAULT '' COMMENT '用户名',
   `avatar` varchar(256) NOT NULL DEFAULT '' COMMENT '头像',
   `password` varchar(32) NOT NULL DEFAULT '' COMMENT '用户密码',
@@ -253,14 +501,51 @@ CREATE TABLE `user` (
   PRIMARY KEY (`id`),
   UNIQUE KEY `uniq_code` (`code`),
   KEY `idx_pid` (`pid`),
-  KEY `idx_mobile` (`mobile`),
-  KEY `idx_openid` (`openid`),
-  KEY `idx_unionid` (`unionid`)
+  KEY `idx_mobile` (`mobile`)
 ) ENGINE=InnoDB COMMENT='用户账号表';
 
-INSERT INTO `user` (`mobile`, `openid`, `unionid`, `username`, `avatar`, `password`, `dbpassword`, `code`, `pid`, `status`, `config`, `create_time`, `update_time`)
+INSERT INTO `user` (`mobile`, `username`, `avatar`, `password`, `dbpassword`, `code`, `pid`, `status`, `config`, `create_time`, `update_time`)
+VALUES
+	('00000000000', '默认用户', '/cc/icon.png', '84f3af15562aa32a475c8aff86f486f1', '154a42ba4f9c714c24c425f03031df63', 'WELCOMECCOOL', 0, 0, '{}', 1711731115, 1711731115);
+
+
+# Dump of table userquota
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `userquota`;
+
+CREATE TABLE `userquota` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `plan` varchar(32) NOT NULL DEFAULT '' COMMENT '存储套餐，为空时使用默认套餐',
+  `hard_limit` bigint unsigned NOT NULL DEFAULT '0' COMMENT '硬限制(B)，为 0 时使用套餐额度',
+  `soft_limit` bigint unsigned NOT NULL DEFAULT '0' COMMENT '软限制(B)，为 0 时使用套餐额度',
+  `update_uid` int unsigned NOT NULL DEFAULT '0' COMMENT '设置额度的管理员 id',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='用户存储额度表';
+
+
+# Dump of table userrole
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `userrole`;
+
+CREATE TABLE `userrole` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `role` varchar(16) NOT NULL DEFAULT '' COMMENT '角色：support-客服，admin-管理员',
+  `create_uid` int unsigned NOT NULL DEFAULT '0' COMMENT '授予角色的用户 id',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_uid_role` (`uid`,`role`)
+) ENGINE=InnoDB COMMENT='用户角色表';
+
+INSERT INTO `userrole` (`uid`, `role`, `create_uid`, `create_time`)
 VALUES
-	('00000000000', '', '', '默认用户', '/cc/icon.png', '84f3af15562aa32a475c8aff86f486f1', '154a42ba4f9c714c24c425f03031df63', 'WELCOMECCOOL', 0, 0, '{}', 1711731115, 1711731115);
+	(1, 'admin', 0, 0);
 
 
 # Dump of table view
@@ -0,0 +1,33 @@
+# 第三方身份迁移到 identity 表
+# 已有数据库升级时执行，需在删除 user 表的 openid/unionid 字段前迁移已关联的微信账号
+# ------------------------------------------------------------
+
+USE `cardcool`;
+
+CREATE TABLE IF NOT EXISTS `identity` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '用户 id',
+  `provider` varchar(32) NOT NULL DEFAULT '' COMMENT '身份提供方：wechat 或配置的 OIDC 名称',
+  `subject` varchar(128) NOT NULL DEFAULT '' COMMENT '提供方内的用户标识：openid/sub',
+  `unionid` varchar(64) NOT NULL DEFAULT '' COMMENT '微信 unionid',
+  `username` varchar(64) NOT NULL DEFAULT '' COMMENT '第三方昵称',
+  `avatar` varchar(256) NOT NULL DEFAULT '' COMMENT '第三方头像',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '创建时间',
+  `update_time` int unsigned NOT NULL DEFAULT '0' COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_provider_subject` (`provider`,`subject`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='第三方身份关联表';
+
+# 同一 openid 关联了多个账号时保留最早的账号
+INSERT IGNORE INTO `identity` (`uid`, `provider`, `subject`, `unionid`, `username`, `avatar`, `create_time`, `update_time`)
+SELECT `id`, 'wechat', `openid`, `unionid`, '', '', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()
+FROM `user`
+WHERE `openid` <> ''
+ORDER BY `id`;
+
+ALTER TABLE `user`
+  DROP INDEX `idx_openid`,
+  DROP INDEX `idx_unionid`,
+  DROP COLUMN `openid`,
+  DROP COLUMN `unionid`;
@@ -0,0 +1,26 @@
+# 邀请码增加最大使用次数和已使用次数
+# 已有的一次性邀请码(limit_type=1)最大使用次数设为 1，已使用的邀请码 status 为 1
+# ------------------------------------------------------------
+
+USE `cardcool`;
+
+ALTER TABLE `invite`
+  ADD COLUMN `max_uses` int unsigned NOT NULL DEFAULT '0' COMMENT '最大使用次数，0-不限' AFTER `end_time`,
+  ADD COLUMN `used_count` int unsigned NOT NULL DEFAULT '0' COMMENT '已使用次数' AFTER `max_uses`,
+  MODIFY COLUMN `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态，0-正常，1-已用完，-1-已作废';
+
+UPDATE `invite` SET `max_uses` = 1 WHERE `limit_type` = 1 AND `max_uses` = 0;
+
+UPDATE `invite` SET `used_count` = 1 WHERE `limit_type` = 1 AND `status` = 1;
+
+CREATE TABLE IF NOT EXISTS `redemption` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `invite_id` int unsigned NOT NULL DEFAULT '0' COMMENT '邀请码 id',
+  `code` varchar(12) NOT NULL DEFAULT '' COMMENT '邀请码',
+  `uid` int unsigned NOT NULL DEFAULT '0' COMMENT '注册用户 id',
+  `pid` int unsigned NOT NULL DEFAULT '0' COMMENT '邀请人用户 id',
+  `create_time` int unsigned NOT NULL DEFAULT '0' COMMENT '使用时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_invite_id` (`invite_id`),
+  KEY `idx_uid` (`uid`)
+) ENGINE=InnoDB COMMENT='邀请码使用记录表';